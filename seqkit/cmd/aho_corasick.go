@@ -0,0 +1,260 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+
+	"github.com/shenwei356/bio/seq"
+)
+
+// AhoCorasick is an Aho-Corasick automaton for finding all occurrences of
+// multiple keywords in one pass over a text.
+// Transitions are saved in a dense table over the alphabet of keywords,
+// i.e., a deterministic automaton with failure transitions resolved.
+type AhoCorasick struct {
+	nClasses int
+	classes  [256]int32 // byte -> class, 0 for bytes absent in keywords
+	delta    []int32    // transitions, delta[state*nClasses+class]
+
+	outputs [][]int32 // keywords ending at a state
+	dict    []int32   // the nearest state on the failure chain with outputs, 0 for none
+	lens    []int     // lengths of keywords
+}
+
+// NewAhoCorasick creates an AhoCorasick automaton from keywords.
+// Keywords can be duplicated, and empty keywords are ignored.
+func NewAhoCorasick(keywords [][]byte) *AhoCorasick {
+	ac := &AhoCorasick{lens: make([]int, len(keywords))}
+
+	// alphabet
+	for _, kw := range keywords {
+		for _, b := range kw {
+			if ac.classes[b] == 0 {
+				ac.nClasses++
+				ac.classes[b] = int32(ac.nClasses)
+			}
+		}
+	}
+	ac.nClasses++
+	nc := ac.nClasses
+
+	// trie
+	ac.delta = make([]int32, nc, nc*64)
+	ac.outputs = make([][]int32, 1, 64)
+	var s, t int32
+	var c int
+	for i, kw := range keywords {
+		ac.lens[i] = len(kw)
+		if len(kw) == 0 {
+			continue
+		}
+		s = 0
+		for _, b := range kw {
+			c = int(s)*nc + int(ac.classes[b])
+			if t = ac.delta[c]; t == 0 {
+				t = int32(len(ac.outputs))
+				ac.delta[c] = t
+				ac.delta = append(ac.delta, make([]int32, nc)...)
+				ac.outputs = append(ac.outputs, nil)
+			}
+			s = t
+		}
+		ac.outputs[s] = append(ac.outputs[s], int32(i))
+	}
+
+	// failure links in BFS order, missing transitions are replaced with
+	// the ones of failure states.
+	n := len(ac.outputs)
+	fail := make([]int32, n)
+	ac.dict = make([]int32, n)
+	queue := make([]int32, 0, n)
+	for c = 1; c < nc; c++ {
+		if t = ac.delta[c]; t != 0 {
+			queue = append(queue, t)
+		}
+	}
+	var f int32
+	for len(queue) > 0 {
+		s, queue = queue[0], queue[1:]
+		for c = 1; c < nc; c++ {
+			t = ac.delta[int(s)*nc+c]
+			f = ac.delta[int(fail[s])*nc+c]
+			if t == 0 {
+				ac.delta[int(s)*nc+c] = f
+				continue
+			}
+			fail[t] = f
+			if len(ac.outputs[f]) > 0 {
+				ac.dict[t] = f
+			} else {
+				ac.dict[t] = ac.dict[f]
+			}
+			queue = append(queue, t)
+		}
+	}
+
+	return ac
+}
+
+// Find calls fn for every occurrence of keywords in the text, in the order
+// of end positions (0-based, exclusive). The search stops when fn returns true.
+func (ac *AhoCorasick) Find(text []byte, fn func(k int, end int) bool) {
+	nc := ac.nClasses
+	var s, t int32
+	var k int32
+	for j, b := range text {
+		s = ac.delta[int(s)*nc+int(ac.classes[b])]
+		if s == 0 {
+			continue
+		}
+		t = s
+		if len(ac.outputs[t]) == 0 {
+			t = ac.dict[t]
+		}
+		for t != 0 {
+			for _, k = range ac.outputs[t] {
+				if fn(int(k), j+1) {
+					return
+				}
+			}
+			t = ac.dict[t]
+		}
+	}
+}
+
+// Match tells whether any keyword occurs in the text.
+func (ac *AhoCorasick) Match(text []byte) bool {
+	nc := ac.nClasses
+	var s int32
+	for _, b := range text {
+		s = ac.delta[int(s)*nc+int(ac.classes[b])]
+		if s != 0 && (len(ac.outputs[s]) > 0 || ac.dict[s] != 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the length of the k-th keyword.
+func (ac *AhoCorasick) Len(k int) int {
+	return ac.lens[k]
+}
+
+// the Aho-Corasick automaton is chosen for searching at least this number of
+// exact or degenerate motifs, with limited numbers of expanded sequences.
+const (
+	acMinMotifs          = 8
+	acMaxExpansions      = 4096    // for a single degenerate motif
+	acMaxTotalExpansions = 1 << 20 // for all motifs
+)
+
+// MotifAutomaton finds multiple motifs, which could contain degenerate bases,
+// in one pass, with degenerate motifs expanded to all possible sequences.
+type MotifAutomaton struct {
+	ac     *AhoCorasick
+	motifs []int // motif index of each keyword
+}
+
+// NewMotifAutomaton creates a MotifAutomaton. Motifs are saved in lower case
+// with ignoreCase being true, where texts to search should be in lower case too.
+// It returns false if any motif is empty, or contains bases not supported
+// in expanding, or there are too many expanded sequences.
+func NewMotifAutomaton(motifs [][]byte, degenerate bool, ignoreCase bool) (*MotifAutomaton, bool) {
+	keywords := make([][]byte, 0, len(motifs))
+	idx := make([]int, 0, len(motifs))
+	var n int
+	var bases string
+	var ok bool
+	for i, m := range motifs {
+		if len(m) == 0 {
+			return nil, false
+		}
+		if ignoreCase {
+			m = bytes.ToLower(m)
+		}
+		if !degenerate {
+			keywords = append(keywords, m)
+			idx = append(idx, i)
+			continue
+		}
+
+		n = 1
+		for _, b := range m {
+			if bases, ok = seq.DegenerateBaseMapNucl2[b]; !ok {
+				return nil, false
+			}
+			if n *= len(bases); n > acMaxExpansions {
+				return nil, false
+			}
+		}
+		if len(keywords)+n > acMaxTotalExpansions {
+			return nil, false
+		}
+		for _, s := range expandDegenerateSeq(m) {
+			keywords = append(keywords, s)
+			idx = append(idx, i)
+		}
+	}
+
+	return &MotifAutomaton{ac: NewAhoCorasick(keywords), motifs: idx}, true
+}
+
+// expandDegenerateSeq returns all possible sequences of a sequence
+// with degenerate bases, which should all be in seq.DegenerateBaseMapNucl2.
+func expandDegenerateSeq(s []byte) [][]byte {
+	seqs := [][]byte{make([]byte, 0, len(s))}
+	var bases string
+	var tmp [][]byte
+	for _, b := range s {
+		bases = seq.DegenerateBaseMapNucl2[b]
+		if len(bases) == 1 {
+			for i := range seqs {
+				seqs[i] = append(seqs[i], bases[0])
+			}
+			continue
+		}
+		tmp = make([][]byte, 0, len(seqs)*len(bases))
+		for _, x := range seqs {
+			for j := 0; j < len(bases); j++ {
+				y := make([]byte, len(x), len(s))
+				copy(y, x)
+				tmp = append(tmp, append(y, bases[j]))
+			}
+		}
+		seqs = tmp
+	}
+	return seqs
+}
+
+// Find calls fn with the motif index and the 0-based start position of every
+// occurrence of motifs in the text, in the order of end positions.
+func (m *MotifAutomaton) Find(text []byte, fn func(motif int, start int)) {
+	m.ac.Find(text, func(k int, end int) bool {
+		fn(m.motifs[k], end-m.ac.Len(k))
+		return false
+	})
+}
+
+// Match tells whether any motif occurs in the text.
+func (m *MotifAutomaton) Match(text []byte) bool {
+	return m.ac.Match(text)
+}
@@ -61,6 +61,22 @@ Attention:
   3. Degenerate bases/residues like "RYMM.." are also supported.
      But do not use degenerate bases/residues in regular expression, you need
      convert them to regular expression, e.g., change "N" or "X"  to ".".
+  4. Panel mode (--panel) evaluates all primer pairs in the primer file
+     (-p/--primer-file) against every genome (each sequence, or each file
+     with --panel-by-file). All binding sites of all primers are searched on
+     both strands, and any two sites on opposite strands within
+     --max-product-len form a PCR product, which could be:
+       target  formed by the forward and reverse primers of the same pair.
+       cross   formed by primers of different pairs, i.e., cross-products.
+       single  formed by the same primer at both ends.
+     Outputs:
+       stdout            a matrix of target products with amplicons as rows
+                         and genomes as columns, each cell is "length:mismatches"
+                         of the products (comma-separated), or "." for dropouts.
+       --panel-products  all products with columns: genome, seqID, type,
+                         left_primer, right_primer, begin, end, length, mismatches.
+       --panel-summary   summary of amplicons with columns: amplicon, genomes,
+                         present, absent, multiple, absent_genomes, multiple_genomes.
 
 Examples:
   0. no region given.
@@ -146,6 +162,23 @@ Examples:
 		saveUnmatched := getFlagBool(cmd, "save-unmatched")
 
 		immediateOutput := getFlagBool(cmd, "immediate-output")
+		oligoProps := getFlagBool(cmd, "oligo-props")
+
+		panel := getFlagBool(cmd, "panel")
+		panelByFile := getFlagBool(cmd, "panel-by-file")
+		maxProductLen := getFlagNonNegativeInt(cmd, "max-product-len")
+		panelProductsFile := getFlagString(cmd, "panel-products")
+		panelSummary := getFlagString(cmd, "panel-summary")
+		if panel {
+			if primerFile == "" {
+				checkError(fmt.Errorf("flag -p/--primer-file needed in panel mode (--panel)"))
+			}
+			if region != "" || outFmtBED || oligoProps || saveUnmatched {
+				checkError(fmt.Errorf("flag -r/--region, --bed, -O/--oligo-props and -u/--save-unmatched are not supported in panel mode (--panel)"))
+			}
+		} else if panelByFile || panelProductsFile != "" || panelSummary != "" {
+			log.Warningf("flag --panel-by-file, --panel-products and --panel-summary only work in panel mode (--panel)")
+		}
 
 		var list [][3]string
 		var primers [][3][]byte
@@ -160,10 +193,42 @@ Examples:
 		primers, err = parsePrimers(list)
 		checkError(err)
 
+		if panel {
+			runAmpliconPanel(files, alphabet, idRegexp, list, maxMismatch, maxProductLen, panelByFile,
+				outfh, panelProductsFile, panelSummary, config.Quiet)
+			return
+		}
+
 		if !config.Quiet {
 			log.Infof("%d primer pair loaded", len(primers))
 		}
 
+		// properties of primers, appended to BED records or sequence names
+		oligoCols := make([]string, len(primers))
+		oligoTags := make([]string, len(primers))
+		if oligoProps {
+			cond := getOligoConditions(cmd)
+			var tm, gc [2]string
+			var dimer string
+			for i, items := range list {
+				for k := 0; k < 2; k++ {
+					tm[k], gc[k] = ".", "."
+					if items[k+1] != "" {
+						s := bytes.ToUpper([]byte(items[k+1]))
+						tm[k] = fmt.Sprintf("%.2f", cond.Tm(s))
+						gc[k] = fmt.Sprintf("%.2f", OligoGC(s))
+					}
+				}
+				dimer = "."
+				if items[1] != "" && items[2] != "" {
+					dg, _ := cond.Dimer(bytes.ToUpper([]byte(items[1])), bytes.ToUpper([]byte(items[2])))
+					dimer = fmt.Sprintf("%.2f", dg)
+				}
+				oligoCols[i] = fmt.Sprintf("\t%s\t%s\t%s\t%s\t%s", tm[0], tm[1], gc[0], gc[1], dimer)
+				oligoTags[i] = fmt.Sprintf(" Tm_F=%s Tm_R=%s GC_F=%s GC_R=%s dimer_dG=%s", tm[0], tm[1], gc[0], gc[1], dimer)
+			}
+		}
+
 		var begin, end int
 
 		var usingRegion bool
@@ -373,7 +438,7 @@ Examples:
 									}
 									if outputMismatches {
 										s = record.Seq.SubSeq(loc[0], loc[1]).Seq
-										results = append(results, fmt.Sprintf("%s\t%d\t%d\t%s\t%d\t%s\t%s\t%d\t%d\t%d\n",
+										results = append(results, fmt.Sprintf("%s\t%d\t%d\t%s\t%d\t%s\t%s\t%d\t%d\t%d%s\n",
 											record.ID,
 											start1,
 											end1,
@@ -384,16 +449,18 @@ Examples:
 											mis[0]+mis[1],
 											mis[0],
 											mis[1],
+											oligoCols[j],
 										))
 									} else {
-										results = append(results, fmt.Sprintf("%s\t%d\t%d\t%s\t%d\t%s\t%s\n",
+										results = append(results, fmt.Sprintf("%s\t%d\t%d\t%s\t%d\t%s\t%s%s\n",
 											record.ID,
 											start1,
 											end1,
 											primer[0],
 											0,
 											strand,
-											record.Seq.SubSeq(loc[0], loc[1]).Seq))
+											record.Seq.SubSeq(loc[0], loc[1]).Seq,
+											oligoCols[j]))
 									}
 
 									continue
@@ -402,7 +469,9 @@ Examples:
 
 								record.Seq = record.Seq.SubSeq(loc[0], loc[1])
 								if outputMismatches {
-									record.Name = []byte(fmt.Sprintf("%s mismatches=%d(%d+%d)", name0, mis[0]+mis[1], mis[0], mis[1]))
+									record.Name = []byte(fmt.Sprintf("%s mismatches=%d(%d+%d)%s", name0, mis[0]+mis[1], mis[0], mis[1], oligoTags[j]))
+								} else if oligoProps {
+									record.Name = []byte(name0 + oligoTags[j])
 								}
 								results = append(results, string(record.Format(config.LineWidth)))
 
@@ -510,7 +579,7 @@ Examples:
 							}
 							if outputMismatches {
 								fmt.Fprintf(outfh,
-									"%s\t%d\t%d\t%s\t%d\t%s\t%s\t%d\t%d\t%d\n",
+									"%s\t%d\t%d\t%s\t%d\t%s\t%s\t%d\t%d\t%d%s\n",
 									record.ID,
 									start1,
 									end1,
@@ -521,17 +590,19 @@ Examples:
 									0,
 									0,
 									0,
+									oligoCols[j],
 								)
 							} else {
 								fmt.Fprintf(outfh,
-									"%s\t%d\t%d\t%s\t%d\t%s\t%s\n",
+									"%s\t%d\t%d\t%s\t%d\t%s\t%s%s\n",
 									record.ID,
 									start1,
 									end1,
 									primer[0],
 									0,
 									strand,
-									record.Seq.SubSeq(loc[0], loc[1]).Seq)
+									record.Seq.SubSeq(loc[0], loc[1]).Seq,
+									oligoCols[j])
 							}
 
 							continue
@@ -540,7 +611,9 @@ Examples:
 
 						record.Seq = record.Seq.SubSeq(loc[0], loc[1])
 						if outputMismatches {
-							record.Name = []byte(fmt.Sprintf("%s mismatches=%d(%d+%d)", name0, 0, 0, 0))
+							record.Name = []byte(fmt.Sprintf("%s mismatches=%d(%d+%d)%s", name0, 0, 0, 0, oligoTags[j]))
+						} else if oligoProps {
+							record.Name = []byte(name0 + oligoTags[j])
 						}
 						record.FormatToWriter(outfh, config.LineWidth)
 
@@ -580,6 +653,13 @@ func init() {
 	ampliconCmd.Flags().BoolP("bed", "", false, "output in BED6+1 format with amplicon as the 7th column")
 	ampliconCmd.Flags().BoolP("immediate-output", "I", false, "print output immediately, do not use write buffer")
 	ampliconCmd.Flags().BoolP("save-unmatched", "u", false, "also save records that do not match any primer")
+	ampliconCmd.Flags().BoolP("panel", "", false, `panel mode: evaluating all primer pairs against every genome, type "seqkit amplicon -h" for details`)
+	ampliconCmd.Flags().BoolP("panel-by-file", "", false, `panel mode: treat each input file rather than each sequence as a genome`)
+	ampliconCmd.Flags().IntP("max-product-len", "", 2000, `panel mode: maximum length of PCR products, 0 for no limit`)
+	ampliconCmd.Flags().StringP("panel-products", "", "", `panel mode: file to save all products, including off-target products and cross-products`)
+	ampliconCmd.Flags().StringP("panel-summary", "", "", `panel mode: file to save the summary of each amplicon, including dropouts in genomes`)
+	ampliconCmd.Flags().BoolP("oligo-props", "O", false, `append Tm and GC content of primers and ΔG of the dimer of each primer pair, with reaction conditions set by --mono, --mg, --dntp, --oligo-conc, --salt-correction and -T/--temperature, type "seqkit oligo -h" for details`)
+	addOligoConditionFlags(ampliconCmd)
 }
 
 // only used in this command
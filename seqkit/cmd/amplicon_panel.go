@@ -0,0 +1,363 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shenwei356/bio/seq"
+	"github.com/shenwei356/bio/seqio/fastx"
+	"github.com/shenwei356/bwt/fmi"
+	"github.com/shenwei356/xopen"
+)
+
+// PanelPrimer is a primer of a primer pair in the panel mode of amplicon.
+type PanelPrimer struct {
+	Pair int    // index of the primer pair
+	Name string // name of the primer pair
+	Dir  byte   // F or R
+	Seq  []byte // 5'-primer-3', upper case
+	RC   []byte // reverse complement sequence
+
+	re, reRC *regexp.Regexp
+}
+
+// Label returns the name and direction of the primer, e.g., amp1_F.
+func (p *PanelPrimer) Label() string {
+	return fmt.Sprintf("%s_%c", p.Name, p.Dir)
+}
+
+// PanelSite is a binding site of a primer.
+type PanelSite struct {
+	Primer *PanelPrimer
+	Begin  int  // 0-based
+	End    int  // 0-based, exclusive
+	Strand byte // +: the primer matches the top strand; -: the reverse complement matches.
+	Mis    int
+}
+
+// PanelProduct is a PCR product formed by two primer binding sites.
+type PanelProduct struct {
+	SeqID       string
+	Left, Right *PanelSite
+}
+
+// Len returns the length of the product.
+func (p *PanelProduct) Len() int {
+	return p.Right.End - p.Left.Begin
+}
+
+// Mismatches returns the total number of mismatches of the two primers.
+func (p *PanelProduct) Mismatches() int {
+	return p.Left.Mis + p.Right.Mis
+}
+
+// Type returns the type of the product: target, cross, or single.
+func (p *PanelProduct) Type() string {
+	if p.Left.Primer == p.Right.Primer {
+		return "single"
+	}
+	if p.Left.Primer.Pair == p.Right.Primer.Pair {
+		return "target"
+	}
+	return "cross"
+}
+
+// newPanelPrimers creates primers from the primer list.
+// Primer pairs without reverse primers are ignored.
+func newPanelPrimers(list [][3]string, maxMismatch int) ([]*PanelPrimer, []string, error) {
+	primers := make([]*PanelPrimer, 0, len(list)*2)
+	names := make([]string, 0, len(list))
+	for _, items := range list {
+		if items[1] == "" || items[2] == "" {
+			log.Warningf("primer pair without reverse primer is ignored in panel mode: %s", items[0])
+			continue
+		}
+		for k, dir := range []byte{'F', 'R'} {
+			p := &PanelPrimer{Pair: len(names), Name: items[0], Dir: dir, Seq: bytes.ToUpper([]byte(items[k+1]))}
+			s, err := seq.NewSeq(seq.DNAredundant, p.Seq)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid primer sequence: %s", p.Seq)
+			}
+			p.RC = s.RevCom().Seq
+
+			if seq.DNA.IsValid(p.Seq) != nil { // containing degenerate base
+				if maxMismatch > 0 {
+					return nil, nil, fmt.Errorf("it does not support both degenerate base and mismatch: %s", p.Label())
+				}
+			}
+			if maxMismatch == 0 {
+				if p.re, err = regexp.Compile(s.Degenerate2Regexp()); err != nil {
+					return nil, nil, fmt.Errorf("fail to parse primer: %s", p.Seq)
+				}
+				rc, _ := seq.NewSeq(seq.DNAredundant, p.RC)
+				if p.reRC, err = regexp.Compile(rc.Degenerate2Regexp()); err != nil {
+					return nil, nil, fmt.Errorf("fail to parse primer: %s", p.Seq)
+				}
+			}
+			primers = append(primers, p)
+		}
+		names = append(names, items[0])
+	}
+	if len(names) == 0 {
+		return nil, nil, fmt.Errorf("no primer pairs with both forward and reverse primers given")
+	}
+	return primers, names, nil
+}
+
+// panelSites finds binding sites of all primers in a sequence.
+func panelSites(s []byte, primers []*PanelPrimer, maxMismatch int) ([]*PanelSite, []*PanelSite, error) {
+	var index *fmi.FMIndex
+	if maxMismatch > 0 {
+		index = fmi.NewFMIndex()
+		if _, err := index.Transform(s); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	plus := make([]*PanelSite, 0, 8)
+	minus := make([]*PanelSite, 0, 8)
+	var locs []int
+	var err error
+	for _, p := range primers {
+		for k, q := range [][]byte{p.Seq, p.RC} {
+			if maxMismatch > 0 {
+				locs, err = index.Locate(q, maxMismatch)
+				if err != nil {
+					return nil, nil, err
+				}
+			} else {
+				re := p.re
+				if k == 1 {
+					re = p.reRC
+				}
+				locs = locs[:0]
+				for offset := 0; offset < len(s); {
+					loc := re.FindIndex(s[offset:])
+					if loc == nil {
+						break
+					}
+					locs = append(locs, offset+loc[0])
+					offset += loc[0] + 1
+				}
+			}
+
+			for _, i := range locs {
+				site := &PanelSite{Primer: p, Begin: i, End: i + len(q)}
+				if maxMismatch > 0 {
+					site.Mis = amplicon_mismatches(s[i:i+len(q)], q)
+				}
+				if k == 0 {
+					site.Strand = '+'
+					plus = append(plus, site)
+				} else {
+					site.Strand = '-'
+					minus = append(minus, site)
+				}
+			}
+		}
+	}
+
+	sort.Slice(plus, func(i, j int) bool { return plus[i].Begin < plus[j].Begin })
+	sort.Slice(minus, func(i, j int) bool { return minus[i].Begin < minus[j].Begin })
+	return plus, minus, nil
+}
+
+// panelProducts pairs primer binding sites on the two strands into products
+// not longer than maxLen (0 for no limit).
+func panelProducts(seqID string, plus, minus []*PanelSite, maxLen int) []*PanelProduct {
+	products := make([]*PanelProduct, 0, len(plus))
+	var j int
+	for _, a := range plus {
+		for j = sort.Search(len(minus), func(i int) bool { return minus[i].Begin >= a.Begin }); j < len(minus); j++ {
+			b := minus[j]
+			if maxLen > 0 && b.End-a.Begin > maxLen {
+				if b.Begin-a.Begin > maxLen {
+					break
+				}
+				continue
+			}
+			if b.End <= a.End {
+				continue
+			}
+			products = append(products, &PanelProduct{SeqID: seqID, Left: a, Right: b})
+		}
+	}
+	return products
+}
+
+// runAmpliconPanel evaluates all primer pairs against every genome.
+func runAmpliconPanel(files []string, alphabet *seq.Alphabet, idRegexp string,
+	list [][3]string, maxMismatch int, maxLen int, byFile bool,
+	outfh *xopen.Writer, productsFile, summaryFile string, quiet bool) {
+
+	primers, names, err := newPanelPrimers(list, maxMismatch)
+	checkError(err)
+
+	genomes := make([]string, 0, 8)
+	targets := make(map[string][][]*PanelProduct) // genome -> pair -> products
+
+	var fhProducts *xopen.Writer
+	if productsFile != "" {
+		fhProducts, err = xopen.Wopen(productsFile)
+		checkError(err)
+		defer fhProducts.Close()
+		fhProducts.WriteString("genome\tseqID\ttype\tleft_primer\tright_primer\tbegin\tend\tlength\tmismatches\n")
+	}
+
+	var record *fastx.Record
+	var genome string
+	var products []*PanelProduct
+	var plus, minus []*PanelSite
+	var nOffTargets int
+	for _, file := range files {
+		if byFile {
+			genome = filepath.Base(file)
+			if _, ok := targets[genome]; !ok {
+				genomes = append(genomes, genome)
+				targets[genome] = make([][]*PanelProduct, len(names))
+			}
+		}
+
+		fastxReader, err := fastx.NewReader(alphabet, file, idRegexp)
+		checkError(err)
+		for {
+			record, err = fastxReader.Read()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				checkError(err)
+				break
+			}
+
+			if !byFile {
+				genome = string(record.ID)
+				if _, ok := targets[genome]; ok {
+					log.Warningf("duplicated sequence ID, products are merged: %s", genome)
+				} else {
+					genomes = append(genomes, genome)
+					targets[genome] = make([][]*PanelProduct, len(names))
+				}
+			}
+
+			plus, minus, err = panelSites(bytes.ToUpper(record.Seq.Seq), primers, maxMismatch)
+			checkError(err)
+			products = panelProducts(string(record.ID), plus, minus, maxLen)
+
+			for _, p := range products {
+				if p.Type() == "target" {
+					targets[genome][p.Left.Primer.Pair] = append(targets[genome][p.Left.Primer.Pair], p)
+				} else {
+					nOffTargets++
+				}
+				if fhProducts != nil {
+					fhProducts.WriteString(fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\n",
+						genome, p.SeqID, p.Type(),
+						p.Left.Primer.Label(), p.Right.Primer.Label(),
+						p.Left.Begin+1, p.Right.End, p.Len(), p.Mismatches()))
+				}
+			}
+		}
+		fastxReader.Close()
+	}
+
+	// matrix
+	outfh.WriteString("amplicon")
+	for _, genome = range genomes {
+		outfh.WriteString("\t" + genome)
+	}
+	outfh.WriteString("\n")
+
+	var cells []string
+	for i, name := range names {
+		outfh.WriteString(name)
+		for _, genome = range genomes {
+			products = targets[genome][i]
+			if len(products) == 0 {
+				outfh.WriteString("\t.")
+				continue
+			}
+			cells = cells[:0]
+			for _, p := range products {
+				cells = append(cells, strconv.Itoa(p.Len())+":"+strconv.Itoa(p.Mismatches()))
+			}
+			outfh.WriteString("\t" + strings.Join(cells, ","))
+		}
+		outfh.WriteString("\n")
+	}
+
+	// summary
+	var nDropouts, nMultiple int
+	if summaryFile != "" {
+		fhSummary, err := xopen.Wopen(summaryFile)
+		checkError(err)
+		defer fhSummary.Close()
+		fhSummary.WriteString("amplicon\tgenomes\tpresent\tabsent\tmultiple\tabsent_genomes\tmultiple_genomes\n")
+
+		var absent, multiple []string
+		for i, name := range names {
+			absent, multiple = absent[:0], multiple[:0]
+			for _, genome = range genomes {
+				switch len(targets[genome][i]) {
+				case 0:
+					absent = append(absent, genome)
+				case 1:
+				default:
+					multiple = append(multiple, genome)
+				}
+			}
+			fhSummary.WriteString(fmt.Sprintf("%s\t%d\t%d\t%d\t%d\t%s\t%s\n",
+				name, len(genomes), len(genomes)-len(absent), len(absent), len(multiple),
+				joinOrDot(absent), joinOrDot(multiple)))
+		}
+	}
+
+	if !quiet {
+		for i := range names {
+			for _, genome = range genomes {
+				switch len(targets[genome][i]) {
+				case 0:
+					nDropouts++
+				case 1:
+				default:
+					nMultiple++
+				}
+			}
+		}
+		log.Infof("%d primer pairs, %d genomes: %d dropouts, %d amplicons with multiple products, %d off-target products",
+			len(names), len(genomes), nDropouts, nMultiple, nOffTargets)
+	}
+}
+
+func joinOrDot(list []string) string {
+	if len(list) == 0 {
+		return "."
+	}
+	return strings.Join(list, ",")
+}
@@ -0,0 +1,534 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/shenwei356/bio/seq"
+	"github.com/shenwei356/bio/seqio/fastx"
+	"github.com/shenwei356/breader"
+	"github.com/shenwei356/xopen"
+	"github.com/spf13/cobra"
+)
+
+// codonCmd represents the codon command
+var codonCmd = &cobra.Command{
+	GroupID: "basic",
+
+	Use:   "codon",
+	Short: "codon usage, RSCU, CAI and ENC of coding sequences",
+	Long: `codon usage, RSCU, CAI and ENC of coding sequences
+
+Input sequences should be coding sequences (CDSs) starting from the first
+base of the first codon. Codons containing ambiguous bases are ignored.
+
+Output (default): codon usage table of each input file, or of each
+sequence (-s/--by-seq).
+
+  1. file/seqID     input file ("-" for STDIN) or sequence ID
+  2. codon          codon
+  3. aa             amino acid, '*' for stop codon
+  4. count          number of the codon
+  5. per_thousand   frequency per thousand codons
+  6. RSCU           relative synonymous codon usage, i.e., the ratio of the
+                    observed count to the expected count if all synonymous
+                    codons were used equally
+
+Output of -I/--indices: indices of each sequence.
+
+  1. seqID          sequence ID
+  2. length         sequence length
+  3. codons         number of valid codons
+  4. ENC            effective number of codons (Wright 1990), ranging
+                    from 20 to 61 for the standard code, -1 for sequences
+                    without enough codons
+  5. CAI            codon adaptation index (Sharp and Li 1987) against the
+                    reference codon usage table (-r/--ref-usage), -1 for
+                    sequences without enough codons or no reference given
+  6. len_mod3       sequence length modulo 3, should be 0 for CDSs
+  7. internal_stops number of internal stop codons, should be 0 for CDSs
+
+Attention:
+
+  1. Numbers of sequences of which lengths are not multiple of 3 or with
+     internal stop codons are reported in the log, these sequences can be
+     checked with -I/--indices, and excluded with -x/--exclude-invalid.
+  2. The reference codon usage table for CAI can be the output of this
+     command, e.g., computed from highly expressed genes. Only columns
+     "codon" and "count" are used, and counts of the same codon in multiple
+     rows are summed. Zero counts are replaced with 0.5 as Sharp and Li did.
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := getConfigs(cmd)
+		alphabet := config.Alphabet
+		idRegexp := config.IDRegexp
+		outFile := config.OutFile
+		quiet := config.Quiet
+		seq.AlphabetGuessSeqLengthThreshold = config.AlphabetGuessSeqLength
+		seq.ValidateSeq = false
+		runtime.GOMAXPROCS(config.Threads)
+
+		translTable := getFlagPositiveInt(cmd, "transl-table")
+		table, ok := seq.CodonTables[translTable]
+		if !ok {
+			checkError(fmt.Errorf("invalid translate table: %d", translTable))
+		}
+		bySeq := getFlagBool(cmd, "by-seq")
+		indices := getFlagBool(cmd, "indices")
+		refFile := getFlagString(cmd, "ref-usage")
+		excludeInvalid := getFlagBool(cmd, "exclude-invalid")
+		basename := getFlagBool(cmd, "basename")
+		decimal := getFlagNonNegativeInt(cmd, "decimal-width")
+
+		if bySeq && indices {
+			checkError(fmt.Errorf("flag -s/--by-seq and -I/--indices are not compatible"))
+		}
+
+		cu := NewCodonUsage(table)
+
+		var weights *[64]float64
+		if refFile != "" {
+			counts, err := readCodonUsageCounts(refFile)
+			checkError(err)
+			weights = cu.Weights(counts)
+		}
+
+		files := getFileListFromArgsAndFile(cmd, args, true, "infile-list", !config.SkipFileCheck)
+		if !config.SkipFileCheck {
+			for _, file := range files {
+				checkIfFilesAreTheSame(file, outFile, "input", "output")
+			}
+		}
+
+		outfh, err := xopen.Wopen(outFile)
+		checkError(err)
+		defer outfh.Close()
+
+		fFloat := fmt.Sprintf("%%.%df", decimal)
+
+		if indices {
+			outfh.WriteString("seqID\tlength\tcodons\tENC\tCAI\tlen_mod3\tinternal_stops\n")
+		} else if bySeq {
+			outfh.WriteString("seqID\tcodon\taa\tcount\tper_thousand\tRSCU\n")
+		} else {
+			outfh.WriteString("file\tcodon\taa\tcount\tper_thousand\tRSCU\n")
+		}
+
+		var record *fastx.Record
+		var counts, total [64]int
+		var stops, nInvalidLen, nInternalStops int
+		var enc, cai float64
+		var name string
+		once := true
+		for _, file := range files {
+			fastxReader, err := fastx.NewReader(alphabet, file, idRegexp)
+			checkError(err)
+
+			total = [64]int{}
+			for {
+				record, err = fastxReader.Read()
+				if err != nil {
+					if err == io.EOF {
+						break
+					}
+					checkError(err)
+					break
+				}
+
+				if once {
+					if !(record.Seq.Alphabet == seq.DNA || record.Seq.Alphabet == seq.DNAredundant ||
+						record.Seq.Alphabet == seq.RNA || record.Seq.Alphabet == seq.RNAredundant) {
+						checkError(fmt.Errorf(`command 'seqkit codon' only apply to DNA/RNA sequences`))
+					}
+					once = false
+				}
+
+				counts = [64]int{}
+				stops = cu.Count(record.Seq.Seq, &counts)
+
+				if len(record.Seq.Seq)%3 != 0 {
+					nInvalidLen++
+				}
+				if stops > 0 {
+					nInternalStops++
+				}
+				if excludeInvalid && (stops > 0 || len(record.Seq.Seq)%3 != 0) {
+					continue
+				}
+
+				if indices {
+					enc = cu.ENC(&counts)
+					cai = -1
+					if weights != nil {
+						cai = cu.CAI(&counts, weights)
+					}
+					outfh.WriteString(fmt.Sprintf("%s\t%d\t%d\t%s\t%s\t%d\t%d\n",
+						record.ID, len(record.Seq.Seq), sumCodonCounts(&counts),
+						formatCodonIndex(enc, fFloat), formatCodonIndex(cai, fFloat),
+						len(record.Seq.Seq)%3, stops))
+					continue
+				}
+
+				if bySeq {
+					cu.Write(outfh, string(record.ID), &counts, fFloat)
+					continue
+				}
+
+				for i, n := range counts {
+					total[i] += n
+				}
+			}
+			fastxReader.Close()
+
+			if !(indices || bySeq) {
+				name = file
+				if basename {
+					name = filepath.Base(file)
+				}
+				cu.Write(outfh, name, &total, fFloat)
+			}
+		}
+
+		if !quiet {
+			if nInvalidLen > 0 {
+				log.Warningf("%d sequences with length not multiple of 3", nInvalidLen)
+			}
+			if nInternalStops > 0 {
+				log.Warningf("%d sequences with internal stop codons", nInternalStops)
+			}
+			if !indices && nInvalidLen+nInternalStops > 0 {
+				log.Warningf("use -I/--indices to check them, or -x/--exclude-invalid to exclude them")
+			}
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(codonCmd)
+
+	codonCmd.Flags().IntP("transl-table", "T", 1, `translate table/genetic code, type 'seqkit translate --help' for more details`)
+	codonCmd.Flags().BoolP("by-seq", "s", false, "output codon usage table of each sequence rather than each file")
+	codonCmd.Flags().BoolP("indices", "I", false, "output ENC, CAI and CDS checking results of each sequence")
+	codonCmd.Flags().StringP("ref-usage", "r", "", "reference codon usage table for computing CAI, e.g., output of this command")
+	codonCmd.Flags().BoolP("exclude-invalid", "x", false, "exclude sequences of which lengths are not multiple of 3 or with internal stop codons")
+	codonCmd.Flags().BoolP("basename", "b", false, "only output basename of files")
+	codonCmd.Flags().IntP("decimal-width", "d", 3, "decimal width")
+}
+
+// codonBase2code maps bases to 2-bit codes in the order of TCAG,
+// so codon indexes are in the order of NCBI codon tables.
+var codonBase2code [256]int8
+
+// codonCode2base is the reverse of codonBase2code.
+var codonCode2base = [4]byte{'T', 'C', 'A', 'G'}
+
+func init() {
+	for i := range codonBase2code {
+		codonBase2code[i] = -1
+	}
+	codonBase2code['T'], codonBase2code['t'] = 0, 0
+	codonBase2code['U'], codonBase2code['u'] = 0, 0
+	codonBase2code['C'], codonBase2code['c'] = 1, 1
+	codonBase2code['A'], codonBase2code['a'] = 2, 2
+	codonBase2code['G'], codonBase2code['g'] = 3, 3
+}
+
+// codonIndex returns the index (0-63) of a codon, -1 for codons containing
+// ambiguous bases.
+func codonIndex(codon []byte) int {
+	a, b, c := codonBase2code[codon[0]], codonBase2code[codon[1]], codonBase2code[codon[2]]
+	if a < 0 || b < 0 || c < 0 {
+		return -1
+	}
+	return int(a)<<4 | int(b)<<2 | int(c)
+}
+
+// CodonUsage computes codon usage statistics with a translate table.
+type CodonUsage struct {
+	Codons [64]string
+	AAs    [64]byte
+
+	synonyms map[byte][]int // amino acid -> indexes of its codons
+}
+
+// NewCodonUsage creates a CodonUsage with a translate table.
+func NewCodonUsage(table *seq.CodonTable) *CodonUsage {
+	cu := &CodonUsage{synonyms: make(map[byte][]int, 21)}
+	var codon []byte
+	var aa byte
+	for i := 0; i < 64; i++ {
+		codon = []byte{codonCode2base[i>>4], codonCode2base[(i>>2)&3], codonCode2base[i&3]}
+		aa, _ = table.Get(codon, false)
+		cu.Codons[i] = string(codon)
+		cu.AAs[i] = aa
+		cu.synonyms[aa] = append(cu.synonyms[aa], i)
+	}
+	return cu
+}
+
+// Count counts codons of a sequence in frame 1, and returns the number of
+// internal stop codons.
+func (cu *CodonUsage) Count(s []byte, counts *[64]int) (internalStops int) {
+	n := len(s) / 3 * 3
+	var i, j int
+	for i = 0; i < n; i += 3 {
+		j = codonIndex(s[i : i+3])
+		if j < 0 {
+			continue
+		}
+		counts[j]++
+		if cu.AAs[j] == '*' && i+3 < n {
+			internalStops++
+		}
+	}
+	return internalStops
+}
+
+// RSCU returns relative synonymous codon usage values.
+func (cu *CodonUsage) RSCU(counts *[64]int) [64]float64 {
+	var rscu [64]float64
+	var sum int
+	for _, idxs := range cu.synonyms {
+		sum = 0
+		for _, i := range idxs {
+			sum += counts[i]
+		}
+		if sum == 0 {
+			continue
+		}
+		for _, i := range idxs {
+			rscu[i] = float64(counts[i]) * float64(len(idxs)) / float64(sum)
+		}
+	}
+	return rscu
+}
+
+// Weights computes the relative adaptiveness of codons for CAI from a
+// reference codon usage, zero counts are replaced with 0.5.
+func (cu *CodonUsage) Weights(counts *[64]int) *[64]float64 {
+	var w [64]float64
+	var max, c float64
+	for _, idxs := range cu.synonyms {
+		max = 0
+		for _, i := range idxs {
+			c = float64(counts[i])
+			if c == 0 {
+				c = 0.5
+			}
+			w[i] = c
+			if c > max {
+				max = c
+			}
+		}
+		for _, i := range idxs {
+			w[i] /= max
+		}
+	}
+	return &w
+}
+
+// CAI computes the codon adaptation index, i.e., the geometric mean of
+// weights of codons, excluding stop codons and amino acids encoded by only
+// one codon. -1 is returned for no codons available.
+func (cu *CodonUsage) CAI(counts *[64]int, weights *[64]float64) float64 {
+	var sum float64
+	var n int
+	for i, c := range counts {
+		if c == 0 || cu.AAs[i] == '*' || len(cu.synonyms[cu.AAs[i]]) < 2 {
+			continue
+		}
+		sum += float64(c) * math.Log(weights[i])
+		n += c
+	}
+	if n == 0 {
+		return -1
+	}
+	return math.Exp(sum / float64(n))
+}
+
+// ENC computes the effective number of codons with the method of Wright (1990).
+// Amino acids are grouped by their degeneracy, and the average homozygosity F
+// of each group is computed from amino acids observed at least twice.
+// The missing F of the 3-fold group (only isoleucine in the standard code)
+// is estimated as the average of those of 2-fold and 4-fold groups.
+// -1 is returned if F of any other group is unavailable.
+func (cu *CodonUsage) ENC(counts *[64]int) float64 {
+	nAAs := make(map[int]int, 6)     // degeneracy -> number of amino acids
+	sumF := make(map[int]float64, 6) // degeneracy -> sum of F
+	nF := make(map[int]int, 6)       // degeneracy -> number of F
+	var k, n int
+	var p, f float64
+	for aa, idxs := range cu.synonyms {
+		if aa == '*' {
+			continue
+		}
+		k = len(idxs)
+		nAAs[k]++
+		if k == 1 {
+			continue
+		}
+		n = 0
+		for _, i := range idxs {
+			n += counts[i]
+		}
+		if n < 2 {
+			continue
+		}
+		f = 0
+		for _, i := range idxs {
+			p = float64(counts[i]) / float64(n)
+			f += p * p
+		}
+		sumF[k] += (float64(n)*f - 1) / float64(n-1)
+		nF[k]++
+	}
+
+	var nSense int
+	for k, n = range nAAs {
+		nSense += k * n
+	}
+
+	var enc float64
+	var Fk float64
+	for k, n = range nAAs {
+		if k == 1 {
+			enc += float64(n)
+			continue
+		}
+		if nF[k] > 0 {
+			Fk = sumF[k] / float64(nF[k])
+		} else if k == 3 && nF[2] > 0 && nF[4] > 0 {
+			Fk = (sumF[2]/float64(nF[2]) + sumF[4]/float64(nF[4])) / 2
+		} else {
+			return -1
+		}
+		if Fk <= 0 {
+			return -1
+		}
+		enc += float64(n) / Fk
+	}
+	if enc > float64(nSense) {
+		enc = float64(nSense)
+	}
+	return enc
+}
+
+// Write outputs the codon usage table.
+func (cu *CodonUsage) Write(outfh *xopen.Writer, name string, counts *[64]int, fFloat string) {
+	rscu := cu.RSCU(counts)
+	total := sumCodonCounts(counts)
+	var perThousand float64
+	for i, c := range counts {
+		perThousand = 0
+		if total > 0 {
+			perThousand = float64(c) / float64(total) * 1000
+		}
+		outfh.WriteString(fmt.Sprintf("%s\t%s\t%c\t%d\t"+fFloat+"\t"+fFloat+"\n",
+			name, cu.Codons[i], cu.AAs[i], c, perThousand, rscu[i]))
+	}
+}
+
+func sumCodonCounts(counts *[64]int) (n int) {
+	for _, c := range counts {
+		n += c
+	}
+	return n
+}
+
+func formatCodonIndex(v float64, fFloat string) string {
+	if v < 0 {
+		return "-1"
+	}
+	return fmt.Sprintf(fFloat, v)
+}
+
+// readCodonUsageCounts reads the columns "codon" and "count" of a codon usage table.
+func readCodonUsageCounts(file string) (*[64]int, error) {
+	type codonCount struct {
+		idx   int
+		count int
+	}
+	iCodon, iCount := -1, -1
+	fn := func(line string) (interface{}, bool, error) {
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" || line[0] == '#' {
+			return nil, false, nil
+		}
+		items := strings.Split(line, "\t")
+		if iCodon < 0 { // header line
+			for i, item := range items {
+				switch strings.ToLower(item) {
+				case "codon":
+					iCodon = i
+				case "count":
+					iCount = i
+				}
+			}
+			if iCodon < 0 || iCount < 0 {
+				return nil, false, fmt.Errorf(`columns "codon" and "count" are needed in the header line`)
+			}
+			return nil, false, nil
+		}
+		if len(items) <= iCodon || len(items) <= iCount {
+			return nil, false, nil
+		}
+		if len(items[iCodon]) != 3 {
+			return nil, false, fmt.Errorf("invalid codon: %s", items[iCodon])
+		}
+		idx := codonIndex([]byte(items[iCodon]))
+		if idx < 0 {
+			return nil, false, fmt.Errorf("invalid codon: %s", items[iCodon])
+		}
+		count, err := strconv.Atoi(items[iCount])
+		if err != nil {
+			return nil, false, fmt.Errorf("count should be integer: %s", items[iCount])
+		}
+		return codonCount{idx: idx, count: count}, true, nil
+	}
+	// one thread to make sure the header line is parsed first
+	reader, err := breader.NewBufferedReader(file, 1, 10, fn)
+	if err != nil {
+		return nil, err
+	}
+	var counts [64]int
+	var cc codonCount
+	for chunk := range reader.Ch {
+		if chunk.Err != nil {
+			return nil, fmt.Errorf("read reference codon usage table %s: %s", file, chunk.Err)
+		}
+		for _, data := range chunk.Data {
+			cc = data.(codonCount)
+			counts[cc.idx] += cc.count
+		}
+	}
+	if sumCodonCounts(&counts) == 0 {
+		return nil, fmt.Errorf("no codon counts found in reference codon usage table: %s", file)
+	}
+	return &counts, nil
+}
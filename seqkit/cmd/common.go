@@ -25,8 +25,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"runtime"
 	"sort"
+	"strings"
 
 	"github.com/cespare/xxhash/v2"
 	"github.com/shenwei356/bio/seq"
@@ -63,6 +66,30 @@ Note:
   4. Some records in one file may have same sequences/IDs. They will ALL be
      retrieved if the sequence/ID was shared in multiple files.
      So the records number may be larger than that of the smallest file.
+  5. For huge inputs, use --max-mem to limit the memory usage of hash
+     values, which are then sorted in chunks and saved in the temporary
+     directory (--tmp-dir). The first file is read twice, and the data
+     from stdin is saved in the temporary directory if it's the first file.
+     -e/--check-embedded-seqs is not supported in this mode.
+
+Set operations (-m/--mode):
+  intersection     keys shared by all files (default)
+  union            keys in any file
+  difference       keys only in the first file, i.e., A - B - C ...
+  sym-difference   keys in only one file
+  at-least         keys in at least K (-k/--min-files) files
+
+  Keys are IDs, full names (-n/--by-name), or sequences (-s/--by-seq).
+  For each selected key, records are outputted from the first file
+  containing it, in the order of files. Input files are read twice for modes
+  other than intersection, and the data from stdin is saved in a temporary
+  directory (--tmp-dir). -e/--check-embedded-seqs only works for intersection.
+
+Presence/absence matrix (-M/--matrix):
+  A matrix of all unique keys (rows) and files (columns) is saved to a TSV file,
+  with 1 for presence and 0 for absence, which could be used for UpSet plots.
+  The first column is the ID (or full name with -n/--by-name) of the first
+  record of a key. It's not supported with --max-mem.
 
 `,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -80,6 +107,9 @@ Note:
 		byName := getFlagBool(cmd, "by-name")
 		ignoreCase := getFlagBool(cmd, "ignore-case")
 		checkembeddedSeqs := getFlagBool(cmd, "check-embedded-seqs")
+		mode := getFlagString(cmd, "mode")
+		minFiles := getFlagPositiveInt(cmd, "min-files")
+		matrixFile := getFlagString(cmd, "matrix")
 
 		if bySeq && byName {
 			checkError(fmt.Errorf("only one/none of the flags -s (--by-seq) and -n (--by-name) is allowed"))
@@ -96,6 +126,22 @@ Note:
 			checkError(fmt.Errorf("flag -s (--by-seq) needed when using -e (--check-embedded-seqs)"))
 		}
 
+		maxMemS := getFlagString(cmd, "max-mem")
+		tmpDir := filepath.Clean(getFlagString(cmd, "tmp-dir"))
+		maxMem, err := ParseByteSize(maxMemS)
+		if err != nil {
+			checkError(fmt.Errorf("invalid value of flag --max-mem: %s", maxMemS))
+		}
+		if maxMem > 0 && checkembeddedSeqs {
+			checkError(fmt.Errorf("flag -e (--check-embedded-seqs) is not supported when using --max-mem"))
+		}
+		if checkembeddedSeqs && (mode != commonIntersection || matrixFile != "") {
+			checkError(fmt.Errorf("flag -e (--check-embedded-seqs) only works for the mode of intersection, without -M (--matrix)"))
+		}
+		if maxMem > 0 && matrixFile != "" {
+			checkError(fmt.Errorf("flag -M (--matrix) is not supported when using --max-mem"))
+		}
+
 		files := getFileListFromArgsAndFile(cmd, args, true, "infile-list", !config.SkipFileCheck)
 
 		if len(files) < 2 {
@@ -104,13 +150,24 @@ Note:
 		if !config.SkipFileCheck {
 			for _, file := range files {
 				checkIfFilesAreTheSame(file, outFile, "input", "output")
+				if matrixFile != "" {
+					checkIfFilesAreTheSame(file, matrixFile, "input", "matrix")
+				}
 			}
 		}
 
+		selected, err := newCommonSelector(mode, len(files), minFiles)
+		checkError(err)
+
 		outfh, err := xopen.Wopen(outFile)
 		checkError(err)
 		defer outfh.Close()
 
+		if maxMem > 0 {
+			commonOnDisk(files, config, bySeq, byName, ignoreCase, revcom, outfh, maxMem, tmpDir, mode, selected)
+			return
+		}
+
 		var fastxReader *fastx.Reader
 		var record *fastx.Record
 		var rc *seq.Seq
@@ -553,6 +610,29 @@ Note:
 		// note that it's []string, i.e., records may have same sequences
 		names := make(map[uint64][]string, 1024)
 
+		fileLabels := append([]string{}, files...) // stdin might be replaced with a temporary file
+
+		// target -> index of the first file containing it, for other modes
+		var firstIdx map[uint64]int
+		if mode != commonIntersection {
+			firstIdx = make(map[uint64]int, 1024)
+
+			// all files are read twice
+			checkError(os.MkdirAll(tmpDir, 0755))
+			dir, err := os.MkdirTemp(tmpDir, "seqkit-common-*")
+			checkError(err)
+			defer func() {
+				checkError(os.RemoveAll(dir))
+			}()
+			files, err = copyStdinForRereading(files, dir, quiet)
+			checkError(err)
+		}
+
+		var matrix *commonMatrix
+		if matrixFile != "" {
+			matrix = newCommonMatrix()
+		}
+
 		for i, file := range files {
 			if !quiet {
 				log.Infof("read file %d/%d: %s", i+1, len(files), file)
@@ -611,10 +691,21 @@ Note:
 				if _counter, ok = counter[subject]; !ok {
 					_counter = make(map[int]struct{})
 					counter[subject] = _counter
+
+					if firstIdx != nil {
+						firstIdx[subject] = i
+					}
+					if matrix != nil {
+						if byName {
+							matrix.Add(subject, record.Name)
+						} else {
+							matrix.Add(subject, record.ID)
+						}
+					}
 				}
 				_counter[i] = struct{}{}
 
-				if isFirstFile {
+				if isFirstFile && firstIdx == nil {
 					if _, ok = names[subject]; !ok {
 						names[subject] = make([]string, 0, 1)
 					}
@@ -628,6 +719,45 @@ Note:
 			}
 		}
 
+		if matrix != nil {
+			keyColumn := "id"
+			if byName {
+				keyColumn = "name"
+			}
+			checkError(matrix.Write(matrixFile, keyColumn, fileLabels, counter))
+			if !quiet {
+				log.Infof("presence/absence matrix of %d keys saved to: %s", len(matrix.order), matrixFile)
+			}
+		}
+
+		if mode != commonIntersection {
+			var nHashes int
+			for _, presence := range counter {
+				if selected(len(presence), hasKey(presence, 0)) {
+					nHashes++
+				}
+			}
+			if !quiet {
+				log.Infof("%d unique keys selected in the mode of %s", nHashes, mode)
+			}
+			if nHashes == 0 {
+				return
+			}
+
+			nOutput := commonRetrieve(files, config, outfh, nil, func(i int, _ uint64, record *fastx.Record) bool {
+				subject = rmdupHash(record, bySeq, byName, ignoreCase, revcom)
+				if firstIdx[subject] != i {
+					return false
+				}
+				presence := counter[subject]
+				return selected(len(presence), hasKey(presence, 0))
+			})
+			if !quiet {
+				log.Infof("%d sequences saved to: %s", nOutput, outFile)
+			}
+			return
+		}
+
 		// find common seqs
 		if !quiet {
 			log.Info("find common seqs ...")
@@ -712,4 +842,135 @@ func init() {
 	// commonCmd.Flags().BoolP("consider-revcom", "r", false, "considering the reverse compelment sequence")
 	commonCmd.Flags().BoolP("only-positive-strand", "P", false, "only considering the positive strand when comparing by sequence")
 	commonCmd.Flags().BoolP("check-embedded-seqs", "e", false, "check embedded sequences, e.g., if a sequence is part of another one, we'll keep the shorter one")
+	commonCmd.Flags().StringP("max-mem", "", "", `maximum memory for storing hash values, supported units: K, M, G. Hash values are sorted on disk when exceeded`)
+	commonCmd.Flags().StringP("tmp-dir", "", "./", "temporary directory for saving sorted hash values and data from stdin when using --max-mem, or data from stdin for modes other than intersection")
+	commonCmd.Flags().StringP("mode", "m", commonIntersection, fmt.Sprintf("set operation, available values: %s", strings.Join(commonModes, ", ")))
+	commonCmd.Flags().IntP("min-files", "k", 2, "minimum number of files containing a key, for the mode of at-least")
+	commonCmd.Flags().StringP("matrix", "M", "", "save a presence/absence matrix of all unique keys (rows) and files (columns) to a TSV file")
+}
+
+// commonOnDisk finds common records with hash values sorted on disk.
+// For the mode of intersection, a hash value is shared if it appears in
+// all files, and records of the first file with shared hash values are
+// outputted. For other modes, records of the first file containing a
+// selected hash value are outputted.
+func commonOnDisk(files []string, config Config, bySeq, byName, ignoreCase, revcom bool,
+	outfh *xopen.Writer, maxMem int64, tmpDir string, mode string, selected commonSelector) {
+
+	sorter, err := newHashSorter(tmpDir, maxMem)
+	checkError(err)
+	defer func() {
+		checkError(sorter.Close())
+	}()
+
+	intersection := mode == commonIntersection
+	if intersection {
+		files2, err := copyStdinForRereading(files[:1], sorter.Dir(), config.Quiet)
+		checkError(err)
+		files[0] = files2[0]
+	} else {
+		files, err = copyStdinForRereading(files, sorter.Dir(), config.Quiet)
+		checkError(err)
+	}
+
+	var record *fastx.Record
+	var idx uint64
+	for i, file := range files {
+		if !config.Quiet {
+			log.Infof("read file %d/%d: %s", i+1, len(files), file)
+		}
+		fastxReader, err := fastx.NewReader(config.Alphabet, file, config.IDRegexp)
+		checkError(err)
+		idx = 0
+		for {
+			record, err = fastxReader.Read()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				checkError(err)
+				break
+			}
+			checkError(sorter.Add(rmdupHash(record, bySeq, byName, ignoreCase, revcom), uint32(i), idx, nil))
+			idx++
+		}
+		fastxReader.Close()
+	}
+
+	// find common seqs
+	if !config.Quiet {
+		log.Infof("find common seqs from %d chunks of hash values ...", sorter.Chunks())
+	}
+	fileNum := len(files)
+	hits := make([]bitSet, fileNum) // records to output of each file
+	var nHashes, nOriginalRecords int
+	checkError(sorter.Groups(func(group []hashEntry) error {
+		if intersection && (group[0].File != 0 || group[len(group)-1].File != uint32(fileNum-1)) {
+			return nil
+		}
+		var n, nFirst int
+		var last uint32
+		first := group[0].File
+		for i, e := range group {
+			if i == 0 || e.File != last {
+				n++
+				last = e.File
+			}
+			if e.File == first {
+				nFirst++
+			}
+		}
+		if !selected(n, first == 0) {
+			return nil
+		}
+		nHashes++
+		nOriginalRecords += nFirst
+		for _, e := range group[:nFirst] {
+			hits[first].Set(e.Index)
+		}
+		return nil
+	}))
+
+	var t string
+	if byName {
+		t = "sequence headers"
+	} else if bySeq {
+		t = "sequences"
+	} else {
+		t = "sequence IDs"
+	}
+	if nHashes == 0 {
+		if intersection {
+			log.Infof("no common %s found", t)
+		} else if !config.Quiet {
+			log.Infof("no %s selected in the mode of %s", t, mode)
+		}
+		return
+	}
+	if !config.Quiet {
+		if intersection {
+			log.Infof("%d unique %s found in %d files, which belong to %d records in the first file: %s",
+				nHashes, t, fileNum, nOriginalRecords, files[0])
+		} else {
+			log.Infof("%d unique %s selected in the mode of %s, which belong to %d records",
+				nHashes, t, mode, nOriginalRecords)
+		}
+	}
+
+	// retrieve
+	skip := make([]bool, fileNum)
+	for i, h := range hits {
+		skip[i] = len(h) == 0
+	}
+	nOutput := commonRetrieve(files, config, outfh, skip, func(i int, idx uint64, _ *fastx.Record) bool {
+		return hits[i].Has(idx)
+	})
+
+	if !config.Quiet {
+		if intersection {
+			log.Infof("%d common/shared sequences saved to: %s", nOutput, config.OutFile)
+		} else {
+			log.Infof("%d sequences saved to: %s", nOutput, config.OutFile)
+		}
+	}
 }
@@ -0,0 +1,174 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/shenwei356/bio/seqio/fastx"
+	"github.com/shenwei356/xopen"
+)
+
+// set operations of "seqkit common"
+const (
+	commonIntersection  = "intersection"
+	commonUnion         = "union"
+	commonDifference    = "difference"
+	commonSymDifference = "sym-difference"
+	commonAtLeast       = "at-least"
+)
+
+var commonModes = []string{commonIntersection, commonUnion, commonDifference, commonSymDifference, commonAtLeast}
+
+// commonSelector decides whether a key is selected, with the number of
+// files containing it and whether the first file contains it.
+type commonSelector func(n int, inFirst bool) bool
+
+func newCommonSelector(mode string, fileNum int, k int) (commonSelector, error) {
+	switch mode {
+	case commonIntersection:
+		return func(n int, inFirst bool) bool { return n == fileNum }, nil
+	case commonUnion:
+		return func(n int, inFirst bool) bool { return true }, nil
+	case commonDifference:
+		return func(n int, inFirst bool) bool { return n == 1 && inFirst }, nil
+	case commonSymDifference:
+		return func(n int, inFirst bool) bool { return n == 1 }, nil
+	case commonAtLeast:
+		if k < 1 || k > fileNum {
+			return nil, fmt.Errorf("the value of -k/--min-files (%d) should be in the range of [1, %d]", k, fileNum)
+		}
+		return func(n int, inFirst bool) bool { return n >= k }, nil
+	}
+	return nil, fmt.Errorf("invalid mode: %s. available: %s", mode, strings.Join(commonModes, ", "))
+}
+
+// hasKey tells whether a file index exists in the presence set.
+func hasKey(presence map[int]struct{}, i int) bool {
+	_, ok := presence[i]
+	return ok
+}
+
+// commonRetrieve outputs records passing the keep function from files,
+// and returns the number of outputted records. Files with skip[i] being
+// true are not read.
+func commonRetrieve(files []string, config Config, outfh *xopen.Writer, skip []bool,
+	keep func(i int, idx uint64, record *fastx.Record) bool) int {
+
+	var record *fastx.Record
+	var idx uint64
+	var nOutput int
+	checkFormat := true
+	for i, file := range files {
+		if skip != nil && skip[i] {
+			continue
+		}
+		fastxReader, err := fastx.NewReader(config.Alphabet, file, config.IDRegexp)
+		checkError(err)
+		idx = 0
+		for {
+			record, err = fastxReader.Read()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				checkError(err)
+				break
+			}
+			if checkFormat {
+				checkFormat = false
+				if fastxReader.IsFastq {
+					if !config.LineWidthChanged {
+						config.LineWidth = 0
+					}
+					fastx.ForcelyOutputFastq = true
+				}
+			}
+
+			if keep(i, idx, record) {
+				nOutput++
+				record.FormatToWriter(outfh, config.LineWidth)
+			}
+			idx++
+		}
+		fastxReader.Close()
+	}
+	return nOutput
+}
+
+// commonMatrix is a presence/absence matrix of keys in files.
+type commonMatrix struct {
+	order  []uint64          // keys in the order of first appearance
+	labels map[uint64]string // ID or name of the first record with the key
+}
+
+func newCommonMatrix() *commonMatrix {
+	return &commonMatrix{
+		order:  make([]uint64, 0, 1024),
+		labels: make(map[uint64]string, 1024),
+	}
+}
+
+// Add adds a key, only the label of the first record is saved.
+func (m *commonMatrix) Add(key uint64, label []byte) {
+	if _, ok := m.labels[key]; ok {
+		return
+	}
+	m.order = append(m.order, key)
+	m.labels[key] = string(label)
+}
+
+// Write outputs the matrix in TSV format, with a header line of the key
+// column and file names, and 1 or 0 for presence or absence.
+func (m *commonMatrix) Write(file string, keyColumn string, files []string,
+	presence map[uint64]map[int]struct{}) error {
+
+	outfh, err := xopen.Wopen(file)
+	if err != nil {
+		return err
+	}
+	defer outfh.Close()
+
+	outfh.WriteString(keyColumn)
+	for _, f := range files {
+		outfh.WriteString("\t")
+		outfh.WriteString(f)
+	}
+	outfh.WriteString("\n")
+
+	var ok bool
+	var p map[int]struct{}
+	for _, key := range m.order {
+		outfh.WriteString(m.labels[key])
+		p = presence[key]
+		for i := range files {
+			if _, ok = p[i]; ok {
+				outfh.WriteString("\t1")
+			} else {
+				outfh.WriteString("\t0")
+			}
+		}
+		outfh.WriteString("\n")
+	}
+	return nil
+}
@@ -0,0 +1,459 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/shenwei356/bio/seq"
+	"github.com/shenwei356/bio/seqio/fastx"
+	"github.com/shenwei356/xopen"
+	"github.com/spf13/cobra"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	GroupID: "set",
+
+	Use:   "diff",
+	Short: "compare two FASTA/Q files and report differences of records",
+	Long: `compare two FASTA/Q files and report differences of records
+
+Records of two files (A and B) are matched by ID (default), full name
+(-n/--by-name), or sequence (-s/--by-seq), with xxhash values of the keys
+and sequences like 'seqkit common'. Status of records:
+
+  identical      the same ID, description and sequence, only outputted with -a/--all
+  seq_changed    the same ID (or name), different sequences
+  desc_changed   the same ID and sequence, different descriptions
+  renamed        the same sequence, different IDs
+  only_in_a      records only in file A
+  only_in_b      records only in file B
+
+Matching:
+  1. Records with the same ID (or name) and sequence are matched first.
+  2. By ID or name, remaining records with the same key are compared, then
+     the remaining ones with the same sequence are "renamed".
+  3. By sequence, remaining records with the same sequence are compared,
+     then the remaining ones with the same ID are "seq_changed".
+  4. Records with duplicated keys are matched in the order of appearance.
+
+Edit summary of "seq_changed" records:
+  For sequences not longer than --max-align-len, numbers of substitutions,
+  insertions, and deletions (from A to B) are computed with a global alignment
+  (edit distance). Otherwise, only the length change is reported, and the
+  others are "NA".
+
+Output (TSV) columns:
+  status, id_a, id_b, len_a, len_b, len_change, subs, ins, dels, desc_a, desc_b
+
+  -u/--unified outputs a human-readable unified-diff style format, where
+  records are shown with '-' for A and '+' for B. Sequences not longer than
+  --max-align-len are also shown for "seq_changed" records.
+
+Attention:
+  1. Only the positive strand is compared by default, please switch on
+     -r/--both-strands to treat reverse complement sequences as the same.
+  2. Sequences are case-sensitive unless -i/--ignore-case is given.
+  3. Records are outputted in the order of file A, followed by records
+     only in file B.
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := getConfigs(cmd)
+		alphabet := config.Alphabet
+		idRegexp := config.IDRegexp
+		outFile := config.OutFile
+		quiet := config.Quiet
+		seq.AlphabetGuessSeqLengthThreshold = config.AlphabetGuessSeqLength
+		seq.ValidateSeq = false
+		runtime.GOMAXPROCS(config.Threads)
+
+		bySeq := getFlagBool(cmd, "by-seq")
+		byName := getFlagBool(cmd, "by-name")
+		ignoreCase := getFlagBool(cmd, "ignore-case")
+		bothStrands := getFlagBool(cmd, "both-strands")
+		maxAlignLen := getFlagNonNegativeInt(cmd, "max-align-len")
+		all := getFlagBool(cmd, "all")
+		unified := getFlagBool(cmd, "unified")
+
+		if bySeq && byName {
+			checkError(fmt.Errorf("only one/none of the flags -s (--by-seq) and -n (--by-name) is allowed"))
+		}
+
+		files := getFileListFromArgsAndFile(cmd, args, true, "infile-list", !config.SkipFileCheck)
+		if len(files) != 2 {
+			checkError(fmt.Errorf("two files needed"))
+		}
+		if isStdin(files[0]) && isStdin(files[1]) {
+			checkError(fmt.Errorf("only one file could be read from stdin"))
+		}
+		if !config.SkipFileCheck {
+			for _, file := range files {
+				checkIfFilesAreTheSame(file, outFile, "input", "output")
+			}
+		}
+
+		opt := &diffOptions{
+			byName:      byName,
+			ignoreCase:  ignoreCase,
+			bothStrands: bothStrands,
+			maxAlignLen: maxAlignLen,
+		}
+		var recordsAB [2][]*diffRecord
+		var err error
+		for i, file := range files {
+			recordsAB[i], err = readDiffRecords(file, alphabet, idRegexp, opt)
+			checkError(err)
+			if !quiet {
+				log.Infof("%d records loaded from file %s: %s", len(recordsAB[i]), "AB"[i:i+1], file)
+			}
+		}
+
+		results := compareDiffRecords(recordsAB[0], recordsAB[1], bySeq, maxAlignLen)
+
+		outfh, err := xopen.Wopen(outFile)
+		checkError(err)
+		defer outfh.Close()
+
+		if unified {
+			fmt.Fprintf(outfh, "--- %s\n+++ %s\n", files[0], files[1])
+		} else {
+			outfh.WriteString("status\tid_a\tid_b\tlen_a\tlen_b\tlen_change\tsubs\tins\tdels\tdesc_a\tdesc_b\n")
+		}
+
+		counts := make(map[string]int, 6)
+		for _, r := range results {
+			counts[r.status]++
+			if r.status == diffIdentical && !all {
+				continue
+			}
+			if unified {
+				r.writeUnified(outfh)
+			} else {
+				r.writeTSV(outfh)
+			}
+		}
+
+		if !quiet {
+			log.Infof("%d identical, %d seq_changed, %d desc_changed, %d renamed, %d only_in_a, %d only_in_b",
+				counts[diffIdentical], counts[diffSeqChanged], counts[diffDescChanged],
+				counts[diffRenamed], counts[diffOnlyInA], counts[diffOnlyInB])
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().BoolP("by-name", "n", false, "match by full name instead of just id")
+	diffCmd.Flags().BoolP("by-seq", "s", false, "match by sequence")
+	diffCmd.Flags().BoolP("ignore-case", "i", false, "ignore case of sequences")
+	diffCmd.Flags().BoolP("both-strands", "r", false, "treat reverse complement sequences as the same")
+	diffCmd.Flags().IntP("max-align-len", "L", 1000, "maximum sequence length for computing numbers of substitutions and indels of changed sequences, 0 for disabling it")
+	diffCmd.Flags().BoolP("all", "a", false, "also output identical records")
+	diffCmd.Flags().BoolP("unified", "u", false, "output in a human-readable unified-diff style format")
+}
+
+const (
+	diffIdentical   = "identical"
+	diffSeqChanged  = "seq_changed"
+	diffDescChanged = "desc_changed"
+	diffRenamed     = "renamed"
+	diffOnlyInA     = "only_in_a"
+	diffOnlyInB     = "only_in_b"
+)
+
+type diffOptions struct {
+	byName      bool
+	ignoreCase  bool
+	bothStrands bool
+	maxAlignLen int
+}
+
+// diffRecord holds information of a record for comparison.
+type diffRecord struct {
+	id   string // ID or full name, used as the key
+	desc string
+
+	hash uint64 // hash value of the sequence
+	len  int
+	seq  []byte // only saved for sequences not longer than maxAlignLen
+}
+
+// readDiffRecords reads all records of a file.
+func readDiffRecords(file string, alphabet *seq.Alphabet, idRegexp string, opt *diffOptions) ([]*diffRecord, error) {
+	fastxReader, err := fastx.NewReader(alphabet, file, idRegexp)
+	if err != nil {
+		return nil, err
+	}
+	defer fastxReader.Close()
+
+	records := make([]*diffRecord, 0, 1024)
+	var record *fastx.Record
+	var s, rc []byte
+	var h, hRC uint64
+	for {
+		record, err = fastxReader.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		s = record.Seq.Seq
+		if opt.ignoreCase {
+			s = bytes.ToLower(s)
+		}
+		h = xxhash.Sum64(s)
+		if opt.bothStrands {
+			rc = record.Seq.RevCom().Seq
+			if opt.ignoreCase {
+				rc = bytes.ToLower(rc)
+			}
+			hRC = xxhash.Sum64(rc)
+			if hRC < h { // only save the smaller one, like keeping canonical k-mers
+				h = hRC
+			}
+		}
+
+		r := &diffRecord{
+			desc: string(record.Desc),
+			hash: h,
+			len:  len(s),
+		}
+		if opt.byName { // the description is a part of the name
+			r.id, r.desc = string(record.Name), ""
+		} else {
+			r.id = string(record.ID)
+		}
+		if len(s) <= opt.maxAlignLen {
+			r.seq = []byte(string(s))
+		}
+		records = append(records, r)
+	}
+
+	return records, nil
+}
+
+// diffResult is the comparison result of a record in A and/or B.
+type diffResult struct {
+	status string
+	a, b   *diffRecord
+
+	aligned         bool
+	subs, ins, dels int
+}
+
+// compareDiffRecords matches records of A and B, and returns the results
+// in the order of A, followed by records only in B.
+func compareDiffRecords(as, bs []*diffRecord, bySeq bool, maxAlignLen int) []*diffResult {
+	matchedA := make([]*diffResult, len(as))
+	usedA := make([]bool, len(as))
+	usedB := make([]bool, len(bs))
+
+	match := func(key func(r *diffRecord) string, status func(a, b *diffRecord) string) {
+		matchByKey(usedA, usedB,
+			func(i int) string { return key(as[i]) },
+			func(j int) string { return key(bs[j]) },
+			func(i, j int) {
+				matchedA[i] = &diffResult{status: status(as[i], bs[j]), a: as[i], b: bs[j]}
+			})
+	}
+
+	byID := func(r *diffRecord) string { return r.id }
+	byHash := func(r *diffRecord) string { return strconv.FormatUint(r.hash, 16) }
+	byIDAndHash := func(r *diffRecord) string { return r.id + "\t" + byHash(r) }
+	statusSameID := func(a, b *diffRecord) string {
+		if a.hash != b.hash {
+			return diffSeqChanged
+		}
+		if a.desc != b.desc {
+			return diffDescChanged
+		}
+		return diffIdentical
+	}
+	statusSameSeq := func(a, b *diffRecord) string {
+		if a.id != b.id {
+			return diffRenamed
+		}
+		if a.desc != b.desc {
+			return diffDescChanged
+		}
+		return diffIdentical
+	}
+
+	// records with the same ID and sequence are matched first, so that
+	// duplicated IDs in different orders are not reported as changed.
+	match(byIDAndHash, statusSameID)
+	if bySeq {
+		match(byHash, statusSameSeq)
+		match(byID, statusSameID)
+	} else {
+		match(byID, statusSameID)
+		match(byHash, statusSameSeq)
+	}
+
+	results := make([]*diffResult, 0, len(as)+len(bs))
+	for i, r := range matchedA {
+		if r == nil {
+			r = &diffResult{status: diffOnlyInA, a: as[i]}
+		} else if r.status == diffSeqChanged && maxAlignLen > 0 &&
+			r.a.seq != nil && r.b.seq != nil {
+			r.aligned = true
+			r.subs, r.ins, r.dels = seqEditSummary(r.a.seq, r.b.seq)
+		}
+		results = append(results, r)
+	}
+	for j, b := range bs {
+		if !usedB[j] {
+			results = append(results, &diffResult{status: diffOnlyInB, b: b})
+		}
+	}
+	return results
+}
+
+func (r *diffResult) writeTSV(outfh *xopen.Writer) {
+	var idA, idB, lenA, lenB, lenChange, descA, descB string
+	subs, ins, dels := "NA", "NA", "NA"
+	if r.a != nil {
+		idA, lenA, descA = r.a.id, strconv.Itoa(r.a.len), r.a.desc
+	}
+	if r.b != nil {
+		idB, lenB, descB = r.b.id, strconv.Itoa(r.b.len), r.b.desc
+	}
+	if r.a != nil && r.b != nil {
+		lenChange = strconv.Itoa(r.b.len - r.a.len)
+		if r.status != diffSeqChanged {
+			subs, ins, dels = "0", "0", "0"
+		} else if r.aligned {
+			subs, ins, dels = strconv.Itoa(r.subs), strconv.Itoa(r.ins), strconv.Itoa(r.dels)
+		}
+	}
+	fmt.Fprintf(outfh, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+		r.status, idA, idB, lenA, lenB, lenChange, subs, ins, dels, descA, descB)
+}
+
+func (r *diffResult) writeUnified(outfh *xopen.Writer) {
+	head := func(d *diffRecord) string {
+		if d.desc == "" {
+			return d.id
+		}
+		return d.id + " " + d.desc
+	}
+
+	switch r.status {
+	case diffOnlyInA:
+		fmt.Fprintf(outfh, "@@ only in A: %s, %d bp @@\n-%s\n", r.a.id, r.a.len, head(r.a))
+	case diffOnlyInB:
+		fmt.Fprintf(outfh, "@@ only in B: %s, %d bp @@\n+%s\n", r.b.id, r.b.len, head(r.b))
+	case diffRenamed:
+		fmt.Fprintf(outfh, "@@ renamed: %s -> %s, %d bp @@\n-%s\n+%s\n", r.a.id, r.b.id, r.a.len, head(r.a), head(r.b))
+	case diffDescChanged:
+		fmt.Fprintf(outfh, "@@ description changed: %s @@\n-%s\n+%s\n", r.a.id, head(r.a), head(r.b))
+	case diffSeqChanged:
+		fmt.Fprintf(outfh, "@@ sequence changed: %s, %d bp -> %d bp", r.a.id, r.a.len, r.b.len)
+		if r.aligned {
+			fmt.Fprintf(outfh, ", %d substitutions, %d insertions, %d deletions", r.subs, r.ins, r.dels)
+		}
+		if r.a.seq != nil && r.b.seq != nil { // short sequences are also shown
+			fmt.Fprintf(outfh, " @@\n-%s\n-%s\n+%s\n+%s\n", head(r.a), r.a.seq, head(r.b), r.b.seq)
+		} else {
+			fmt.Fprintf(outfh, " @@\n-%s\n+%s\n", head(r.a), head(r.b))
+		}
+	case diffIdentical:
+		fmt.Fprintf(outfh, "@@ identical: %s, %d bp @@\n %s\n", r.a.id, r.a.len, head(r.a))
+	}
+}
+
+// seqEditSummary returns the numbers of substitutions, insertions and
+// deletions from a to b, with a global alignment of the minimum edit distance.
+func seqEditSummary(a, b []byte) (subs, ins, dels int) {
+	if len(a) == len(b) { // a quick check for substitutions only
+		for i := range a {
+			if a[i] != b[i] {
+				subs++
+			}
+		}
+		if subs <= 1 {
+			return subs, 0, 0
+		}
+		subs = 0
+	}
+
+	n, m := len(a), len(b)
+	// operations: 0 for match/substitution, 1 for insertion, 2 for deletion
+	ops := make([]byte, (n+1)*(m+1))
+	prev := make([]int, m+1)
+	cur := make([]int, m+1)
+	for j := 1; j <= m; j++ {
+		prev[j] = j
+		ops[j] = 1
+	}
+	var cost, c int
+	for i := 1; i <= n; i++ {
+		cur[0] = i
+		ops[i*(m+1)] = 2
+		for j := 1; j <= m; j++ {
+			cost = prev[j-1]
+			if a[i-1] != b[j-1] {
+				cost++
+			}
+			ops[i*(m+1)+j] = 0
+			if c = cur[j-1] + 1; c < cost {
+				cost = c
+				ops[i*(m+1)+j] = 1
+			}
+			if c = prev[j] + 1; c < cost {
+				cost = c
+				ops[i*(m+1)+j] = 2
+			}
+			cur[j] = cost
+		}
+		prev, cur = cur, prev
+	}
+
+	// trace back
+	i, j := n, m
+	for i > 0 || j > 0 {
+		switch ops[i*(m+1)+j] {
+		case 0:
+			if a[i-1] != b[j-1] {
+				subs++
+			}
+			i--
+			j--
+		case 1:
+			ins++
+			j--
+		case 2:
+			dels++
+			i--
+		}
+	}
+	return subs, ins, dels
+}
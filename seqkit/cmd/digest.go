@@ -0,0 +1,736 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shenwei356/bio/seq"
+	"github.com/shenwei356/bio/seqio/fastx"
+	"github.com/shenwei356/util/byteutil"
+	"github.com/shenwei356/xopen"
+	"github.com/spf13/cobra"
+)
+
+// digestCmd represents the digest command
+var digestCmd = &cobra.Command{
+	GroupID: "search",
+
+	Use:   "digest",
+	Short: "in-silico restriction digestion of DNA sequences",
+	Long: `in-silico restriction digestion of DNA sequences
+
+Attention:
+
+  1. Enzymes are given by names (case-insensitive) via -e/--enzyme,
+     multiple enzymes (e.g., -e EcoRI,MseI) are used for double digests.
+     Use -L/--list-enzymes to list all available enzymes.
+  2. Custom enzymes can be given via -E/--enzyme-file, a tab-delimited file
+     with two columns: name and recognition site with cut positions in
+     REBASE notation. Lines starting with "#" are ignored. Custom enzymes
+     override built-in ones with the same name. If -e/--enzyme is not
+     given, all enzymes in the file are used.
+       EcoRI   G^AATTC       the cut position of the top strand is marked
+                             by "^", the bottom strand is cut symmetrically.
+       BsaI    GGTCTC(1/5)   cut positions downstream of the site,
+                             for the top and bottom strand respectively.
+  3. Degenerate bases like "RYMM.." are supported in recognition sites,
+     and sites are searched on both strands, like 'seqkit locate -d'.
+  4. Only cuts with both strands cut inside the sequence are used.
+     For circular sequences (-c/--circular), sites and cuts across the
+     origin are also detected, and the end position of fragments crossing
+     the origin would be greater than the sequence length.
+  5. Sticky ends are shown as the overhang type and the overhang sequence
+     on the top strand, e.g., 5'AATT for EcoRI, 3'TGCA for PstI, and
+     "blunt" for blunt ends. Sequence ends are shown as ".".
+
+Output formats:
+
+  default   fragments in tab-delimited format with columns:
+              seqID, fragment, begin, end, length,
+              left_enzyme, left_end, right_enzyme, right_end
+  -F        FASTA of fragments, with the sequence ID of
+              <seqID>_fragment=<N>_begin=<begin>_end=<end>
+  --bed     BED6 of fragments, with <seqID>_fragment<N> as the name.
+  -S        cut sites in tab-delimited format with columns:
+              seqID, enzyme, site, begin, end, strand, cut, end_type
+            where begin and end are locations of recognition sites,
+            and cut is the position of the cut on the top strand (after it).
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := getConfigs(cmd)
+		alphabet := config.Alphabet
+		idRegexp := config.IDRegexp
+		lineWidth := config.LineWidth
+		outFile := config.OutFile
+		seq.AlphabetGuessSeqLengthThreshold = config.AlphabetGuessSeqLength
+		seq.ValidateSeq = false
+		runtime.GOMAXPROCS(config.Threads)
+
+		enzymeNames := getFlagStringSlice(cmd, "enzyme")
+		enzymeFile := getFlagString(cmd, "enzyme-file")
+		listEnzymes := getFlagBool(cmd, "list-enzymes")
+		circular := getFlagBool(cmd, "circular")
+		minLen := getFlagNonNegativeInt(cmd, "min-len")
+		maxLen := getFlagNonNegativeInt(cmd, "max-len")
+		outFASTA := getFlagBool(cmd, "fasta")
+		outFmtBED := getFlagBool(cmd, "bed")
+		outSites := getFlagBool(cmd, "sites")
+		if maxLen > 0 && minLen > maxLen {
+			checkError(fmt.Errorf("value of -m/--min-len (%d) should not be greater than that of -M/--max-len (%d)", minLen, maxLen))
+		}
+		var nFmt int
+		for _, v := range []bool{outFASTA, outFmtBED, outSites} {
+			if v {
+				nFmt++
+			}
+		}
+		if nFmt > 1 {
+			checkError(fmt.Errorf("flag -F/--fasta, --bed and -S/--sites are not compatible"))
+		}
+
+		enzymes, names, err := builtinEnzymes()
+		checkError(err)
+		var namesInFile []string
+		if enzymeFile != "" {
+			var custom map[string]*Enzyme
+			custom, namesInFile, err = readEnzymes(enzymeFile)
+			checkError(err)
+			for _, name := range namesInFile {
+				if _, ok := enzymes[strings.ToLower(name)]; !ok {
+					names = append(names, name)
+				}
+				enzymes[strings.ToLower(name)] = custom[strings.ToLower(name)]
+			}
+		}
+
+		outfh, err := xopen.Wopen(outFile)
+		checkError(err)
+		defer outfh.Close()
+
+		if listEnzymes {
+			outfh.WriteString("enzyme\tsite\tend_type\n")
+			var e *Enzyme
+			for _, name := range names {
+				e = enzymes[strings.ToLower(name)]
+				outfh.WriteString(fmt.Sprintf("%s\t%s\t%s\n", e.Name, e.Notation(), e.EndType()))
+			}
+			return
+		}
+
+		if len(enzymeNames) == 0 {
+			if len(namesInFile) == 0 {
+				checkError(fmt.Errorf("flag -e/--enzyme needed"))
+			}
+			enzymeNames = namesInFile
+		}
+		selected := make([]*Enzyme, 0, len(enzymeNames))
+		var e *Enzyme
+		var ok bool
+		for _, name := range enzymeNames {
+			if e, ok = enzymes[strings.ToLower(name)]; !ok {
+				checkError(fmt.Errorf(`unknown enzyme: %s, use "seqkit digest -L" to list all available enzymes`, name))
+			}
+			selected = append(selected, e)
+		}
+
+		files := getFileListFromArgsAndFile(cmd, args, true, "infile-list", !config.SkipFileCheck)
+		if !config.SkipFileCheck {
+			for _, file := range files {
+				checkIfFilesAreTheSame(file, outFile, "input", "output")
+			}
+		}
+
+		if !(outFASTA || outFmtBED) {
+			if outSites {
+				outfh.WriteString("seqID\tenzyme\tsite\tbegin\tend\tstrand\tcut\tend_type\n")
+			} else {
+				outfh.WriteString("seqID\tfragment\tbegin\tend\tlength\tleft_enzyme\tleft_end\tright_enzyme\tright_end\n")
+			}
+		}
+
+		var record *fastx.Record
+		var cuts []DigestCut
+		var c DigestCut
+		var frags []DigestFragment
+		var f DigestFragment
+		var i, l int
+		var name string
+		once := true
+		for _, file := range files {
+			fastxReader, err := fastx.NewReader(alphabet, file, idRegexp)
+			checkError(err)
+
+			for {
+				record, err = fastxReader.Read()
+				if err != nil {
+					if err == io.EOF {
+						break
+					}
+					checkError(err)
+					break
+				}
+
+				if once {
+					if !(record.Seq.Alphabet == seq.DNA || record.Seq.Alphabet == seq.DNAredundant) {
+						checkError(fmt.Errorf(`command 'seqkit digest' only apply to DNA sequences`))
+					}
+					once = false
+				}
+
+				l = len(record.Seq.Seq)
+				cuts = cuts[:0]
+				for _, e = range selected {
+					cuts = e.Cuts(cuts, record.Seq.Seq, circular)
+				}
+
+				if outSites {
+					sortDigestCuts(cuts)
+					for _, c = range cuts {
+						outfh.WriteString(fmt.Sprintf("%s\t%s\t%s\t%d\t%d\t%c\t%d\t%s\n",
+							record.ID,
+							c.Enzyme.Name,
+							c.Enzyme.Notation(),
+							c.Begin+1,
+							c.Begin+len(c.Enzyme.Site),
+							c.Strand,
+							c.Top,
+							c.End(record.Seq.Seq)))
+					}
+					continue
+				}
+
+				frags = digestFragments(cuts, l, circular)
+
+				i = 0
+				for _, f = range frags {
+					if f.Len() < minLen || (maxLen > 0 && f.Len() > maxLen) {
+						continue
+					}
+					i++
+
+					if outFmtBED {
+						outfh.WriteString(fmt.Sprintf("%s\t%d\t%d\t%s_fragment%d\t%d\t%c\n",
+							record.ID,
+							f.Begin,
+							f.End,
+							record.ID, f.Index,
+							0,
+							'+'))
+						continue
+					} else if outFASTA {
+						name = fmt.Sprintf("%s_fragment=%d_begin=%d_end=%d", record.ID, f.Index, f.Begin+1, f.End)
+						outfh.WriteString(fmt.Sprintf(">%s left=%s:%s right=%s:%s\n", name,
+							f.LeftEnzyme(), f.LeftEnd(record.Seq.Seq),
+							f.RightEnzyme(), f.RightEnd(record.Seq.Seq)))
+						outfh.Write(byteutil.WrapByteSlice(f.Subseq(record.Seq.Seq), lineWidth))
+						outfh.WriteString("\n")
+						continue
+					}
+
+					outfh.WriteString(fmt.Sprintf("%s\t%d\t%d\t%d\t%d\t%s\t%s\t%s\t%s\n",
+						record.ID,
+						f.Index,
+						f.Begin+1,
+						f.End,
+						f.Len(),
+						f.LeftEnzyme(), f.LeftEnd(record.Seq.Seq),
+						f.RightEnzyme(), f.RightEnd(record.Seq.Seq)))
+				}
+			}
+			fastxReader.Close()
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(digestCmd)
+
+	digestCmd.Flags().StringSliceP("enzyme", "e", []string{}, `enzyme name(s), multiple values for double digests, e.g., -e EcoRI,MseI`)
+	digestCmd.Flags().StringP("enzyme-file", "E", "", `tab-delimited file of custom enzymes: name and site in REBASE notation, e.g., "EcoRI G^AATTC" or "BsaI GGTCTC(1/5)"`)
+	digestCmd.Flags().BoolP("list-enzymes", "L", false, `list all available enzymes`)
+	digestCmd.Flags().BoolP("circular", "c", false, `circular genome. type "seqkit digest -h" for details`)
+	digestCmd.Flags().IntP("min-len", "m", 0, `minimum length of fragments to output`)
+	digestCmd.Flags().IntP("max-len", "M", 0, `maximum length of fragments to output, 0 for no limit`)
+	digestCmd.Flags().BoolP("fasta", "F", false, `output fragments in FASTA format`)
+	digestCmd.Flags().BoolP("bed", "", false, `output fragments in BED6 format`)
+	digestCmd.Flags().BoolP("sites", "S", false, `output cut sites instead of fragments`)
+}
+
+// Enzyme is a restriction enzyme.
+// Cut positions are 0-based offsets relative to the start of the recognition
+// site on the top strand, i.e., the enzyme cuts the top strand between
+// Site[Cut1-1] and Site[Cut1], and the bottom strand between Site[Cut2-1]
+// and Site[Cut2].
+type Enzyme struct {
+	Name string
+	Site string
+	Cut1 int // cut position on the top strand
+	Cut2 int // cut position on the bottom strand
+
+	caret bool // REBASE notation with "^"
+
+	re   *regexp.Regexp // regular expression of the site
+	reRC *regexp.Regexp // regular expression of reverse complement of the site, nil for palindromic sites
+}
+
+// NewEnzyme parses a recognition site in REBASE notation, e.g., "G^AATTC" and "GGTCTC(1/5)".
+func NewEnzyme(name, site string) (*Enzyme, error) {
+	e := &Enzyme{Name: name}
+	s := strings.ToUpper(strings.TrimSpace(site))
+
+	if i := strings.IndexByte(s, '('); i >= 0 {
+		if !strings.HasSuffix(s, ")") {
+			return nil, fmt.Errorf("invalid site of enzyme %s: %s", name, site)
+		}
+		items := strings.Split(s[i+1:len(s)-1], "/")
+		if len(items) != 2 {
+			return nil, fmt.Errorf("invalid cut positions of enzyme %s: %s", name, site)
+		}
+		n, err1 := strconv.Atoi(items[0])
+		m, err2 := strconv.Atoi(items[1])
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid cut positions of enzyme %s: %s", name, site)
+		}
+		e.Site = s[:i]
+		e.Cut1 = len(e.Site) + n
+		e.Cut2 = len(e.Site) + m
+	} else if i := strings.IndexByte(s, '^'); i >= 0 {
+		e.Site = s[:i] + s[i+1:]
+		e.Cut1 = i
+		e.Cut2 = len(e.Site) - i
+		e.caret = true
+	} else {
+		return nil, fmt.Errorf(`no cut position given for enzyme %s: %s, please mark it with "^" or "(n/m)"`, name, site)
+	}
+
+	if len(e.Site) == 0 || strings.ContainsAny(e.Site, "^()") {
+		return nil, fmt.Errorf("invalid site of enzyme %s: %s", name, site)
+	}
+
+	s1, err := seq.NewSeq(seq.DNAredundant, []byte(e.Site))
+	if err != nil {
+		return nil, fmt.Errorf("invalid site of enzyme %s: %s", name, site)
+	}
+	e.re, err = regexp.Compile("(?i)" + s1.Degenerate2Regexp())
+	if err != nil {
+		return nil, err
+	}
+
+	s2 := s1.RevCom()
+	if string(s2.Seq) != e.Site {
+		e.reRC, err = regexp.Compile("(?i)" + s2.Degenerate2Regexp())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+// Notation returns the recognition site in REBASE notation.
+func (e *Enzyme) Notation() string {
+	if e.caret {
+		return e.Site[:e.Cut1] + "^" + e.Site[e.Cut1:]
+	}
+	return fmt.Sprintf("%s(%d/%d)", e.Site, e.Cut1-len(e.Site), e.Cut2-len(e.Site))
+}
+
+// EndType returns the type of ends, e.g., 5'(4), 3'(4), and blunt.
+func (e *Enzyme) EndType() string {
+	d := e.Cut2 - e.Cut1
+	if d > 0 {
+		return fmt.Sprintf("5'(%d)", d)
+	} else if d < 0 {
+		return fmt.Sprintf("3'(%d)", -d)
+	}
+	return "blunt"
+}
+
+// DigestCut is a cut of an enzyme in a sequence.
+type DigestCut struct {
+	Enzyme *Enzyme
+	Begin  int  // 0-based start position of the recognition site
+	Strand byte // strand of the recognition site
+
+	Top      int // cut position on the top strand, in [1, len(seq))
+	Overhang int // cut position on the bottom strand minus Top
+}
+
+// End returns the end type and overhang sequence on the top strand,
+// e.g., 5'AATT, 3'TGCA, and blunt.
+func (c DigestCut) End(s []byte) string {
+	if c.Overhang == 0 {
+		return "blunt"
+	}
+	l := len(s)
+	var b, e int
+	var prefix string
+	if c.Overhang > 0 {
+		b, e = c.Top, c.Top+c.Overhang
+		prefix = "5'"
+	} else {
+		b, e = c.Top+c.Overhang, c.Top
+		prefix = "3'"
+	}
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	for i := b; i < e; i++ {
+		sb.WriteByte(s[((i%l)+l)%l])
+	}
+	return sb.String()
+}
+
+// Cuts finds all cuts of the enzyme in a sequence and appends them to cuts.
+func (e *Enzyme) Cuts(cuts []DigestCut, s []byte, circular bool) []DigestCut {
+	l := len(s)
+	if l == 0 {
+		return cuts
+	}
+	ls := len(e.Site)
+
+	var s2 []byte
+	if circular { // concat two copies of sequence
+		s2 = make([]byte, 2*l)
+		copy(s2, s)
+		copy(s2[l:], s)
+	} else {
+		s2 = s
+	}
+
+	var loc []int
+	var offset, p, top, bottom int
+	for _, re := range []*regexp.Regexp{e.re, e.reRC} {
+		if re == nil {
+			continue
+		}
+		offset = 0
+		for offset < len(s2) {
+			loc = re.FindIndex(s2[offset:])
+			if loc == nil {
+				break
+			}
+			p = offset + loc[0]
+			offset = p + 1
+			if circular && p >= l { // 2nd clone of original part
+				break
+			}
+
+			if re == e.re {
+				top, bottom = p+e.Cut1, p+e.Cut2
+			} else {
+				top, bottom = p+ls-e.Cut2, p+ls-e.Cut1
+			}
+
+			if circular {
+				bottom -= top
+				top = ((top % l) + l) % l
+				if top == 0 { // the origin is treated as position l
+					top = l
+				}
+				bottom += top
+			} else if top <= 0 || top >= l || bottom <= 0 || bottom >= l {
+				continue
+			}
+
+			if re == e.re {
+				cuts = append(cuts, DigestCut{Enzyme: e, Begin: p, Strand: '+', Top: top, Overhang: bottom - top})
+			} else {
+				cuts = append(cuts, DigestCut{Enzyme: e, Begin: p, Strand: '-', Top: top, Overhang: bottom - top})
+			}
+		}
+	}
+	return cuts
+}
+
+func sortDigestCuts(cuts []DigestCut) {
+	sort.Slice(cuts, func(i, j int) bool {
+		if cuts[i].Top == cuts[j].Top {
+			if cuts[i].Begin == cuts[j].Begin {
+				return cuts[i].Enzyme.Name < cuts[j].Enzyme.Name
+			}
+			return cuts[i].Begin < cuts[j].Begin
+		}
+		return cuts[i].Top < cuts[j].Top
+	})
+}
+
+// DigestFragment is a fragment of a digested sequence.
+type DigestFragment struct {
+	Index int
+	Begin int // 0-based
+	End   int // 0-based, exclusive, might be greater than the sequence length for circular sequences
+
+	Left  []DigestCut // cuts at the left end, nil for the sequence start
+	Right []DigestCut // cuts at the right end, nil for the sequence end
+}
+
+// Len returns the length of the fragment.
+func (f DigestFragment) Len() int {
+	return f.End - f.Begin
+}
+
+// Subseq returns the sequence of the fragment.
+func (f DigestFragment) Subseq(s []byte) []byte {
+	l := len(s)
+	if f.End <= l {
+		return s[f.Begin:f.End]
+	}
+	sub := make([]byte, 0, f.Len())
+	sub = append(sub, s[f.Begin:]...)
+	return append(sub, s[:f.End-l]...)
+}
+
+// LeftEnzyme returns names of enzymes at the left end.
+func (f DigestFragment) LeftEnzyme() string {
+	return digestCutsEnzymes(f.Left)
+}
+
+// RightEnzyme returns names of enzymes at the right end.
+func (f DigestFragment) RightEnzyme() string {
+	return digestCutsEnzymes(f.Right)
+}
+
+// LeftEnd returns end types at the left end.
+func (f DigestFragment) LeftEnd(s []byte) string {
+	return digestCutsEnds(f.Left, s)
+}
+
+// RightEnd returns end types at the right end.
+func (f DigestFragment) RightEnd(s []byte) string {
+	return digestCutsEnds(f.Right, s)
+}
+
+func digestCutsEnzymes(cuts []DigestCut) string {
+	if len(cuts) == 0 {
+		return "."
+	}
+	names := make([]string, 0, len(cuts))
+	for _, c := range cuts {
+		names = append(names, c.Enzyme.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func digestCutsEnds(cuts []DigestCut, s []byte) string {
+	if len(cuts) == 0 {
+		return "."
+	}
+	ends := make([]string, 0, len(cuts))
+	for _, c := range cuts {
+		ends = append(ends, c.End(s))
+	}
+	return strings.Join(ends, ",")
+}
+
+// digestFragments returns fragments produced by the cuts.
+// Cuts at the same position on the top strand are merged.
+func digestFragments(cuts []DigestCut, l int, circular bool) []DigestFragment {
+	sortDigestCuts(cuts)
+
+	// group cuts by position, duplicated cuts from isoschizomers are kept
+	groups := make([][]DigestCut, 0, len(cuts))
+	var j int
+	for i := 0; i < len(cuts); i = j {
+		for j = i + 1; j < len(cuts) && cuts[j].Top == cuts[i].Top; j++ {
+		}
+		groups = append(groups, cuts[i:j])
+	}
+
+	frags := make([]DigestFragment, 0, len(groups)+1)
+	if len(groups) == 0 {
+		return append(frags, DigestFragment{Index: 1, Begin: 0, End: l})
+	}
+
+	if circular {
+		if groups[len(groups)-1][0].Top == l { // cut at the origin
+			groups = append([][]DigestCut{groups[len(groups)-1]}, groups[:len(groups)-1]...)
+		}
+		var b, e int
+		for i, g := range groups {
+			b = g[0].Top % l
+			if i < len(groups)-1 {
+				e = groups[i+1][0].Top
+			} else {
+				e = groups[0][0].Top%l + l
+			}
+			frags = append(frags, DigestFragment{Index: i + 1, Begin: b, End: e, Left: g, Right: groups[(i+1)%len(groups)]})
+		}
+		return frags
+	}
+
+	frags = append(frags, DigestFragment{Index: 1, Begin: 0, End: groups[0][0].Top, Right: groups[0]})
+	for i, g := range groups {
+		if i < len(groups)-1 {
+			frags = append(frags, DigestFragment{Index: i + 2, Begin: g[0].Top, End: groups[i+1][0].Top, Left: g, Right: groups[i+1]})
+		} else {
+			frags = append(frags, DigestFragment{Index: i + 2, Begin: g[0].Top, End: l, Left: g})
+		}
+	}
+	return frags
+}
+
+// readEnzymes reads custom enzymes from a tab-delimited file.
+func readEnzymes(file string) (map[string]*Enzyme, []string, error) {
+	fh, err := xopen.Ropen(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer fh.Close()
+	return parseEnzymes(fh, file)
+}
+
+func builtinEnzymes() (map[string]*Enzyme, []string, error) {
+	return parseEnzymes(strings.NewReader(builtinEnzymeTable), "built-in enzyme table")
+}
+
+func parseEnzymes(r io.Reader, source string) (map[string]*Enzyme, []string, error) {
+	enzymes := make(map[string]*Enzyme, 128)
+	names := make([]string, 0, 128)
+
+	scanner := bufio.NewScanner(r)
+	var line string
+	var items []string
+	var e *Enzyme
+	var err error
+	var key string
+	for scanner.Scan() {
+		line = strings.TrimRight(scanner.Text(), "\r\n")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		items = strings.Fields(line)
+		if len(items) < 2 {
+			return nil, nil, fmt.Errorf("%s: two columns (name and site) needed: %s", source, line)
+		}
+		e, err = NewEnzyme(items[0], items[1])
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %s", source, err)
+		}
+		key = strings.ToLower(e.Name)
+		if _, ok := enzymes[key]; !ok {
+			names = append(names, e.Name)
+		}
+		enzymes[key] = e
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return enzymes, names, nil
+}
+
+// builtinEnzymeTable contains commonly used commercial enzymes
+// with recognition sites in REBASE notation.
+var builtinEnzymeTable = `AatII	GACGT^C
+Acc65I	G^GTACC
+AccI	GT^MKAC
+AciI	C^CGC
+AclI	AA^CGTT
+AfeI	AGC^GCT
+AflII	C^TTAAG
+AgeI	A^CCGGT
+AluI	AG^CT
+ApaI	GGGCC^C
+ApaLI	G^TGCAC
+ApeKI	G^CWGC
+AscI	GG^CGCGCC
+AseI	AT^TAAT
+AvaI	C^YCGRG
+AvrII	C^CTAGG
+BamHI	G^GATCC
+BbsI	GAAGAC(2/6)
+BclI	T^GATCA
+BglII	A^GATCT
+BsaI	GGTCTC(1/5)
+BsmBI	CGTCTC(1/5)
+BspEI	T^CCGGA
+BspMI	ACCTGC(4/8)
+BsrGI	T^GTACA
+BssHII	G^CGCGC
+BstBI	TT^CGAA
+ClaI	AT^CGAT
+Csp6I	G^TAC
+CviQI	G^TAC
+DpnII	^GATC
+DraI	TTT^AAA
+EagI	C^GGCCG
+EcoRI	G^AATTC
+EcoRV	GAT^ATC
+Esp3I	CGTCTC(1/5)
+FseI	GGCCGG^CC
+FspI	TGC^GCA
+HaeIII	GG^CC
+HhaI	GCG^C
+HindIII	A^AGCTT
+HinfI	G^ANTC
+HpaI	GTT^AAC
+HpaII	C^CGG
+KpnI	GGTAC^C
+MboI	^GATC
+MfeI	C^AATTG
+MluI	A^CGCGT
+MmeI	TCCRAC(20/18)
+MseI	T^TAA
+MspI	C^CGG
+NaeI	GCC^GGC
+NarI	GG^CGCC
+NcoI	C^CATGG
+NdeI	CA^TATG
+NheI	G^CTAGC
+NlaIII	CATG^
+NotI	GC^GGCCGC
+NruI	TCG^CGA
+NsiI	ATGCA^T
+PacI	TTAAT^TAA
+PaqCI	CACCTGC(4/8)
+PciI	A^CATGT
+PmeI	GTTT^AAAC
+PstI	CTGCA^G
+PvuI	CGAT^CG
+PvuII	CAG^CTG
+RsaI	GT^AC
+SacI	GAGCT^C
+SacII	CCGC^GG
+SalI	G^TCGAC
+SapI	GCTCTTC(1/4)
+Sau3AI	^GATC
+SbfI	CCTGCA^GG
+ScaI	AGT^ACT
+SfiI	GGCCNNNN^NGGCC
+SmaI	CCC^GGG
+SpeI	A^CTAGT
+SphI	GCATG^C
+SspI	AAT^ATT
+StuI	AGG^CCT
+SwaI	ATTT^AAAT
+TaqI	T^CGA
+XbaI	T^CTAGA
+XhoI	C^TCGAG
+XmaI	C^CCGGG
+`
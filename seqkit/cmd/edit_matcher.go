@@ -0,0 +1,280 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/shenwei356/bio/seq"
+)
+
+// EditHit is an approximate match of a pattern, allowing substitutions,
+// insertions and deletions.
+type EditHit struct {
+	Start, End int // 0-based, end exclusive
+	Edits      int
+	CIGAR      string // extended CIGAR, "=" for match, "X" for substitution, "I" and "D" for insertion and deletion in the pattern
+}
+
+// EditMatcher finds approximate matches of a pattern with at most k edits,
+// with Myers' bit-parallel algorithm for patterns no longer than 64,
+// and dynamic programming for longer ones.
+type EditMatcher struct {
+	pattern []byte
+	k       int
+
+	eq  [256][]bool // eq[c][i] is true if text letter c matches pattern[i]
+	peq [256]uint64 // bit vectors of eq, for patterns <= 64 bp
+}
+
+// NewEditMatcher creates an EditMatcher. Degenerate bases in the pattern
+// are supported with degenerate being true.
+func NewEditMatcher(pattern []byte, k int, degenerate bool, ignoreCase bool) *EditMatcher {
+	m := &EditMatcher{pattern: pattern, k: k}
+	var p byte
+	var bases string
+	var ok bool
+	for c := 0; c < 256; c++ {
+		m.eq[c] = make([]bool, len(pattern))
+	}
+	for i, b := range pattern {
+		p = b
+		if ignoreCase {
+			p = toUpper(b)
+		}
+		m.eq[p][i] = true
+		if degenerate {
+			if bases, ok = seq.DegenerateBaseMapNucl2[p]; ok {
+				for j := 0; j < len(bases); j++ {
+					m.eq[bases[j]][i] = true
+				}
+			}
+		}
+	}
+	if ignoreCase {
+		for c := 'a'; c <= 'z'; c++ {
+			copy(m.eq[c], m.eq[c-'a'+'A'])
+		}
+	}
+	if len(pattern) <= 64 {
+		for c := 0; c < 256; c++ {
+			for i, v := range m.eq[c] {
+				if v {
+					m.peq[c] |= 1 << uint(i)
+				}
+			}
+		}
+	}
+	return m
+}
+
+func toUpper(b byte) byte {
+	if b >= 'a' && b <= 'z' {
+		return b - 32
+	}
+	return b
+}
+
+// scan computes the minimum edit distance of the pattern ending at every
+// position of the text, and calls fn for positions with distances <= k.
+// The scan stops when fn returns true.
+func (m *EditMatcher) scan(text []byte, fn func(j, d int) bool) {
+	n := len(m.pattern)
+	if n == 0 {
+		return
+	}
+
+	if n <= 64 {
+		var pv, mv uint64 = ^uint64(0), 0
+		var eq, xv, xh, ph, mh uint64
+		high := uint64(1) << uint(n-1)
+		score := n
+		for j, c := range text {
+			eq = m.peq[c]
+			xv = eq | mv
+			xh = (((eq & pv) + pv) ^ pv) | eq
+			ph = mv | ^(xh | pv)
+			mh = pv & xh
+			if ph&high != 0 {
+				score++
+			} else if mh&high != 0 {
+				score--
+			}
+			ph <<= 1
+			mh <<= 1
+			pv = mh | ^(xv | ph)
+			mv = ph & xv
+			if score <= m.k && fn(j, score) {
+				return
+			}
+		}
+		return
+	}
+
+	col := make([]int, n+1)
+	for i := range col {
+		col[i] = i
+	}
+	var diag, up, v int
+	for j, c := range text {
+		diag = col[0] // free start in the text
+		for i := 1; i <= n; i++ {
+			up = col[i]
+			v = diag
+			if !m.eq[c][i-1] {
+				v++
+			}
+			if up+1 < v {
+				v = up + 1
+			}
+			if col[i-1]+1 < v {
+				v = col[i-1] + 1
+			}
+			diag = up
+			col[i] = v
+		}
+		if col[n] <= m.k && fn(j, col[n]) {
+			return
+		}
+	}
+}
+
+// Match tells whether the pattern occurs in the text with at most k edits.
+func (m *EditMatcher) Match(text []byte) bool {
+	var found bool
+	m.scan(text, func(j, d int) bool {
+		found = true
+		return true
+	})
+	return found
+}
+
+// Find returns hits sorted by end positions. Among consecutive end positions
+// with at most k edits, only the one with the fewest edits is chosen,
+// and the last one on ties.
+func (m *EditMatcher) Find(text []byte) []*EditHit {
+	hits := make([]*EditHit, 0, 4)
+	bestJ, bestD, lastJ := -1, 0, -2
+	flush := func() {
+		if bestJ < 0 {
+			return
+		}
+		h := m.align(text, bestJ+1)
+		if len(hits) == 0 || hits[len(hits)-1].Start != h.Start {
+			hits = append(hits, h)
+		}
+		bestJ = -1
+	}
+	m.scan(text, func(j, d int) bool {
+		if j != lastJ+1 {
+			flush()
+		}
+		if bestJ < 0 || d <= bestD {
+			bestJ, bestD = j, d
+		}
+		lastJ = j
+		return false
+	})
+	flush()
+	return hits
+}
+
+// align aligns the pattern to the text ending at end, and returns
+// the hit with the start position and CIGAR from the traceback.
+func (m *EditMatcher) align(text []byte, end int) *EditHit {
+	n := len(m.pattern)
+	start := end - n - m.k
+	if start < 0 {
+		start = 0
+	}
+	w := text[start:end]
+	l := len(w)
+
+	// d[i][j]: edit distance of pattern[:i] and a suffix of w[:j]
+	d := make([][]int, n+1)
+	for i := range d {
+		d[i] = make([]int, l+1)
+		d[i][0] = i
+	}
+	var v int
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= l; j++ {
+			v = d[i-1][j-1]
+			if !m.eq[w[j-1]][i-1] {
+				v++
+			}
+			if d[i-1][j]+1 < v {
+				v = d[i-1][j] + 1
+			}
+			if d[i][j-1]+1 < v {
+				v = d[i][j-1] + 1
+			}
+			d[i][j] = v
+		}
+	}
+
+	// traceback, preferring matches/substitutions
+	ops := make([]byte, 0, n+m.k)
+	i, j := n, l
+	for i > 0 {
+		if j > 0 {
+			v = d[i-1][j-1]
+			if !m.eq[w[j-1]][i-1] {
+				v++
+			}
+			if d[i][j] == v {
+				if m.eq[w[j-1]][i-1] {
+					ops = append(ops, '=')
+				} else {
+					ops = append(ops, 'X')
+				}
+				i--
+				j--
+				continue
+			}
+			if d[i][j] == d[i][j-1]+1 {
+				ops = append(ops, 'D')
+				j--
+				continue
+			}
+		}
+		ops = append(ops, 'I')
+		i--
+	}
+
+	return &EditHit{Start: start + j, End: end, Edits: d[n][l], CIGAR: compactCIGAR(ops)}
+}
+
+// compactCIGAR converts reversed operations to a CIGAR string.
+func compactCIGAR(ops []byte) string {
+	var buf bytes.Buffer
+	var n int
+	for k := len(ops) - 1; k >= 0; k-- {
+		n++
+		if k == 0 || ops[k-1] != ops[k] {
+			buf.WriteString(strconv.Itoa(n))
+			buf.WriteByte(ops[k])
+			n = 0
+		}
+	}
+	return buf.String()
+}
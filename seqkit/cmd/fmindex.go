@@ -0,0 +1,90 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/spf13/cobra"
+)
+
+// fmindexCmd represents the fmindex command
+var fmindexCmd = &cobra.Command{
+	GroupID: "search",
+
+	Use:   "fmindex",
+	Short: "create FM-index files for repeated searches with locate and grep",
+	Long: `create FM-index files for repeated searches with locate and grep
+
+For each input FASTA file, an FM-index file (<infile>.fmi) is created,
+containing the BWT, sampled suffix array and sequence boundaries of
+all sequences. "seqkit locate --index" and "seqkit grep -s --index" load it
+via memory mapping, so queries of patterns, with mismatches allowed,
+do not need to build FM-indexes for every run and every sequence.
+
+Attention:
+  1. Sequences are indexed as they are, unless -i/--ignore-case is given,
+     where sequences are indexed in lower case and searches with the index
+     are case-insensitive.
+  2. Sizes and modification times of FASTA files are recorded in the index,
+     please rebuild the index once a FASTA file changes.
+  3. Sequences are saved in the index too. The index file is about 3-4 times
+     the size of the sequences, and a smaller -s/--sa-sample-rate makes
+     locating faster at the cost of a bigger index.
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := getConfigs(cmd)
+		runtime.GOMAXPROCS(config.Threads)
+		quiet := config.Quiet
+
+		ignoreCase := getFlagBool(cmd, "ignore-case")
+		saRate := getFlagPositiveInt(cmd, "sa-sample-rate")
+
+		files := getFileListFromArgsAndFile(cmd, args, true, "infile-list", !config.SkipFileCheck)
+
+		for _, file := range files {
+			if isStdin(file) {
+				checkError(fmt.Errorf("stdin not supported"))
+			}
+		}
+
+		for _, file := range files {
+			fileIdx := fmIndexFileOf(file)
+			if !quiet {
+				log.Infof("create FM-index for %s", file)
+			}
+			n, err := BuildFMIndexFile(file, fileIdx, ignoreCase, saRate)
+			checkError(err)
+			if !quiet {
+				log.Infof("  %d sequences saved to %s", n, fileIdx)
+			}
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(fmindexCmd)
+
+	fmindexCmd.Flags().BoolP("ignore-case", "i", false, "index sequences in lower case for case-insensitive searches")
+	fmindexCmd.Flags().IntP("sa-sample-rate", "s", 32, "sampling rate of suffix array")
+}
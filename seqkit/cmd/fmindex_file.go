@@ -0,0 +1,584 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+	"sort"
+
+	"github.com/edsrzf/mmap-go"
+	"github.com/shenwei356/bio/seq"
+	"github.com/shenwei356/bio/seqio/fastx"
+	"github.com/shenwei356/bwt"
+)
+
+// An FM-index file (.fmi) of all sequences in a FASTA file.
+//
+// Sequences are concatenated with a separator into a text T, i.e.,
+// s1 + sep + s2 + sep + ... + sN + sep, and the BWT of T$ is saved along with
+// occurrence checkpoints, sampled suffix array and sequence boundaries.
+// All integers are little-endian uint64, and every section is 8-byte aligned,
+// so the file can be memory-mapped and used directly.
+//
+//	header           128 bytes
+//	letters          256 bytes, letters in the BWT except $
+//	C                256 x uint64, number of letters smaller than c in T$
+//	starts           (N+1) x uint64, start positions of sequences in T
+//	name offsets     (N+1) x uint64
+//	names            full headers of sequences
+//	BWT              n+1 bytes
+//	occ checkpoints  (n+1)/64+1 blocks x σ x uint64, occurrences of letters before the block
+//	sampled marks    bit vector of rows with SA[i] % rate == 0
+//	ranks of marks   uint64 per word of the bit vector
+//	sampled SA       positions of marked rows
+//	text             n bytes, T itself, for outputting matched sequences
+
+const fmiMagic = "SKFMIDX\x00"
+const fmiVersion = 1
+const fmiHeaderSize = 128
+
+const fmiFlagIgnoreCase = 1
+const fmiFlagNucleotide = 2
+const fmiFlagRNA = 4
+
+// fmiSeparator separates sequences in the text, it's not searched.
+const fmiSeparator byte = 1
+
+const fmiOccInterval = 64
+
+// FMIndexFile is a memory-mapped FM-index of sequences of a FASTA file.
+type FMIndexFile struct {
+	fh   *os.File
+	data mmap.MMap
+
+	flags  uint32
+	n      int // length of T
+	nSeqs  int
+	saRate int
+
+	FileSize    int64
+	FileModTime int64
+
+	letters  []byte    // letters in the BWT except $
+	idx      [256]int  // index of a letter in letters, -1 for absent
+	c        [256]int  // C table
+	searched []byte    // letters allowed in matching with mismatches
+	found    [256]bool // letters existing in the text
+
+	offStarts, offNameOffsets, offNames int
+	offBWT, offOcc, offMarks, offRanks  int
+	offSamples, offText                 int
+}
+
+// fmiLayout computes offsets of sections.
+func fmiLayout(n, nSeqs, sigma, namesLen, nSamples int) (offs [9]int, size int) {
+	pad := func(x int) int { return (x + 7) &^ 7 }
+	off := fmiHeaderSize + 256 + 256*8
+	offs[0] = off // starts
+	off += (nSeqs + 1) * 8
+	offs[1] = off // name offsets
+	off += (nSeqs + 1) * 8
+	offs[2] = off // names
+	off += pad(namesLen)
+	offs[3] = off // BWT
+	off += pad(n + 1)
+	offs[4] = off // occ
+	off += ((n+1)/fmiOccInterval + 1) * sigma * 8
+	nWords := (n + 1 + 63) / 64
+	offs[5] = off // marks
+	off += nWords * 8
+	offs[6] = off // ranks
+	off += nWords * 8
+	offs[7] = off // samples
+	off += nSamples * 8
+	offs[8] = off // text
+	off += n
+	return offs, off
+}
+
+// BuildFMIndexFile builds the FM-index of all sequences in a FASTA file,
+// and saves it to outFile.
+func BuildFMIndexFile(file string, outFile string, ignoreCase bool, saRate int) (int, error) {
+	if saRate < 1 {
+		return 0, fmt.Errorf("the sampling rate of suffix array should be positive")
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return 0, err
+	}
+
+	reader, err := fastx.NewReader(nil, file, "")
+	if err != nil {
+		return 0, err
+	}
+	var record *fastx.Record
+	text := make([]byte, 0, info.Size())
+	starts := make([]int, 0, 1024)
+	names := make([]byte, 0, 1<<16)
+	nameOffsets := make([]int, 1, 1024)
+	nucleotide, rna := true, false
+	checkAlphabet := true
+	for {
+		record, err = reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return 0, err
+		}
+		if reader.IsFastq {
+			return 0, fmt.Errorf("only FASTA format is supported: %s", file)
+		}
+		if checkAlphabet {
+			if reader.Alphabet() == seq.Unlimit || reader.Alphabet() == seq.Protein {
+				nucleotide = false
+			} else if reader.Alphabet() == seq.RNA || reader.Alphabet() == seq.RNAredundant {
+				rna = true
+			}
+			checkAlphabet = false
+		}
+		if bytes.IndexByte(record.Seq.Seq, fmiSeparator) >= 0 || bytes.IndexByte(record.Seq.Seq, 0) >= 0 {
+			return 0, fmt.Errorf("invalid character found in sequence: %s", record.Name)
+		}
+
+		starts = append(starts, len(text))
+		if ignoreCase {
+			text = append(text, bytes.ToLower(record.Seq.Seq)...)
+		} else {
+			text = append(text, record.Seq.Seq...)
+		}
+		text = append(text, fmiSeparator)
+
+		names = append(names, record.Name...)
+		nameOffsets = append(nameOffsets, len(names))
+	}
+	reader.Close()
+	if len(starts) == 0 {
+		return 0, fmt.Errorf("no sequences found in file: %s", file)
+	}
+	nSeqs := len(starts)
+	starts = append(starts, len(text))
+	n := len(text)
+
+	// suffix array and BWT
+	sa := bwt.SuffixArray(text)
+	bwtSeq := make([]byte, n+1)
+	var count [256]int
+	for i, p := range sa {
+		if p > 0 {
+			bwtSeq[i] = text[p-1]
+			count[bwtSeq[i]]++
+		}
+	}
+	var letters []byte
+	var idx [256]int
+	for b := range idx {
+		idx[b] = -1
+	}
+	for b, v := range count {
+		if v > 0 && b > 0 {
+			idx[b] = len(letters)
+			letters = append(letters, byte(b))
+		}
+	}
+	sigma := len(letters)
+
+	var C [256]int
+	sum := 1 // $
+	for b := 1; b < 256; b++ {
+		C[b] = sum
+		sum += count[b]
+	}
+
+	nSamples := 0
+	for _, p := range sa {
+		if p%saRate == 0 {
+			nSamples++
+		}
+	}
+
+	offs, _ := fmiLayout(n, nSeqs, sigma, len(names), nSamples)
+
+	outfh, err := os.Create(outFile)
+	if err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriterSize(outfh, 1<<20)
+	var written int
+	buf := make([]byte, 8)
+	putU64 := func(v uint64) {
+		binary.LittleEndian.PutUint64(buf, v)
+		w.Write(buf)
+		written += 8
+	}
+	putBytes := func(b []byte) {
+		w.Write(b)
+		written += len(b)
+	}
+	padTo := func(off int) {
+		for written < off {
+			w.WriteByte(0)
+			written++
+		}
+	}
+
+	// header
+	var flags uint32
+	if ignoreCase {
+		flags |= fmiFlagIgnoreCase
+	}
+	if nucleotide {
+		flags |= fmiFlagNucleotide
+	}
+	if rna {
+		flags |= fmiFlagRNA
+	}
+	putBytes([]byte(fmiMagic))
+	binary.LittleEndian.PutUint32(buf[:4], fmiVersion)
+	binary.LittleEndian.PutUint32(buf[4:], flags)
+	putBytes(buf)
+	putU64(uint64(n))
+	putU64(uint64(nSeqs))
+	putU64(uint64(saRate))
+	putU64(uint64(sigma))
+	putU64(uint64(info.Size()))
+	putU64(uint64(info.ModTime().UnixNano()))
+	putU64(uint64(len(names)))
+	padTo(fmiHeaderSize)
+
+	// letters and C
+	_letters := make([]byte, 256)
+	copy(_letters, letters)
+	putBytes(_letters)
+	for b := 0; b < 256; b++ {
+		putU64(uint64(C[b]))
+	}
+
+	// sequences
+	for _, s := range starts {
+		putU64(uint64(s))
+	}
+	for _, s := range nameOffsets {
+		putU64(uint64(s))
+	}
+	putBytes(names)
+	padTo(offs[3])
+
+	// BWT
+	putBytes(bwtSeq)
+	padTo(offs[4])
+
+	// occurrence checkpoints
+	occ := make([]int, sigma)
+	for i := 0; i <= n; i++ {
+		if i%fmiOccInterval == 0 {
+			for _, v := range occ {
+				putU64(uint64(v))
+			}
+		}
+		if bwtSeq[i] > 0 {
+			occ[idx[bwtSeq[i]]]++
+		}
+	}
+	if (n+1)%fmiOccInterval == 0 {
+		for _, v := range occ {
+			putU64(uint64(v))
+		}
+	}
+
+	// sampled marks and their ranks
+	nWords := (n + 1 + 63) / 64
+	words := make([]uint64, nWords)
+	for i, p := range sa {
+		if p%saRate == 0 {
+			words[i>>6] |= 1 << uint(i&63)
+		}
+	}
+	for _, word := range words {
+		putU64(word)
+	}
+	rank := 0
+	for _, word := range words {
+		putU64(uint64(rank))
+		rank += bits.OnesCount64(word)
+	}
+
+	// sampled suffix array
+	for _, p := range sa {
+		if p%saRate == 0 {
+			putU64(uint64(p))
+		}
+	}
+
+	// text
+	putBytes(text)
+
+	if err = w.Flush(); err != nil {
+		return 0, err
+	}
+	if err = outfh.Close(); err != nil {
+		return 0, err
+	}
+	return nSeqs, nil
+}
+
+// OpenFMIndexFile memory-maps an FM-index file.
+func OpenFMIndexFile(file string) (*FMIndexFile, error) {
+	fh, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	data, err := mmap.Map(fh, mmap.RDONLY, 0)
+	if err != nil {
+		fh.Close()
+		return nil, err
+	}
+	idx := &FMIndexFile{fh: fh, data: data}
+	if err = idx.parse(); err != nil {
+		idx.Close()
+		return nil, fmt.Errorf("invalid FM-index file: %s: %s", file, err)
+	}
+	return idx, nil
+}
+
+func (idx *FMIndexFile) parse() error {
+	data := idx.data
+	if len(data) < fmiHeaderSize+256+256*8 || string(data[:8]) != fmiMagic {
+		return fmt.Errorf("not an FM-index file created by seqkit")
+	}
+	if v := binary.LittleEndian.Uint32(data[8:]); v != fmiVersion {
+		return fmt.Errorf("unsupported version: %d", v)
+	}
+	idx.flags = binary.LittleEndian.Uint32(data[12:])
+	idx.n = int(binary.LittleEndian.Uint64(data[16:]))
+	idx.nSeqs = int(binary.LittleEndian.Uint64(data[24:]))
+	idx.saRate = int(binary.LittleEndian.Uint64(data[32:]))
+	sigma := int(binary.LittleEndian.Uint64(data[40:]))
+	idx.FileSize = int64(binary.LittleEndian.Uint64(data[48:]))
+	idx.FileModTime = int64(binary.LittleEndian.Uint64(data[56:]))
+	namesLen := int(binary.LittleEndian.Uint64(data[64:]))
+	if sigma > 255 || idx.saRate < 1 {
+		return fmt.Errorf("broken header")
+	}
+
+	idx.letters = data[fmiHeaderSize : fmiHeaderSize+sigma]
+	for b := range idx.idx {
+		idx.idx[b] = -1
+	}
+	for i, b := range idx.letters {
+		idx.idx[b] = i
+		idx.found[b] = true
+		if b != fmiSeparator {
+			idx.searched = append(idx.searched, b)
+		}
+	}
+	off := fmiHeaderSize + 256
+	for b := 0; b < 256; b++ {
+		idx.c[b] = int(binary.LittleEndian.Uint64(data[off+b*8:]))
+	}
+
+	// number of samples
+	nSamples := (idx.n + idx.saRate) / idx.saRate // positions 0, rate, 2*rate, ... <= n
+	offs, size := fmiLayout(idx.n, idx.nSeqs, sigma, namesLen, nSamples)
+	if size != len(data) {
+		return fmt.Errorf("unexpected file size")
+	}
+	idx.offStarts, idx.offNameOffsets, idx.offNames = offs[0], offs[1], offs[2]
+	idx.offBWT, idx.offOcc, idx.offMarks, idx.offRanks = offs[3], offs[4], offs[5], offs[6]
+	idx.offSamples, idx.offText = offs[7], offs[8]
+	return nil
+}
+
+// Close unmaps the index file.
+func (idx *FMIndexFile) Close() error {
+	if idx.data != nil {
+		idx.data.Unmap()
+	}
+	return idx.fh.Close()
+}
+
+// IgnoreCase tells whether sequences are indexed in lower case.
+func (idx *FMIndexFile) IgnoreCase() bool { return idx.flags&fmiFlagIgnoreCase > 0 }
+
+// Alphabet returns the alphabet for computing reverse complement sequences
+// of patterns, nil for protein or other sequences where only the positive
+// strand is searched.
+func (idx *FMIndexFile) Alphabet() *seq.Alphabet {
+	if idx.flags&fmiFlagNucleotide == 0 {
+		return nil
+	}
+	if idx.flags&fmiFlagRNA > 0 {
+		return seq.RNAredundant
+	}
+	return seq.DNAredundant
+}
+
+// NumSeqs returns the number of sequences.
+func (idx *FMIndexFile) NumSeqs() int { return idx.nSeqs }
+
+func (idx *FMIndexFile) u64(off int, i int) int {
+	return int(binary.LittleEndian.Uint64(idx.data[off+i*8:]))
+}
+
+// SeqName returns the full header of the i-th sequence.
+func (idx *FMIndexFile) SeqName(i int) []byte {
+	return idx.data[idx.offNames+idx.u64(idx.offNameOffsets, i) : idx.offNames+idx.u64(idx.offNameOffsets, i+1)]
+}
+
+// SeqLen returns the length of the i-th sequence.
+func (idx *FMIndexFile) SeqLen(i int) int {
+	return idx.u64(idx.offStarts, i+1) - idx.u64(idx.offStarts, i) - 1
+}
+
+// SubSeq returns the subsequence of the i-th sequence, start is 0-based,
+// and end is exclusive.
+func (idx *FMIndexFile) SubSeq(i int, start, end int) []byte {
+	s := idx.offText + idx.u64(idx.offStarts, i)
+	return idx.data[s+start : s+end]
+}
+
+// SeqOf returns the index of sequence containing the text position,
+// and the 0-based position in the sequence.
+func (idx *FMIndexFile) SeqOf(pos int) (int, int) {
+	i := sort.Search(idx.nSeqs, func(j int) bool { return idx.u64(idx.offStarts, j+1) > pos })
+	return i, pos - idx.u64(idx.offStarts, i)
+}
+
+// occ returns the number of letter c in BWT[0:i].
+func (idx *FMIndexFile) occ(c byte, i int) int {
+	j := idx.idx[c]
+	b := i / fmiOccInterval
+	v := idx.u64(idx.offOcc, b*len(idx.letters)+j)
+	for _, x := range idx.data[idx.offBWT+b*fmiOccInterval : idx.offBWT+i] {
+		if x == c {
+			v++
+		}
+	}
+	return v
+}
+
+// position resolves the text position of a row with the sampled suffix array.
+func (idx *FMIndexFile) position(r int) int {
+	var steps int
+	var w, bit uint64
+	var c byte
+	for {
+		w = binary.LittleEndian.Uint64(idx.data[idx.offMarks+(r>>6)*8:])
+		bit = 1 << uint(r&63)
+		if w&bit > 0 {
+			rank := idx.u64(idx.offRanks, r>>6) + bits.OnesCount64(w&(bit-1))
+			return idx.u64(idx.offSamples, rank) + steps
+		}
+		c = idx.data[idx.offBWT+r]
+		r = idx.c[c] + idx.occ(c, r)
+		steps++
+	}
+}
+
+// Locate returns sorted 0-based text positions of the query, with at most
+// the given number of mismatches. Matches never span two sequences.
+func (idx *FMIndexFile) Locate(query []byte, mismatches int) []int {
+	if len(query) == 0 {
+		return nil
+	}
+	if mismatches == 0 {
+		for _, b := range query {
+			if !idx.found[b] || b == fmiSeparator {
+				return nil
+			}
+		}
+	}
+
+	locs := make([]int, 0, 8)
+	var search func(i int, lo, hi int, m int)
+	search = func(i int, lo, hi int, m int) {
+		if i < 0 {
+			for r := lo; r < hi; r++ {
+				locs = append(locs, idx.position(r))
+			}
+			return
+		}
+		q := query[i]
+		var _lo, _hi int
+		if m == 0 {
+			if !idx.found[q] || q == fmiSeparator {
+				return
+			}
+			_lo, _hi = idx.c[q]+idx.occ(q, lo), idx.c[q]+idx.occ(q, hi)
+			if _lo < _hi {
+				search(i-1, _lo, _hi, 0)
+			}
+			return
+		}
+		for _, c := range idx.searched {
+			_lo, _hi = idx.c[c]+idx.occ(c, lo), idx.c[c]+idx.occ(c, hi)
+			if _lo >= _hi {
+				continue
+			}
+			if c == q {
+				search(i-1, _lo, _hi, m)
+			} else {
+				search(i-1, _lo, _hi, m-1)
+			}
+		}
+	}
+	search(len(query)-1, 0, idx.n+1, mismatches)
+
+	sort.Ints(locs)
+	return locs
+}
+
+// fmIndexFileOf returns the FM-index file of a FASTA file.
+func fmIndexFileOf(file string) string {
+	return file + ".fmi"
+}
+
+// openFMIndexOfFile opens the FM-index of a FASTA file, and checks
+// whether it's outdated.
+func openFMIndexOfFile(file string) (*FMIndexFile, error) {
+	if isStdin(file) {
+		return nil, fmt.Errorf("stdin is not supported when using an FM-index file")
+	}
+	fileIdx := fmIndexFileOf(file)
+	if _, err := os.Stat(fileIdx); os.IsNotExist(err) {
+		return nil, fmt.Errorf(`FM-index file not found: %s, please create it with "seqkit fmindex %s"`, fileIdx, file)
+	}
+	idx, err := OpenFMIndexFile(fileIdx)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		idx.Close()
+		return nil, err
+	}
+	if info.Size() != idx.FileSize || info.ModTime().UnixNano() != idx.FileModTime {
+		idx.Close()
+		return nil, fmt.Errorf(`FM-index file is outdated: %s, please rebuild it with "seqkit fmindex %s"`, fileIdx, file)
+	}
+	return idx, nil
+}
@@ -0,0 +1,202 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/shenwei356/bio/seq"
+	"github.com/shenwei356/bio/seqio/fastx"
+	"github.com/shenwei356/xopen"
+)
+
+// fmiHit is a match of a pattern found with an FM-index file.
+type fmiHit struct {
+	seq     int  // index of sequence
+	strand  byte // '+' or '-'
+	pattern int  // index of pattern
+	begin   int  // 0-based
+}
+
+// fmiRevCom returns the reverse complement sequence of a copy of s.
+func fmiRevCom(alphabet *seq.Alphabet, s []byte) []byte {
+	return (&seq.Seq{Alphabet: alphabet, Seq: append([]byte{}, s...)}).RevComInplace().Seq
+}
+
+// fmiPatterns prepares patterns for searching with an FM-index file.
+func fmiPatterns(idx *FMIndexFile, file string, patterns [][]byte, ignoreCase bool) [][]byte {
+	if ignoreCase && !idx.IgnoreCase() {
+		checkError(fmt.Errorf(`the FM-index of %s is case-sensitive, please rebuild it with "seqkit fmindex -i"`, file))
+	}
+	if !idx.IgnoreCase() {
+		return patterns
+	}
+	_patterns := make([][]byte, len(patterns))
+	for i, p := range patterns {
+		_patterns[i] = bytes.ToLower(p)
+	}
+	return _patterns
+}
+
+// searchFMIndexFile searches patterns on one or both strands with multiple
+// threads, and returns hits sorted by sequence, strand, pattern and position.
+func searchFMIndexFile(idx *FMIndexFile, patterns [][]byte, mismatches int,
+	onlyPositiveStrand bool, threads int) []fmiHit {
+
+	alphabet := idx.Alphabet()
+	strands := []byte{'+', '-'}
+	if onlyPositiveStrand || alphabet == nil {
+		strands = strands[:1]
+	}
+
+	results := make([][]fmiHit, len(patterns))
+	var wg sync.WaitGroup
+	tokens := make(chan int, threads)
+	for i, p := range patterns {
+		tokens <- 1
+		wg.Add(1)
+		go func(i int, p []byte) {
+			defer func() {
+				wg.Done()
+				<-tokens
+			}()
+			hits := make([]fmiHit, 0, 8)
+			var s int
+			for _, strand := range strands {
+				q := p
+				if strand == '-' {
+					q = fmiRevCom(alphabet, p)
+				}
+				for _, pos := range idx.Locate(q, mismatches) {
+					s, pos = idx.SeqOf(pos)
+					hits = append(hits, fmiHit{seq: s, strand: strand, pattern: i, begin: pos})
+				}
+			}
+			results[i] = hits
+		}(i, p)
+	}
+	wg.Wait()
+
+	var n int
+	for _, hits := range results {
+		n += len(hits)
+	}
+	hits := make([]fmiHit, 0, n)
+	for _, _hits := range results {
+		hits = append(hits, _hits...)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if a.seq != b.seq {
+			return a.seq < b.seq
+		}
+		if a.strand != b.strand {
+			return a.strand < b.strand
+		}
+		if a.pattern != b.pattern {
+			return a.pattern < b.pattern
+		}
+		return a.begin < b.begin
+	})
+	return hits
+}
+
+// locateWithFMIndex locates patterns in a FASTA file with its FM-index file,
+// and outputs results in the same formats of "seqkit locate".
+func locateWithFMIndex(outfh *xopen.Writer, file string, idRe *regexp.Regexp,
+	pNames []string, patterns [][]byte, mismatches int, ignoreCase bool, onlyPositiveStrand bool,
+	outFmtGTF bool, outFmtBED bool, hideMatched bool, len2show int, threads int) {
+
+	idx, err := openFMIndexOfFile(file)
+	checkError(err)
+	defer idx.Close()
+
+	_patterns := fmiPatterns(idx, file, patterns, ignoreCase)
+	hits := searchFMIndexFile(idx, _patterns, mismatches, onlyPositiveStrand, threads)
+
+	alphabet := idx.Alphabet()
+	var id []byte
+	lastSeq := -1
+	var begin, end int
+	var pName string
+	var matched []byte
+	for _, h := range hits {
+		if h.seq != lastSeq {
+			id = fastx.ParseHeadID(idRe, idx.SeqName(h.seq))
+			lastSeq = h.seq
+		}
+		pName = pNames[h.pattern]
+		begin = h.begin + 1
+		end = h.begin + len(patterns[h.pattern])
+
+		if outFmtGTF {
+			fmt.Fprintf(outfh, "%s\t%s\t%s\t%d\t%d\t%d\t%c\t%s\tgene_id \"%s\"; \n",
+				id, "SeqKit", "location", begin, end, 0, h.strand, ".", pName)
+		} else if outFmtBED {
+			fmt.Fprintf(outfh, "%s\t%d\t%d\t%s\t%d\t%c\n",
+				id, begin-1, end, pName, 0, h.strand)
+		} else if hideMatched {
+			fmt.Fprintf(outfh, "%s\t%s\t%s\t%c\t%d\t%d\n",
+				id, pName, prune(patterns[h.pattern], len2show), h.strand, begin, end)
+		} else {
+			matched = idx.SubSeq(h.seq, begin-1, end)
+			if h.strand == '-' {
+				matched = fmiRevCom(alphabet, matched)
+			}
+			fmt.Fprintf(outfh, "%s\t%s\t%s\t%c\t%d\t%d\t%s\n",
+				id, pName, prune(patterns[h.pattern], len2show), h.strand, begin, end, prune(matched, len2show))
+		}
+	}
+}
+
+// grepWithFMIndex returns whether each sequence in a FASTA file matches
+// any pattern, with its FM-index file. An empty pattern matches empty sequences.
+func grepWithFMIndex(file string, patterns [][]byte, mismatches int, ignoreCase bool,
+	onlyPositiveStrand bool, threads int) (*FMIndexFile, []bool) {
+
+	idx, err := openFMIndexOfFile(file)
+	checkError(err)
+
+	_patterns := fmiPatterns(idx, file, patterns, ignoreCase)
+	hits := make([]bool, idx.NumSeqs())
+
+	nonEmpty := make([][]byte, 0, len(_patterns))
+	for _, p := range _patterns {
+		if len(p) > 0 {
+			nonEmpty = append(nonEmpty, p)
+			continue
+		}
+		for i := range hits {
+			if idx.SeqLen(i) == 0 {
+				hits[i] = true
+			}
+		}
+	}
+
+	for _, h := range searchFMIndexFile(idx, nonEmpty, mismatches, onlyPositiveStrand, threads) {
+		hits[h.seq] = true
+	}
+	return idx, hits
+}
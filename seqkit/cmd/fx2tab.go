@@ -55,6 +55,27 @@ Attention:
      an error probability, 3) take the mean of those, 4) and then convert that
      mean error back into a qscore.
      Reference: https://github.com/shenwei356/seqkit/issues/448
+  3. Protein properties (-p/--protein-props or individual flags) only apply to
+     protein sequences, the sequence type is detected by the first sequence or
+     specified by -t/--seq-type. Gaps, stop symbols and unknown residues are
+     ignored, and methods and parameters are the same as those of ExPASy
+     ProtParam (https://web.expasy.org/protparam/):
+       mol.weight        molecular weight (Da), average masses by default
+       pI                isoelectric point, with pKa values of Bjellqvist et al.
+       charge            net charge at a pH given by --ph
+       gravy             grand average of hydropathy (Kyte & Doolittle),
+                         U and O excluded
+       aromaticity       relative frequency of F, W and Y, U and O excluded
+       instability       instability index, < 40 for stable proteins
+       ext.coef          molar extinction coefficient at 280 nm, all Cys reduced
+       ext.coef.cystine  molar extinction coefficient at 280 nm, all Cys
+                         pairs form cystines
+  4. GA4GH refget digests (-R/--refget) are computed from upper-cased sequences
+     as the specification requires, for matching sequences in public registries
+     (https://ga4gh.github.io/refget/sequences/):
+       sha512t24u        refget identifier, e.g., SQ.aKF498dAxcJAqme6QYQ7EZ07-fiw8Kw2
+       md5               MD5 digest, different from -s/--seq-hash which uses lower-cased sequences
+     Gaps are not removed, you can remove them with "seqkit seq -g".
 
 `,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -86,8 +107,25 @@ Attention:
 		printAvgQual := getFlagBool(cmd, "avg-qual")
 		qBase := getFlagPositiveInt(cmd, "qual-ascii-base")
 		printSeqHash := getFlagBool(cmd, "seq-hash")
+		printRefget := getFlagBool(cmd, "refget")
 		noQual := getFlagBool(cmd, "no-qual")
 
+		protProps := getFlagBool(cmd, "protein-props")
+		printMolWeight := protProps || getFlagBool(cmd, "mol-weight")
+		monoisotopic := getFlagBool(cmd, "monoisotopic")
+		printPI := protProps || getFlagBool(cmd, "iso-point")
+		printCharge := protProps || getFlagBool(cmd, "charge")
+		pH := getFlagFloat64(cmd, "ph")
+		printGRAVY := protProps || getFlagBool(cmd, "gravy")
+		printAromaticity := protProps || getFlagBool(cmd, "aromaticity")
+		printInstability := protProps || getFlagBool(cmd, "instability")
+		printExtCoef := protProps || getFlagBool(cmd, "ext-coef")
+		printProtProps := printMolWeight || printPI || printCharge || printGRAVY ||
+			printAromaticity || printInstability || printExtCoef
+		if pH < 0 || pH > 14 {
+			checkError(fmt.Errorf("value of flag --ph should be in range of [0, 14]"))
+		}
+
 		printFile := getFlagBool(cmd, "file-name")
 		basename := getFlagBool(cmd, "basename")
 		stdinLabel := getFlagString(cmd, "stdin-label")
@@ -147,6 +185,30 @@ Attention:
 			if printSeqHash {
 				outfh.WriteString("\tseq.hash")
 			}
+			if printRefget {
+				outfh.WriteString("\tsha512t24u\tmd5")
+			}
+			if printMolWeight {
+				outfh.WriteString("\tmol.weight")
+			}
+			if printPI {
+				outfh.WriteString("\tpI")
+			}
+			if printCharge {
+				outfh.WriteString("\tcharge")
+			}
+			if printGRAVY {
+				outfh.WriteString("\tgravy")
+			}
+			if printAromaticity {
+				outfh.WriteString("\taromaticity")
+			}
+			if printInstability {
+				outfh.WriteString("\tinstability")
+			}
+			if printExtCoef {
+				outfh.WriteString("\text.coef\text.coef.cystine")
+			}
 
 			outfh.WriteString("\n")
 		}
@@ -155,6 +217,9 @@ Attention:
 		var g, c, a, t int
 		var record *fastx.Record
 		var sum [md5.Size]byte
+		var refgetID, refgetMD5 string
+		var extCoef, extCoefCystine int
+		checkAlphabet := printProtProps
 
 		var _file string
 		for _, file := range files {
@@ -180,6 +245,13 @@ Attention:
 					checkError(err)
 					break
 				}
+				if checkAlphabet {
+					if record.Seq.Alphabet == seq.DNA || record.Seq.Alphabet == seq.DNAredundant ||
+						record.Seq.Alphabet == seq.RNA || record.Seq.Alphabet == seq.RNAredundant {
+						checkError(fmt.Errorf("protein properties only apply to protein sequences, but DNA/RNA sequences detected, you can specify the sequence type with -t/--seq-type"))
+					}
+					checkAlphabet = false
+				}
 				if onlyID {
 					name = record.ID
 				} else {
@@ -256,6 +328,34 @@ Attention:
 					}
 				}
 
+				if printRefget {
+					refgetID, refgetMD5 = RefgetDigests(bytes.ToUpper(record.Seq.Seq))
+					fmt.Fprintf(outfh, "\t%s\t%s", refgetID, refgetMD5)
+				}
+
+				if printMolWeight {
+					fmt.Fprintf(outfh, "\t%.2f", ProteinMolWeight(record.Seq.Seq, monoisotopic))
+				}
+				if printPI {
+					fmt.Fprintf(outfh, "\t%.2f", ProteinIsoelectricPoint(record.Seq.Seq))
+				}
+				if printCharge {
+					fmt.Fprintf(outfh, "\t%.2f", ProteinCharge(record.Seq.Seq, pH))
+				}
+				if printGRAVY {
+					fmt.Fprintf(outfh, "\t%.4f", ProteinGRAVY(record.Seq.Seq))
+				}
+				if printAromaticity {
+					fmt.Fprintf(outfh, "\t%.4f", ProteinAromaticity(record.Seq.Seq))
+				}
+				if printInstability {
+					fmt.Fprintf(outfh, "\t%.2f", ProteinInstabilityIndex(record.Seq.Seq))
+				}
+				if printExtCoef {
+					extCoef, extCoefCystine = ProteinExtinctionCoefficient(record.Seq.Seq)
+					fmt.Fprintf(outfh, "\t%d\t%d", extCoef, extCoefCystine)
+				}
+
 				if printFile {
 					fmt.Fprintf(outfh, "\t%s", _file)
 				}
@@ -284,7 +384,18 @@ func init() {
 	fx2tabCmd.Flags().BoolP("avg-qual", "q", false, "print average quality of a read")
 	fx2tabCmd.Flags().IntP("qual-ascii-base", "b", 33, "ASCII BASE, 33 for Phred+33")
 	fx2tabCmd.Flags().BoolP("seq-hash", "s", false, "print hash (MD5) of sequence")
+	fx2tabCmd.Flags().BoolP("refget", "R", false, "print GA4GH refget digests (sha512t24u and MD5) of upper-cased sequence")
 	fx2tabCmd.Flags().BoolP("no-qual", "Q", false, "only output two column even for FASTQ file")
+	fx2tabCmd.Flags().BoolP("protein-props", "p", false, "print all protein properties, type \"seqkit fx2tab -h\" for details")
+	fx2tabCmd.Flags().BoolP("mol-weight", "", false, "print molecular weight of protein")
+	fx2tabCmd.Flags().BoolP("monoisotopic", "", false, "use monoisotopic masses instead of average masses for --mol-weight")
+	fx2tabCmd.Flags().BoolP("iso-point", "", false, "print isoelectric point (pI) of protein")
+	fx2tabCmd.Flags().BoolP("charge", "", false, "print net charge of protein at the pH given by --ph")
+	fx2tabCmd.Flags().Float64P("ph", "", 7.0, "pH for computing net charge of protein")
+	fx2tabCmd.Flags().BoolP("gravy", "", false, "print grand average of hydropathy (GRAVY) of protein")
+	fx2tabCmd.Flags().BoolP("aromaticity", "", false, "print aromaticity of protein")
+	fx2tabCmd.Flags().BoolP("instability", "", false, "print instability index of protein")
+	fx2tabCmd.Flags().BoolP("ext-coef", "", false, "print extinction coefficients of protein, with all Cys reduced and all Cys pairs forming cystines")
 	fx2tabCmd.Flags().BoolP("file-name", "f", false, "print the name of input file")
 	fx2tabCmd.Flags().BoolP("basename", "", false, "print the basename of input file")
 	fx2tabCmd.Flags().StringP("stdin-label", "", "-", `label for replacing default "-" for stdin`)
@@ -63,6 +63,7 @@ Attention:
      search only on the positive strand.
      Mismatch is allowed using flag "-m/--max-mismatch", you can increase
      the value of "-j/--threads" to accelerate processing.
+     Insertions and deletions are also allowed using flag "-e/--max-edits".
   3. Degenerate bases/residues like "RYMM.." are also supported by flag -d.
      But do not use degenerate bases/residues in regular expression, you need
      convert them to regular expression, e.g., change "N" or "X"  to ".".
@@ -77,6 +78,11 @@ Attention:
         seqkit faidx seqs.fasta --infile-list IDs.txt
   6. For multiple patterns, you can either set "-p" multiple times, i.e.,
      -p pattern1 -p pattern2, or give a file of patterns via "-f/--pattern-file".
+  7. For FASTA files searched by sequence repeatedly, create FM-index files
+     with "seqkit fmindex" once, and search with the flag --index.
+  8. For 8 or more plain or degenerate motifs, without mismatches or
+     regular expressions, all motifs are searched in one pass over each
+     strand with an Aho-Corasick automaton.
 
 Tips:
   1. Empty patterns are allowed. So you can search records with empty ID or sequence.
@@ -119,12 +125,14 @@ Examples:
 		bySeq := getFlagBool(cmd, "by-seq")
 		onlyPositiveStrand := getFlagBool(cmd, "only-positive-strand")
 		mismatches := getFlagNonNegativeInt(cmd, "max-mismatch")
+		maxEdits := getFlagNonNegativeInt(cmd, "max-edits")
 		byName := getFlagBool(cmd, "by-name")
 		ignoreCase := getFlagBool(cmd, "ignore-case")
 		degenerate := getFlagBool(cmd, "degenerate")
 		region := getFlagString(cmd, "region")
 		circular := getFlagBool(cmd, "circular")
 		allowDups := getFlagBool(cmd, "allow-duplicated-patterns")
+		useIndex := getFlagBool(cmd, "index")
 
 		immediateOutput := getFlagBool(cmd, "immediate-output")
 
@@ -172,10 +180,42 @@ Examples:
 			}
 		}
 
+		if maxEdits > 0 {
+			if mismatches > 0 {
+				checkError(fmt.Errorf("flag -m (--max-mismatch) and -e (--max-edits) are not compatible"))
+			}
+			if useRegexp {
+				checkError(fmt.Errorf("flag -r (--use-regexp) not allowed when giving flag -e (--max-edits)"))
+			}
+			if useIndex {
+				checkError(fmt.Errorf("flag --index not allowed when giving flag -e (--max-edits)"))
+			}
+			if !bySeq {
+				log.Infof("when value of flag -e (--max-edits) > 0, flag -s (--by-seq) is automatically on")
+				bySeq = true
+			}
+		}
+
 		if useRegexp && degenerate {
 			checkError(fmt.Errorf("could not give both flags -d (--degenerate) and -r (--use-regexp)"))
 		}
 
+		if useIndex {
+			if useRegexp || degenerate {
+				checkError(fmt.Errorf("flag -r (--use-regexp) or -d (--degenerate) not allowed when giving flag --index"))
+			}
+			if region != "" {
+				checkError(fmt.Errorf("flag -R (--region) not allowed when giving flag --index"))
+			}
+			if circular {
+				checkError(fmt.Errorf("flag -c (--circular) not allowed when giving flag --index"))
+			}
+			if !bySeq {
+				log.Infof("when flag --index given, flag -s (--by-seq) is automatically on")
+				bySeq = true
+			}
+		}
+
 		var start, end int
 		var err error
 		var limitRegion bool
@@ -206,6 +246,7 @@ Examples:
 		patternsN := make(map[uint64]int, 1<<20)
 		// patternsS := make(map[string]interface{}, 1<<10)
 		patternsS := make([][]byte, 0, 16)
+		patternsD := make([][]byte, 0, 16) // degenerate patterns, for the Aho-Corasick automaton
 
 		var pattern2seq *seq.Seq
 		var pbyte []byte
@@ -231,12 +272,13 @@ Examples:
 						}
 					}
 
-					if degenerate || useRegexp {
+					if (degenerate && maxEdits == 0) || useRegexp {
 						if degenerate {
 							pattern2seq, err = seq.NewSeq(alphabet, []byte(p))
 							if err != nil {
 								checkError(fmt.Errorf("it seems that flag -d is given, but you provide regular expression instead of available %s sequence", alphabet.String()))
 							}
+							patternsD = append(patternsD, pattern2seq.Seq)
 							p = pattern2seq.Degenerate2Regexp()
 						}
 						if ignoreCase {
@@ -250,6 +292,9 @@ Examples:
 						if mismatches > 0 && mismatches > len(p) {
 							checkError(fmt.Errorf("mismatch should be <= length of sequence: %s", p))
 						}
+						if maxEdits > 0 && maxEdits >= len(p) {
+							checkError(fmt.Errorf("edits should be < length of sequence: %s", p))
+						}
 						if seq.DNAredundant.IsValid(pbyte) == nil ||
 							seq.RNAredundant.IsValid(pbyte) == nil ||
 							seq.Protein.IsValid(pbyte) == nil { // legal sequence
@@ -291,7 +336,7 @@ Examples:
 					}
 				}
 
-				if degenerate || useRegexp {
+				if (degenerate && maxEdits == 0) || useRegexp {
 					if p == "" {
 						p = `^$`
 					} else {
@@ -300,6 +345,7 @@ Examples:
 							if err != nil {
 								checkError(fmt.Errorf("it seems that flag -d is given, but you provide regular expression instead of available %s sequence", alphabet.String()))
 							}
+							patternsD = append(patternsD, pattern2seq.Seq)
 							p = pattern2seq.Degenerate2Regexp()
 						}
 						if ignoreCase {
@@ -314,6 +360,9 @@ Examples:
 					if mismatches > 0 && mismatches > len(p) {
 						checkError(fmt.Errorf("mismatch should be <= length of sequence: %s", p))
 					}
+					if maxEdits > 0 && maxEdits >= len(p) {
+						checkError(fmt.Errorf("edits should be < length of sequence: %s", p))
+					}
 					if seq.DNAredundant.IsValid(pbyte) == nil ||
 						seq.RNAredundant.IsValid(pbyte) == nil ||
 						seq.Protein.IsValid(pbyte) == nil { // legal sequence
@@ -347,9 +396,73 @@ Examples:
 		var count int
 
 		// -------------------------------------------------------------------
-		// only for searching with sequences and mismatch > 0, were FMI is very slow
+		// searching with FM-index files created by "seqkit fmindex"
+
+		if useIndex {
+			var idx *FMIndexFile
+			var hits []bool
+			var i int
+			for _, file := range files {
+				idx, hits = grepWithFMIndex(file, patternsS, mismatches, ignoreCase, onlyPositiveStrand, config.Threads)
 
-		if bySeq && mismatches > 0 {
+				fastxReader, err := fastx.NewReader(alphabet, file, idRegexp)
+				checkError(err)
+				i = 0
+				for {
+					record, err = fastxReader.Read()
+					if err != nil {
+						if err == io.EOF {
+							break
+						}
+						checkError(err)
+						break
+					}
+					if i >= len(hits) || !bytes.Equal(record.Name, idx.SeqName(i)) {
+						checkError(fmt.Errorf(`FM-index file does not match the sequences: %s, please rebuild it with "seqkit fmindex %s"`,
+							fmIndexFileOf(file), file))
+					}
+					if hits[i] == invertMatch {
+						i++
+						continue
+					}
+					i++
+
+					if justCount {
+						count++
+						continue
+					}
+					record.FormatToWriter(outfh, config.LineWidth)
+					if immediateOutput {
+						outfh.Flush()
+					}
+				}
+				fastxReader.Close()
+				idx.Close()
+				if i != len(hits) {
+					checkError(fmt.Errorf(`FM-index file does not match the sequences: %s, please rebuild it with "seqkit fmindex %s"`,
+						fmIndexFileOf(file), file))
+				}
+			}
+
+			if justCount {
+				fmt.Fprintf(outfh, "%d\n", count)
+			}
+			return
+		}
+
+		// -------------------------------------------------------------------
+		// only for searching with sequences and mismatch > 0, were FMI is very slow,
+		// or with edit distance
+
+		var editMatchers []*EditMatcher
+		if maxEdits > 0 {
+			editMatchers = make([]*EditMatcher, len(patternsS))
+			for i, p := range patternsS {
+				editMatchers[i] = NewEditMatcher(p, maxEdits, degenerate, ignoreCase)
+			}
+		}
+
+		if bySeq && (mismatches > 0 || maxEdits > 0) {
 			type Arecord struct {
 				id     uint64
 				ok     bool
@@ -511,6 +624,15 @@ Examples:
 								target = bytes.ToLower(target)
 							}
 
+							if maxEdits > 0 {
+								for _, matcher := range editMatchers {
+									if hit = matcher.Match(target); hit {
+										break
+									}
+								}
+								continue
+							}
+
 							_, err = sfmi.Transform(target)
 							if err != nil {
 								checkError(fmt.Errorf("fail to build FMIndex for sequence: %s", record.Name))
@@ -558,11 +680,28 @@ Examples:
 			return
 		}
 
+		// -------------------------------------------------------------------
+		// many exact or degenerate patterns are searched in one pass with an
+		// Aho-Corasick automaton
+
+		var motifAC *MotifAutomaton
+		var ok bool
+		if bySeq && mismatches == 0 && !useRegexp {
+			if !degenerate && len(patternsS) >= acMinMotifs {
+				motifAC, ok = NewMotifAutomaton(patternsS, false, ignoreCase)
+			} else if degenerate && !deleteMatched && alphabet != seq.Protein && len(patternsD) >= acMinMotifs {
+				motifAC, ok = NewMotifAutomaton(patternsD, true, ignoreCase)
+			}
+			if !ok {
+				motifAC = nil
+			}
+		}
+
 		// -------------------------------------------------------------------
 
 		var sequence *seq.Seq
 		var target []byte
-		var ok, hit bool
+		var hit bool
 		// var k string
 		var k []byte
 		var re *regexp.Regexp
@@ -648,7 +787,12 @@ Examples:
 						}
 					}
 
-					if degenerate || useRegexp {
+					if motifAC != nil {
+						if ignoreCase {
+							target = bytes.ToLower(target)
+						}
+						hit = motifAC.Match(target)
+					} else if degenerate || useRegexp {
 						for h, re = range patternsR {
 							if re.Match(target) {
 								hit = true
@@ -739,11 +883,13 @@ func init() {
 	grepCmd.Flags().BoolP("by-seq", "s", false, "search subseq on seq. Both positive and negative strand are searched by default, you might use -P/--only-positive-strand. Mismatch allowed using flag -m/--max-mismatch")
 	grepCmd.Flags().BoolP("only-positive-strand", "P", false, "only search on the positive strand")
 	grepCmd.Flags().IntP("max-mismatch", "m", 0, "max mismatch when matching by seq. For large genomes like human genome, using mapping/alignment tools would be faster")
+	grepCmd.Flags().IntP("max-edits", "e", 0, "max edit distance (substitutions, insertions and deletions) when matching by seq, degenerate bases supported")
 	grepCmd.Flags().BoolP("ignore-case", "i", false, "ignore case")
 	grepCmd.Flags().BoolP("degenerate", "d", false, "pattern/motif contains degenerate base")
 	grepCmd.Flags().StringP("region", "R", "", "specify sequence region for searching. "+
 		"e.g 1:12 for first 12 bases, -12:-1 for last 12 bases")
 	grepCmd.Flags().BoolP("circular", "c", false, "circular genome")
+	grepCmd.Flags().BoolP("index", "", false, `use FM-index files (<infile>.fmi) created by "seqkit fmindex" for repeated searches by sequence`)
 	grepCmd.Flags().BoolP("immediate-output", "I", false, "print output immediately, do not use write buffer")
 	grepCmd.Flags().BoolP("count", "C", false, "just print a count of matching records. with the -v/--invert-match flag, count non-matching records")
 }
@@ -0,0 +1,321 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// hashEntry is the hash value of a record, used for disk-backed
+// deduplication and set operations.
+type hashEntry struct {
+	Hash  uint64
+	File  uint32 // index of the input file
+	Index uint64 // index of the record
+	Name  []byte // optional, e.g., the sequence ID
+}
+
+// estimated memory occupation of a hashEntry, excluding the name
+const hashEntrySize = 48
+
+func hashEntryLess(a, b *hashEntry) bool {
+	if a.Hash != b.Hash {
+		return a.Hash < b.Hash
+	}
+	if a.File != b.File {
+		return a.File < b.File
+	}
+	return a.Index < b.Index
+}
+
+// hashSorter sorts hash entries with bounded memory. Entries are kept in
+// a buffer, which is sorted and written to a temporary file (chunk) when
+// the memory exceeds the limit. Chunks are merged at last.
+type hashSorter struct {
+	dir    string
+	maxMem int64
+	mem    int64
+
+	entries []hashEntry
+	chunks  []string
+}
+
+// newHashSorter creates a hashSorter with a temporary directory in tmpDir.
+func newHashSorter(tmpDir string, maxMem int64) (*hashSorter, error) {
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(tmpDir, "seqkit-hash-*")
+	if err != nil {
+		return nil, err
+	}
+	return &hashSorter{
+		dir:     dir,
+		maxMem:  maxMem,
+		entries: make([]hashEntry, 0, 1024),
+	}, nil
+}
+
+// Dir returns the temporary directory, which could also be used by callers.
+func (s *hashSorter) Dir() string {
+	return s.dir
+}
+
+// Add adds an entry, the name is copied.
+func (s *hashSorter) Add(hash uint64, file uint32, index uint64, name []byte) error {
+	e := hashEntry{Hash: hash, File: file, Index: index}
+	if len(name) > 0 {
+		e.Name = append([]byte(nil), name...)
+	}
+	s.entries = append(s.entries, e)
+	s.mem += hashEntrySize + int64(len(name))
+	if s.mem >= s.maxMem {
+		return s.flush()
+	}
+	return nil
+}
+
+// flush sorts entries in the buffer and writes them into a chunk file.
+func (s *hashSorter) flush() error {
+	if len(s.entries) == 0 {
+		return nil
+	}
+	sort.Slice(s.entries, func(i, j int) bool { return hashEntryLess(&s.entries[i], &s.entries[j]) })
+
+	file := filepath.Join(s.dir, fmt.Sprintf("chunk_%06d.bin", len(s.chunks)))
+	fh, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriterSize(fh, 1<<20)
+	for i := range s.entries {
+		if err = writeHashEntry(w, &s.entries[i]); err != nil {
+			fh.Close()
+			return err
+		}
+	}
+	if err = w.Flush(); err != nil {
+		fh.Close()
+		return err
+	}
+	if err = fh.Close(); err != nil {
+		return err
+	}
+
+	s.chunks = append(s.chunks, file)
+	s.entries = s.entries[:0]
+	s.mem = 0
+	return nil
+}
+
+func writeHashEntry(w *bufio.Writer, e *hashEntry) error {
+	var buf [24]byte
+	le := binary.LittleEndian
+	le.PutUint64(buf[0:8], e.Hash)
+	le.PutUint64(buf[8:16], e.Index)
+	le.PutUint32(buf[16:20], e.File)
+	le.PutUint32(buf[20:24], uint32(len(e.Name)))
+	if _, err := w.Write(buf[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(e.Name)
+	return err
+}
+
+func readHashEntry(r *bufio.Reader, e *hashEntry) error {
+	var buf [24]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return err
+	}
+	le := binary.LittleEndian
+	e.Hash = le.Uint64(buf[0:8])
+	e.Index = le.Uint64(buf[8:16])
+	e.File = le.Uint32(buf[16:20])
+	n := le.Uint32(buf[20:24])
+	if n == 0 {
+		e.Name = nil
+		return nil
+	}
+	e.Name = make([]byte, n)
+	_, err := io.ReadFull(r, e.Name)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+type hashChunkReader struct {
+	fh    *os.File
+	r     *bufio.Reader
+	entry hashEntry
+}
+
+type hashChunkHeap []*hashChunkReader
+
+func (h hashChunkHeap) Len() int           { return len(h) }
+func (h hashChunkHeap) Less(i, j int) bool { return hashEntryLess(&h[i].entry, &h[j].entry) }
+func (h hashChunkHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *hashChunkHeap) Push(x any)        { *h = append(*h, x.(*hashChunkReader)) }
+func (h *hashChunkHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// Groups calls fn for every group of entries sharing the same hash value,
+// in which entries are sorted by file index and record index.
+// The slice passed to fn is reused.
+func (s *hashSorter) Groups(fn func(group []hashEntry) error) error {
+	var group []hashEntry
+	emit := func(e *hashEntry) error {
+		if len(group) > 0 && group[0].Hash != e.Hash {
+			if err := fn(group); err != nil {
+				return err
+			}
+			group = group[:0]
+		}
+		group = append(group, *e)
+		return nil
+	}
+
+	if len(s.chunks) == 0 { // all in memory
+		sort.Slice(s.entries, func(i, j int) bool { return hashEntryLess(&s.entries[i], &s.entries[j]) })
+		for i := range s.entries {
+			if err := emit(&s.entries[i]); err != nil {
+				return err
+			}
+		}
+	} else {
+		if err := s.flush(); err != nil {
+			return err
+		}
+
+		h := make(hashChunkHeap, 0, len(s.chunks))
+		defer func() {
+			for _, c := range h {
+				c.fh.Close()
+			}
+		}()
+		for _, file := range s.chunks {
+			fh, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			c := &hashChunkReader{fh: fh, r: bufio.NewReaderSize(fh, 1<<16)}
+			if err = readHashEntry(c.r, &c.entry); err != nil {
+				fh.Close()
+				if err == io.EOF {
+					continue
+				}
+				return err
+			}
+			h = append(h, c)
+		}
+		heap.Init(&h)
+
+		var c *hashChunkReader
+		var err error
+		for len(h) > 0 {
+			c = h[0]
+			if err = emit(&c.entry); err != nil {
+				return err
+			}
+			if err = readHashEntry(c.r, &c.entry); err != nil {
+				if err != io.EOF {
+					return err
+				}
+				c.fh.Close()
+				heap.Pop(&h)
+				continue
+			}
+			heap.Fix(&h, 0)
+		}
+	}
+
+	if len(group) > 0 {
+		return fn(group)
+	}
+	return nil
+}
+
+// Chunks returns the number of chunk files.
+func (s *hashSorter) Chunks() int {
+	return len(s.chunks)
+}
+
+// Close removes the temporary directory.
+func (s *hashSorter) Close() error {
+	s.entries = nil
+	return os.RemoveAll(s.dir)
+}
+
+// bitSet is a simple growable bit set for marking records.
+type bitSet []uint64
+
+func (b *bitSet) Set(i uint64) {
+	j := int(i >> 6)
+	if j >= len(*b) {
+		*b = append(*b, make([]uint64, j-len(*b)+1)...)
+	}
+	(*b)[j] |= 1 << (i & 63)
+}
+
+func (b bitSet) Has(i uint64) bool {
+	j := int(i >> 6)
+	if j >= len(b) {
+		return false
+	}
+	return b[j]&(1<<(i&63)) > 0
+}
+
+// copyStdinForRereading saves the data from stdin into a temporary file in
+// dir, so that all input files could be read more than once.
+func copyStdinForRereading(files []string, dir string, quiet bool) ([]string, error) {
+	files2 := make([]string, len(files))
+	for i, file := range files {
+		if !isStdin(file) {
+			files2[i] = file
+			continue
+		}
+		newFile := filepath.Join(dir, "stdin.fastx")
+		if !quiet {
+			log.Infof("read and write sequences from stdin to temporary file: %s ...", newFile)
+		}
+		n, err := copySeqs(file, newFile)
+		if err != nil {
+			return nil, err
+		}
+		if !quiet {
+			log.Infof("%d sequences saved", n)
+		}
+		files2[i] = newFile
+	}
+	return files2, nil
+}
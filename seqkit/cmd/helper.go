@@ -24,6 +24,7 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -213,6 +214,12 @@ func getFlagStringSlice(cmd *cobra.Command, flag string) []string {
 	return value
 }
 
+func getFlagStringArray(cmd *cobra.Command, flag string) []string {
+	value, err := cmd.Flags().GetStringArray(flag)
+	checkError(err)
+	return value
+}
+
 func getFlagFloat64Slice(cmd *cobra.Command, flag string) []float64 {
 	value, err := cmd.Flags().GetFloat64Slice(flag)
 	checkError(err)
@@ -649,3 +656,31 @@ func ParseByteSize(val string) (int64, error) {
 	}
 	return int64(size * float64(u)), nil
 }
+
+// ParseGenomeSize parses genome size from string, with decimal units,
+// i.e., K=1e3, M=1e6, G=1e9, and T=1e12.
+func ParseGenomeSize(val string) (int64, error) {
+	val = strings.Trim(val, " \t\r\n")
+	if val == "" {
+		return 0, nil
+	}
+	u := 1.0
+	switch val[len(val)-1] {
+	case 'K', 'k':
+		u = 1e3
+	case 'M', 'm':
+		u = 1e6
+	case 'G', 'g':
+		u = 1e9
+	case 'T', 't':
+		u = 1e12
+	}
+	if u > 1 {
+		val = strings.Trim(val[0:len(val)-1], " \t\r\n")
+	}
+	size, err := strconv.ParseFloat(val, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid genome size: %s", val)
+	}
+	return int64(math.Round(size * u)), nil
+}
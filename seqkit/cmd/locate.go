@@ -26,6 +26,7 @@ import (
 	"io"
 	"regexp"
 	"runtime"
+	"sort"
 	"sync"
 
 	"github.com/shenwei356/bio/seq"
@@ -61,6 +62,24 @@ Attention:
      you can increase the value of "-j/--threads" to accelerate processing.
   5. When using flag --circular, end position of matched subsequence that 
      crossing genome sequence end would be greater than sequence length.
+  6. Insertions and deletions are allowed using flag "-e/--max-edits", e.g.,
+     for Nanopore reads with homopolymer indels. The aligned span, number of
+     edits and extended CIGAR ("=" for match, "X" for substitution, "I" and
+     "D" for insertion and deletion in the pattern) are reported, and
+     the number of edits is used as the score in GTF/BED format. Among
+     overlapping matches ending at consecutive positions, only the one with
+     the fewest edits is reported, and the last one on ties, which covers
+     the whole pattern.
+  7. For FASTA files searched repeatedly, create FM-index files with
+     "seqkit fmindex" once, and search with the flag --index, where
+     input files are not read and only non-regular-expression patterns are
+     supported. The order of results may be different from that without
+     --index.
+  8. For 8 or more plain or degenerate motifs, without mismatches or
+     regular expressions, all motifs are searched in one pass over each
+     strand with an Aho-Corasick automaton, where degenerate motifs are
+     expanded to all possible sequences, unless too many. Results are
+     sorted by motif names, strands and positions.
 
 `,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -89,12 +108,14 @@ Attention:
 		degenerate := getFlagBool(cmd, "degenerate")
 		useRegexp := getFlagBool(cmd, "use-regexp")
 		useFMI := getFlagBool(cmd, "use-fmi")
+		useIndex := getFlagBool(cmd, "index")
 		ignoreCase := getFlagBool(cmd, "ignore-case")
 		onlyPositiveStrand := getFlagBool(cmd, "only-positive-strand")
 		nonGreedy := getFlagBool(cmd, "non-greedy")
 		outFmtGTF := getFlagBool(cmd, "gtf")
 		outFmtBED := getFlagBool(cmd, "bed")
 		mismatches := getFlagNonNegativeInt(cmd, "max-mismatch")
+		maxEdits := getFlagNonNegativeInt(cmd, "max-edits")
 		hideMatched := getFlagBool(cmd, "hide-matched")
 		circular := getFlagBool(cmd, "circular")
 		len2show := getFlagNonNegativeInt(cmd, "max-len-to-show")
@@ -137,6 +158,20 @@ Attention:
 			}
 
 		}
+		if maxEdits > 0 {
+			if mismatches > 0 {
+				checkError(fmt.Errorf("flag -m (--max-mismatch) and -e (--max-edits) are not compatible"))
+			}
+			if useRegexp {
+				checkError(fmt.Errorf("flag -r (--use-regexp) not allowed when giving flag -e (--max-edits)"))
+			}
+			if useFMI || useIndex {
+				checkError(fmt.Errorf("flag -F (--use-fmi) or --index not allowed when giving flag -e (--max-edits)"))
+			}
+			if nonGreedy && !quiet {
+				log.Infof("flag -G (--non-greedy) ignored when giving flag -e (--max-edits)")
+			}
+		}
 		if useFMI {
 			if degenerate {
 				checkError(fmt.Errorf("flag -d (--degenerate) ignored when giving flag -F (--use-fmi)"))
@@ -146,6 +181,21 @@ Attention:
 			}
 		}
 
+		if useIndex {
+			if degenerate {
+				checkError(fmt.Errorf("flag -d (--degenerate) not allowed when giving flag --index"))
+			}
+			if useRegexp {
+				checkError(fmt.Errorf("flag -r (--use-regexp) not allowed when giving flag --index"))
+			}
+			if circular {
+				checkError(fmt.Errorf("flag -c (--circular) not allowed when giving flag --index"))
+			}
+			if nonGreedy && !quiet {
+				log.Infof("flag -G (--non-greedy) ignored when giving flag --index")
+			}
+		}
+
 		// prepare pattern
 		regexps := make(map[string]*regexp.Regexp)
 		patterns := make(map[string][]byte)
@@ -173,10 +223,13 @@ Attention:
 				}
 
 				// check pattern
-				if mismatches > 0 {
+				if mismatches > 0 || maxEdits > 0 {
 					if mismatches > len(record.Seq.Seq) {
 						checkError(fmt.Errorf("mismatch should be <= length of sequence: %s", record.Seq.Seq))
 					}
+					if maxEdits >= len(record.Seq.Seq) {
+						checkError(fmt.Errorf("edits should be < length of sequence: %s", record.Seq.Seq))
+					}
 					if seq.DNAredundant.IsValid(record.Seq.Seq) == nil ||
 						seq.RNAredundant.IsValid(record.Seq.Seq) == nil ||
 						seq.Protein.IsValid(record.Seq.Seq) == nil { // legal sequence
@@ -222,10 +275,13 @@ Attention:
 				}
 
 				// check pattern
-				if mismatches > 0 {
+				if mismatches > 0 || maxEdits > 0 {
 					if mismatches > len(patterns[p]) {
 						checkError(fmt.Errorf("mismatch should be <= length of sequence: %s", p))
 					}
+					if maxEdits >= len(patterns[p]) {
+						checkError(fmt.Errorf("edits should be < length of sequence: %s", p))
+					}
 					if seq.DNAredundant.IsValid(patterns[p]) == nil ||
 						seq.RNAredundant.IsValid(patterns[p]) == nil ||
 						seq.Protein.IsValid(patterns[p]) == nil { // legal sequence
@@ -256,19 +312,65 @@ Attention:
 
 		if !(outFmtGTF || outFmtBED) {
 			if hideMatched {
-				outfh.WriteString("seqID\tpatternName\tpattern\tstrand\tstart\tend\n")
+				outfh.WriteString("seqID\tpatternName\tpattern\tstrand\tstart\tend")
 			} else {
-				outfh.WriteString("seqID\tpatternName\tpattern\tstrand\tstart\tend\tmatched\n")
+				outfh.WriteString("seqID\tpatternName\tpattern\tstrand\tstart\tend\tmatched")
+			}
+			if maxEdits > 0 {
+				outfh.WriteString("\tedits\tcigar")
+			}
+			outfh.WriteString("\n")
+		}
+
+		// matchers for edit distance
+		var pNames []string
+		var editMatchers []*EditMatcher
+		if maxEdits > 0 {
+			pNames = make([]string, 0, len(patterns))
+			for pName := range patterns {
+				pNames = append(pNames, pName)
+			}
+			sort.Strings(pNames)
+			editMatchers = make([]*EditMatcher, len(pNames))
+			for i, pName := range pNames {
+				editMatchers[i] = NewEditMatcher(patterns[pName], maxEdits, degenerate, ignoreCase)
 			}
 		}
 
+		// -------------------------------------------------------------------
+		// searching with FM-index files created by "seqkit fmindex"
+
+		if useIndex {
+			idRe, err := regexp.Compile(idRegexp)
+			checkError(err)
+
+			pNames := make([]string, 0, len(patterns))
+			for pName := range patterns {
+				pNames = append(pNames, pName)
+			}
+			sort.Strings(pNames)
+			pSeqs := make([][]byte, len(pNames))
+			for i, pName := range pNames {
+				pSeqs[i] = patterns[pName]
+			}
+
+			for _, file := range files {
+				locateWithFMIndex(outfh, file, idRe, pNames, pSeqs, mismatches, ignoreCase, onlyPositiveStrand,
+					outFmtGTF, outFmtBED, hideMatched, len2show, config.Threads)
+				if immediateOutput {
+					outfh.Flush()
+				}
+			}
+			return
+		}
+
 		// -------------------------------------------------------------------
 		// only for m > 0, where FMI is slow
 
 		var record *fastx.Record
 		_onlyPositiveStrand := onlyPositiveStrand
 
-		if mismatches > 0 || useFMI {
+		if mismatches > 0 || useFMI || maxEdits > 0 {
 			type Arecord struct {
 				id     uint64
 				ok     bool
@@ -383,6 +485,14 @@ Attention:
 							<-tokens
 						}()
 
+						if maxEdits > 0 {
+							results := locateEditsInRecord(record, pNames, patterns, editMatchers,
+								!(degenerate || useRegexp) && ignoreCase, _onlyPositiveStrand, circular,
+								outFmtGTF, outFmtBED, hideMatched, len2show)
+							ch <- &Arecord{record: results, id: id, ok: len(results) > 0}
+							return
+						}
+
 						var seqRP *seq.Seq
 						var l int
 						var sfmi *fmi.FMIndex
@@ -585,6 +695,28 @@ Attention:
 			return
 		}
 
+		// -------------------------------------------------------------------
+		// many exact or degenerate patterns are searched in one pass with an
+		// Aho-Corasick automaton
+
+		var motifAC *MotifAutomaton
+		var acNames []string
+		if !useRegexp && len(patterns) >= acMinMotifs && !(degenerate && alphabet == seq.Protein) {
+			acNames = make([]string, 0, len(patterns))
+			for pName := range patterns {
+				acNames = append(acNames, pName)
+			}
+			sort.Strings(acNames)
+			motifs := make([][]byte, len(acNames))
+			for i, pName := range acNames {
+				motifs[i] = patterns[pName]
+			}
+			var ok bool
+			if motifAC, ok = NewMotifAutomaton(motifs, degenerate, ignoreCase); !ok {
+				motifAC = nil
+			}
+		}
+
 		// -------------------------------------------------------------------
 
 		var seqRP *seq.Seq
@@ -777,6 +909,18 @@ Attention:
 					continue
 				}
 
+				if motifAC != nil {
+					locateMotifsInRecord(outfh, record, l, acNames, patterns, motifAC,
+						degenerate && ignoreCase, onlyPositiveStrand, circular, nonGreedy,
+						outFmtGTF, outFmtBED, hideMatched, len2show)
+
+					if immediateOutput {
+						outfh.Flush()
+					}
+
+					continue
+				}
+
 				for pName = range patterns {
 					// locs = locs[:0]
 
@@ -989,12 +1133,14 @@ func init() {
 	locateCmd.Flags().BoolP("degenerate", "d", false, "pattern/motif contains degenerate base")
 	locateCmd.Flags().BoolP("use-regexp", "r", false, "patterns/motifs are regular expression")
 	locateCmd.Flags().BoolP("use-fmi", "F", false, "use FM-index for much faster search of lots of sequence patterns")
+	locateCmd.Flags().BoolP("index", "", false, `use FM-index files (<infile>.fmi) created by "seqkit fmindex" for repeated searches`)
 	locateCmd.Flags().BoolP("ignore-case", "i", false, "ignore case")
 	locateCmd.Flags().BoolP("only-positive-strand", "P", false, "only search on positive strand")
 	locateCmd.Flags().BoolP("non-greedy", "G", false, "non-greedy mode, faster but may miss motifs overlapping with others")
 	locateCmd.Flags().BoolP("gtf", "", false, "output in GTF format")
 	locateCmd.Flags().BoolP("bed", "", false, "output in BED6 format")
 	locateCmd.Flags().IntP("max-mismatch", "m", 0, "max mismatch when matching by seq. For large genomes like human genome, using mapping/alignment tools would be faster")
+	locateCmd.Flags().IntP("max-edits", "e", 0, "max edit distance (substitutions, insertions and deletions) when matching by seq, degenerate bases supported")
 	locateCmd.Flags().BoolP("hide-matched", "M", false, "do not show matched sequences")
 	locateCmd.Flags().IntP("max-len-to-show", "s", 0, "show at most X characters for the search pattern or matched sequences")
 	locateCmd.Flags().BoolP("circular", "c", false, `circular genome. type "seqkit locate -h" for details`)
@@ -1013,3 +1159,147 @@ func prune(s []byte, n int) []byte {
 
 	return []byte(string(s[:n]) + "...")
 }
+
+// locateEditsInRecord locates patterns with edit distance in a sequence,
+// and returns formatted rows.
+func locateEditsInRecord(record *fastx.Record, pNames []string, patterns map[string][]byte,
+	matchers []*EditMatcher, toLower bool, onlyPositiveStrand bool, circular bool,
+	outFmtGTF bool, outFmtBED bool, hideMatched bool, len2show int) []string {
+
+	if toLower {
+		record.Seq.Seq = bytes.ToLower(record.Seq.Seq)
+	}
+	l := len(record.Seq.Seq)
+	if circular { // concat two copies of sequence
+		record.Seq.Seq = append(record.Seq.Seq, record.Seq.Seq...)
+	}
+
+	results := make([]string, 0, 2)
+	var begin, end int
+	var matched []byte
+	for _, strand := range []byte{'+', '-'} {
+		sequence := record.Seq
+		if strand == '-' {
+			if onlyPositiveStrand {
+				break
+			}
+			sequence = record.Seq.RevCom()
+		}
+		for i, pName := range pNames {
+			for _, h := range matchers[i].Find(sequence.Seq) {
+				if circular && h.Start >= l { // 2nd clone of original part
+					continue
+				}
+				if strand == '+' {
+					begin, end = h.Start+1, h.End
+				} else {
+					begin, end = l-h.End+1, l-h.Start
+					if begin < 1 { // crossing the sequence end
+						begin, end = begin+l, end+l
+					}
+				}
+				if outFmtGTF {
+					results = append(results, fmt.Sprintf("%s\t%s\t%s\t%d\t%d\t%d\t%c\t%s\tgene_id \"%s\"; \n",
+						record.ID, "SeqKit", "location", begin, end, h.Edits, strand, ".", pName))
+				} else if outFmtBED {
+					results = append(results, fmt.Sprintf("%s\t%d\t%d\t%s\t%d\t%c\n",
+						record.ID, begin-1, end, pName, h.Edits, strand))
+				} else if hideMatched {
+					results = append(results, fmt.Sprintf("%s\t%s\t%s\t%c\t%d\t%d\t%d\t%s\n",
+						record.ID, pName, prune(patterns[pName], len2show), strand, begin, end, h.Edits, h.CIGAR))
+				} else {
+					matched = sequence.Seq[h.Start:h.End]
+					results = append(results, fmt.Sprintf("%s\t%s\t%s\t%c\t%d\t%d\t%s\t%d\t%s\n",
+						record.ID, pName, prune(patterns[pName], len2show), strand, begin, end,
+						prune(matched, len2show), h.Edits, h.CIGAR))
+				}
+			}
+		}
+	}
+	return results
+}
+
+// locateMotifsInRecord searches all patterns in one pass on each strand
+// with an Aho-Corasick automaton, and outputs locations sorted by patterns,
+// strands and positions.
+func locateMotifsInRecord(outfh *xopen.Writer, record *fastx.Record, l int, pNames []string,
+	patterns map[string][]byte, ma *MotifAutomaton, toLower bool, onlyPositiveStrand bool,
+	circular bool, nonGreedy bool, outFmtGTF bool, outFmtBED bool, hideMatched bool, len2show int) {
+
+	type acHit struct {
+		motif, strand, start int
+	}
+	hits := make([]acHit, 0, 8)
+	sequences := [2]*seq.Seq{record.Seq, nil}
+	var text []byte
+	var lastEnd map[int]int
+	for strand := 0; strand < 2; strand++ {
+		if strand == 1 {
+			if onlyPositiveStrand {
+				break
+			}
+			sequences[1] = record.Seq.RevCom()
+		}
+		text = sequences[strand].Seq
+		if toLower {
+			text = bytes.ToLower(text)
+		}
+		if nonGreedy {
+			lastEnd = make(map[int]int, 8)
+		}
+		ma.Find(text, func(motif int, start int) {
+			if circular && start >= l { // 2nd clone of original part
+				return
+			}
+			if nonGreedy {
+				if start < lastEnd[motif] {
+					return
+				}
+				lastEnd[motif] = start + len(patterns[pNames[motif]])
+			}
+			hits = append(hits, acHit{motif: motif, strand: strand, start: start})
+		})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].motif != hits[j].motif {
+			return hits[i].motif < hits[j].motif
+		}
+		if hits[i].strand != hits[j].strand {
+			return hits[i].strand < hits[j].strand
+		}
+		return hits[i].start < hits[j].start
+	})
+
+	var pName string
+	var begin, end, n int
+	var strand byte
+	for _, h := range hits {
+		pName = pNames[h.motif]
+		n = len(patterns[pName])
+		if h.strand == 0 {
+			strand = '+'
+			begin, end = h.start+1, h.start+n
+		} else {
+			strand = '-'
+			begin, end = l-h.start-n+1, l-h.start
+			if begin < 1 { // crossing the sequence end
+				begin, end = begin+l, end+l
+			}
+		}
+		if outFmtGTF {
+			outfh.WriteString(fmt.Sprintf("%s\t%s\t%s\t%d\t%d\t%d\t%c\t%s\tgene_id \"%s\"; \n",
+				record.ID, "SeqKit", "location", begin, end, 0, strand, ".", pName))
+		} else if outFmtBED {
+			outfh.WriteString(fmt.Sprintf("%s\t%d\t%d\t%s\t%d\t%c\n",
+				record.ID, begin-1, end, pName, 0, strand))
+		} else if hideMatched {
+			outfh.WriteString(fmt.Sprintf("%s\t%s\t%s\t%c\t%d\t%d\n",
+				record.ID, pName, prune(patterns[pName], len2show), strand, begin, end))
+		} else {
+			outfh.WriteString(fmt.Sprintf("%s\t%s\t%s\t%c\t%d\t%d\t%s\n",
+				record.ID, pName, prune(patterns[pName], len2show), strand, begin, end,
+				prune(sequences[h.strand].Seq[h.start:h.start+n], len2show)))
+		}
+	}
+}
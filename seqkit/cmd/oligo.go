@@ -0,0 +1,579 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/shenwei356/bio/seq"
+	"github.com/shenwei356/bio/seqio/fastx"
+	"github.com/shenwei356/xopen"
+	"github.com/spf13/cobra"
+)
+
+// oligoCmd represents the oligo command
+var oligoCmd = &cobra.Command{
+	GroupID: "search",
+
+	Use:   "oligo",
+	Short: "oligo properties and primer QC (Tm, GC, hairpin, dimers)",
+	Long: `oligo properties and primer QC (Tm, GC, hairpin, dimers)
+
+Oligos can be given as FASTA/Q sequences, or via a primer file (-p/--primer-file)
+in the same format of 'seqkit amplicon', i.e., a 2- or 3-column tab-delimited
+file with the first column as primer name, followed by forward primer and
+optional reverse primer (5'-primer-3').
+
+Output columns:
+
+  1.  name              oligo name (sequence ID or primer name)
+  2.  primer            F or R for primers in the primer file, "." for sequences
+  3.  seq               oligo sequence
+  4.  length            oligo length
+  5.  GC                GC content (%)
+  6.  Tm                melting temperature (°C)
+  7.  hairpin_dG        ΔG (kcal/mol) of the most stable hairpin
+  8.  self_dimer_dG     ΔG (kcal/mol) of the most stable self-dimer
+  9.  self_dimer_3end_dG  ΔG (kcal/mol) of the most stable self-dimer involving
+                        the 3' end
+  10. end_stability_dG  ΔG (kcal/mol) of the duplex of the last 5 bases at the
+                        3' end, i.e., 3'-end stability
+
+Cross-dimers (-x/--cross-dimers): ΔG of the most stable dimers between all
+pairs of oligos, sorted by ΔG, with columns:
+
+  name1, primer1, name2, primer2, dimer_dG, dimer_3end_dG
+
+Attention:
+
+  1. Tm is computed with the nearest-neighbor model using the unified
+     parameters of SantaLucia (1998), assuming equal concentrations of
+     the oligo and its target. Salt corrections (--salt-correction):
+       santalucia  SantaLucia (1998) entropy correction, where Mg2+ is
+                   converted into monovalent equivalent (von Ahsen 2001):
+                   [Mon+] + 120 * sqrt([Mg2+] - [dNTP])
+       owczarzy    Owczarzy et al. (2008), considering both monovalent
+                   and divalent cations.
+  2. Degenerate bases are supported in Tm and GC content computation,
+     where nearest-neighbor parameters of all possible bases are averaged.
+  3. Hairpins and dimers are evaluated with a simplified model: only
+     contiguous Watson-Crick base pairs are considered (no internal loops,
+     bulges or dangling ends), and degenerate bases never pair.
+     ΔG values are computed at the temperature of -T/--temperature, and
+     0 means no stable structure found. They are designed for fast
+     screening, please check the candidates with other dedicated tools.
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := getConfigs(cmd)
+		alphabet := config.Alphabet
+		idRegexp := config.IDRegexp
+		outFile := config.OutFile
+		seq.AlphabetGuessSeqLengthThreshold = config.AlphabetGuessSeqLength
+		seq.ValidateSeq = false
+		runtime.GOMAXPROCS(config.Threads)
+
+		primerFile := getFlagString(cmd, "primer-file")
+		crossDimers := getFlagBool(cmd, "cross-dimers")
+		decimal := getFlagNonNegativeInt(cmd, "decimal-width")
+		cond := getOligoConditions(cmd)
+
+		var oligos []*Oligo
+		if primerFile != "" {
+			if len(args) > 0 {
+				log.Warningf("positional arguments are ignored when -p/--primer-file is given")
+			}
+			list, err := loadPrimers(primerFile)
+			checkError(err)
+			if len(list) == 0 {
+				checkError(fmt.Errorf("no primers found in file: %s", primerFile))
+			}
+			for _, items := range list {
+				for i, primer := range []string{"F", "R"} {
+					if items[i+1] == "" {
+						continue
+					}
+					o, err := NewOligo(items[0], primer, []byte(items[i+1]))
+					checkError(err)
+					oligos = append(oligos, o)
+				}
+			}
+		} else {
+			files := getFileListFromArgsAndFile(cmd, args, true, "infile-list", !config.SkipFileCheck)
+			if !config.SkipFileCheck {
+				for _, file := range files {
+					checkIfFilesAreTheSame(file, outFile, "input", "output")
+				}
+			}
+
+			var record *fastx.Record
+			for _, file := range files {
+				fastxReader, err := fastx.NewReader(alphabet, file, idRegexp)
+				checkError(err)
+
+				for {
+					record, err = fastxReader.Read()
+					if err != nil {
+						if err == io.EOF {
+							break
+						}
+						checkError(err)
+						break
+					}
+
+					o, err := NewOligo(string(record.ID), ".", record.Seq.Seq)
+					checkError(err)
+					oligos = append(oligos, o)
+				}
+				fastxReader.Close()
+			}
+		}
+
+		outfh, err := xopen.Wopen(outFile)
+		checkError(err)
+		defer outfh.Close()
+
+		ff := fmt.Sprintf("%%.%df", decimal)
+
+		if crossDimers {
+			type dimer struct {
+				o1, o2  *Oligo
+				dg, dg3 float64
+			}
+			dimers := make([]dimer, 0, len(oligos)*(len(oligos)-1)/2)
+			var dg, dg3 float64
+			for i, o1 := range oligos {
+				for _, o2 := range oligos[i+1:] {
+					dg, dg3 = cond.Dimer(o1.Seq, o2.Seq)
+					dimers = append(dimers, dimer{o1: o1, o2: o2, dg: dg, dg3: dg3})
+				}
+			}
+			sort.SliceStable(dimers, func(i, j int) bool { return dimers[i].dg < dimers[j].dg })
+
+			outfh.WriteString("name1\tprimer1\tname2\tprimer2\tdimer_dG\tdimer_3end_dG\n")
+			for _, d := range dimers {
+				outfh.WriteString(fmt.Sprintf("%s\t%s\t%s\t%s\t"+ff+"\t"+ff+"\n",
+					d.o1.Name, d.o1.Primer, d.o2.Name, d.o2.Primer, d.dg, d.dg3))
+			}
+			return
+		}
+
+		outfh.WriteString("name\tprimer\tseq\tlength\tGC\tTm\thairpin_dG\tself_dimer_dG\tself_dimer_3end_dG\tend_stability_dG\n")
+		var dg, dg3 float64
+		for _, o := range oligos {
+			dg, dg3 = cond.Dimer(o.Seq, o.Seq)
+			outfh.WriteString(fmt.Sprintf("%s\t%s\t%s\t%d\t"+ff+"\t"+ff+"\t"+ff+"\t"+ff+"\t"+ff+"\t"+ff+"\n",
+				o.Name, o.Primer, o.Seq, len(o.Seq),
+				OligoGC(o.Seq),
+				cond.Tm(o.Seq),
+				cond.Hairpin(o.Seq),
+				dg, dg3,
+				cond.EndStability(o.Seq, 5)))
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(oligoCmd)
+
+	oligoCmd.Flags().StringP("primer-file", "p", "", "3- or 2-column tabular primer file, with first column as primer name, the same as 'seqkit amplicon'")
+	oligoCmd.Flags().BoolP("cross-dimers", "x", false, "output cross-dimers between all pairs of oligos")
+	oligoCmd.Flags().IntP("decimal-width", "d", 2, "decimal width")
+	addOligoConditionFlags(oligoCmd)
+}
+
+func addOligoConditionFlags(cmd *cobra.Command) {
+	cmd.Flags().Float64P("mono", "", 50, "concentration of monovalent cations (Na+, K+) in mM")
+	cmd.Flags().Float64P("mg", "", 1.5, "concentration of Mg2+ in mM")
+	cmd.Flags().Float64P("dntp", "", 0.6, "concentration of dNTPs in mM")
+	cmd.Flags().Float64P("oligo-conc", "", 50, "concentration of oligos in nM")
+	cmd.Flags().StringP("salt-correction", "", "santalucia", "salt correction method for Tm: santalucia, owczarzy")
+	cmd.Flags().Float64P("temperature", "T", 37, "temperature (°C) for computing ΔG")
+}
+
+func getOligoConditions(cmd *cobra.Command) *OligoConditions {
+	c := &OligoConditions{
+		Mono:           getFlagFloat64(cmd, "mono"),
+		Mg:             getFlagFloat64(cmd, "mg"),
+		DNTP:           getFlagFloat64(cmd, "dntp"),
+		OligoConc:      getFlagFloat64(cmd, "oligo-conc"),
+		SaltCorrection: strings.ToLower(getFlagString(cmd, "salt-correction")),
+		Temperature:    getFlagFloat64(cmd, "temperature"),
+	}
+	if c.Mono < 0 || c.Mg < 0 || c.DNTP < 0 {
+		checkError(fmt.Errorf("concentrations of cations and dNTPs should not be negative"))
+	}
+	if c.OligoConc <= 0 {
+		checkError(fmt.Errorf("value of --oligo-conc should be positive"))
+	}
+	if c.Mono == 0 && c.Mg <= c.DNTP {
+		checkError(fmt.Errorf("no free cations: --mono is 0 and --mg is not greater than --dntp"))
+	}
+	switch c.SaltCorrection {
+	case "santalucia", "owczarzy":
+	default:
+		checkError(fmt.Errorf("invalid salt correction method: %s, available: santalucia, owczarzy", c.SaltCorrection))
+	}
+	return c
+}
+
+// Oligo is an oligonucleotide, e.g., a primer.
+type Oligo struct {
+	Name   string
+	Primer string // F, R, or .
+	Seq    []byte // upper case DNA
+}
+
+// NewOligo creates an Oligo, RNA bases are converted to DNA.
+func NewOligo(name, primer string, s []byte) (*Oligo, error) {
+	s = bytes.ToUpper(bytes.ReplaceAll(bytes.ReplaceAll(s, []byte("u"), []byte("t")), []byte("U"), []byte("T")))
+	if len(s) < 2 {
+		return nil, fmt.Errorf("oligo too short: %s", name)
+	}
+	if seq.DNAredundant.IsValid(s) != nil {
+		return nil, fmt.Errorf("invalid oligo sequence of %s: %s", name, s)
+	}
+	return &Oligo{Name: name, Primer: primer, Seq: s}, nil
+}
+
+// OligoConditions contains reaction conditions for computing
+// thermodynamic properties of oligos.
+type OligoConditions struct {
+	Mono      float64 // monovalent cations, mM
+	Mg        float64 // Mg2+, mM
+	DNTP      float64 // dNTPs, mM
+	OligoConc float64 // oligo concentration, nM
+
+	SaltCorrection string  // santalucia or owczarzy
+	Temperature    float64 // temperature (°C) for computing ΔG
+}
+
+// DefaultOligoConditions returns the default reaction conditions.
+func DefaultOligoConditions() *OligoConditions {
+	return &OligoConditions{
+		Mono:           50,
+		Mg:             1.5,
+		DNTP:           0.6,
+		OligoConc:      50,
+		SaltCorrection: "santalucia",
+		Temperature:    37,
+	}
+}
+
+// unified nearest-neighbor parameters of SantaLucia (1998), ΔH (kcal/mol), ΔS (cal/K/mol).
+var oligoNNParams = map[string][2]float64{
+	"AA": {-7.9, -22.2},
+	"AT": {-7.2, -20.4},
+	"TA": {-7.2, -21.3},
+	"CA": {-8.5, -22.7},
+	"GT": {-8.4, -22.4},
+	"CT": {-7.8, -21.0},
+	"GA": {-8.2, -22.2},
+	"CG": {-10.6, -27.2},
+	"GC": {-9.8, -24.4},
+	"GG": {-8.0, -19.9},
+}
+
+var oligoInitGC = [2]float64{0.1, -2.8}
+var oligoInitAT = [2]float64{2.3, 4.1}
+var oligoSymmetryCorrection = -1.4 // ΔS
+
+// hairpin loop ΔG37 (kcal/mol) of SantaLucia and Hicks (2004)
+var oligoHairpinLoops = [][2]float64{
+	{3, 3.5}, {4, 3.5}, {5, 3.3}, {6, 4.0}, {7, 4.2}, {8, 4.3}, {9, 4.5}, {10, 4.6},
+	{12, 5.0}, {14, 5.1}, {16, 5.3}, {18, 5.5}, {20, 5.7}, {25, 6.1}, {30, 6.3},
+}
+
+const oligoR = 1.9872 // gas constant, cal/K/mol
+
+var oligoNN [256][256][2]float64  // ΔH and ΔS of nearest neighbors, degenerate bases included
+var oligoInit [256][2]float64     // ΔH and ΔS of initiation with the terminal base
+var oligoGCFrac [256]float64      // GC fraction of bases
+var oligoWCComp [256]byte         // Watson-Crick complement of ACGT, 0 for others
+var oligoIUPAC = map[byte]string{ // bases of IUPAC codes
+	'A': "A", 'C': "C", 'G': "G", 'T': "T",
+	'R': "AG", 'Y': "CT", 'S': "GC", 'W': "AT", 'K': "GT", 'M': "AC",
+	'B': "CGT", 'D': "AGT", 'H': "ACT", 'V': "ACG", 'N': "ACGT",
+}
+
+func init() {
+	comp := map[byte]byte{'A': 'T', 'C': 'G', 'G': 'C', 'T': 'A'}
+	for b, c := range comp {
+		oligoWCComp[b] = c
+	}
+
+	nn := make(map[string][2]float64, 16)
+	for k, v := range oligoNNParams {
+		nn[k] = v
+		nn[string([]byte{comp[k[1]], comp[k[0]]})] = v
+	}
+
+	var a, b byte
+	var n float64
+	var v [2]float64
+	for code1, bases1 := range oligoIUPAC {
+		// GC fraction and initiation
+		n = 0
+		v = [2]float64{0, 0}
+		for i := 0; i < len(bases1); i++ {
+			a = bases1[i]
+			if a == 'G' || a == 'C' {
+				n++
+				v[0] += oligoInitGC[0]
+				v[1] += oligoInitGC[1]
+			} else {
+				v[0] += oligoInitAT[0]
+				v[1] += oligoInitAT[1]
+			}
+		}
+		oligoGCFrac[code1] = n / float64(len(bases1))
+		oligoInit[code1] = [2]float64{v[0] / float64(len(bases1)), v[1] / float64(len(bases1))}
+
+		// nearest neighbors
+		for code2, bases2 := range oligoIUPAC {
+			v = [2]float64{0, 0}
+			for i := 0; i < len(bases1); i++ {
+				a = bases1[i]
+				for j := 0; j < len(bases2); j++ {
+					b = bases2[j]
+					v[0] += nn[string([]byte{a, b})][0]
+					v[1] += nn[string([]byte{a, b})][1]
+				}
+			}
+			n = float64(len(bases1) * len(bases2))
+			oligoNN[code1][code2] = [2]float64{v[0] / n, v[1] / n}
+		}
+	}
+}
+
+// OligoGC returns the GC content (%) of an oligo, degenerate bases are counted
+// by the fraction of G and C in all possible bases.
+func OligoGC(s []byte) float64 {
+	if len(s) == 0 {
+		return 0
+	}
+	var n float64
+	for _, b := range s {
+		n += oligoGCFrac[b]
+	}
+	return n / float64(len(s)) * 100
+}
+
+// oligoIsSelfComplementary checks if a sequence equals its reverse complement.
+func oligoIsSelfComplementary(s []byte) bool {
+	l := len(s)
+	for i := 0; i < (l+1)/2; i++ {
+		if oligoWCComp[s[i]] == 0 || oligoWCComp[s[i]] != s[l-1-i] {
+			return false
+		}
+	}
+	return true
+}
+
+// oligoThermo returns ΔH (kcal/mol) and ΔS (cal/K/mol) of the perfect duplex
+// of s in 1 M NaCl.
+func oligoThermo(s []byte) (dH, dS float64) {
+	l := len(s)
+	for i := 0; i < l-1; i++ {
+		dH += oligoNN[s[i]][s[i+1]][0]
+		dS += oligoNN[s[i]][s[i+1]][1]
+	}
+	dH += oligoInit[s[0]][0] + oligoInit[s[l-1]][0]
+	dS += oligoInit[s[0]][1] + oligoInit[s[l-1]][1]
+	return
+}
+
+// naEquivalent returns the monovalent equivalent (M) of cations.
+func (c *OligoConditions) naEquivalent() float64 {
+	na := c.Mono
+	if c.Mg > c.DNTP {
+		na += 120 * math.Sqrt(c.Mg-c.DNTP)
+	}
+	return na / 1000
+}
+
+// saltDS returns the SantaLucia (1998) salt correction of ΔS (cal/K/mol)
+// for a duplex with n base pairs.
+func (c *OligoConditions) saltDS(n int) float64 {
+	return 0.368 * float64(n-1) * math.Log(c.naEquivalent())
+}
+
+// Tm returns the melting temperature (°C) of an oligo.
+func (c *OligoConditions) Tm(s []byte) float64 {
+	l := len(s)
+	if l < 2 {
+		return 0
+	}
+	dH, dS := oligoThermo(s)
+
+	ct := c.OligoConc * 1e-9
+	x := 4.0
+	if oligoIsSelfComplementary(s) {
+		dS += oligoSymmetryCorrection
+		x = 1
+	}
+
+	if c.SaltCorrection == "santalucia" {
+		dS += c.saltDS(l)
+		return dH*1000/(dS+oligoR*math.Log(ct/x)) - 273.15
+	}
+
+	// Owczarzy et al. (2008)
+	tm1M := dH * 1000 / (dS + oligoR*math.Log(ct/x))
+	mon := c.Mono / 1000
+	mg := math.Max(c.Mg-c.DNTP, 0) / 1000
+	fGC := OligoGC(s) / 100
+	var inv float64
+	if mg == 0 || (mon > 0 && math.Sqrt(mg)/mon < 0.22) { // monovalent cations dominate, Owczarzy et al. (2004)
+		lnMon := math.Log(mon)
+		inv = 1/tm1M + (4.29*fGC-3.95)*1e-5*lnMon + 9.40e-6*lnMon*lnMon
+	} else {
+		a, b, cc, d, e, f, g := 3.92e-5, -9.11e-6, 6.26e-5, 1.42e-5, -4.82e-4, 5.25e-4, 8.31e-5
+		if mon > 0 && math.Sqrt(mg)/mon < 6 {
+			lnMon := math.Log(mon)
+			a = 3.92e-5 * (0.843 - 0.352*math.Sqrt(mon)*lnMon)
+			d = 1.42e-5 * (1.279 - 4.03e-3*lnMon - 8.03e-3*lnMon*lnMon)
+			g = 8.31e-5 * (0.486 - 0.258*lnMon + 5.25e-3*lnMon*lnMon*lnMon)
+		}
+		lnMg := math.Log(mg)
+		inv = 1/tm1M + a + b*lnMg + fGC*(cc+d*lnMg) +
+			1/(2*float64(l-1))*(e+f*lnMg+g*lnMg*lnMg)
+	}
+	return 1/inv - 273.15
+}
+
+// kelvin returns the temperature in Kelvin.
+func (c *OligoConditions) kelvin() float64 {
+	return c.Temperature + 273.15
+}
+
+// duplexDG returns ΔG of a contiguous duplex of n base pairs with the given ΔH and ΔS.
+func (c *OligoConditions) duplexDG(dH, dS float64, n int) float64 {
+	return dH - c.kelvin()*(dS+c.saltDS(n))/1000
+}
+
+// EndStability returns ΔG (kcal/mol) of the perfect duplex of the last n bases.
+func (c *OligoConditions) EndStability(s []byte, n int) float64 {
+	if len(s) > n {
+		s = s[len(s)-n:]
+	}
+	if len(s) < 2 {
+		return 0
+	}
+	dH, dS := oligoThermo(s)
+	return c.duplexDG(dH, dS, len(s))
+}
+
+// Dimer returns ΔG (kcal/mol) of the most stable dimer of two oligos
+// and that of dimers involving the 3' end of either oligo.
+// Only contiguous Watson-Crick base pairs are considered.
+func (c *OligoConditions) Dimer(a, b []byte) (dg, dg3 float64) {
+	la, lb := len(a), len(b)
+	var i, j, i0, n int
+	var dH, dS, g float64
+	for d := 0; d <= la+lb-2; d++ { // a[i] pairs with b[d-i]
+		n = 0
+		for i = max(0, d-lb+1); i <= min(la-1, d); i++ {
+			j = d - i
+			if oligoWCComp[a[i]] != 0 && oligoWCComp[a[i]] == b[j] {
+				if n == 0 {
+					i0 = i
+					dH, dS = oligoInit[a[i]][0], oligoInit[a[i]][1]
+				} else {
+					dH += oligoNN[a[i-1]][a[i]][0]
+					dS += oligoNN[a[i-1]][a[i]][1]
+				}
+				n++
+			} else {
+				n = 0
+			}
+
+			if n < 2 { // the run is not extended
+				continue
+			}
+			// ends of the run: a[i0..i] pairs with b[d-i0..d-i]
+			g = c.duplexDG(dH+oligoInit[a[i]][0], dS+oligoInit[a[i]][1], n)
+			if g < dg {
+				dg = g
+			}
+			if (i == la-1 || d-i0 == lb-1) && g < dg3 {
+				dg3 = g
+			}
+		}
+	}
+	return dg, dg3
+}
+
+// Hairpin returns ΔG (kcal/mol) of the most stable hairpin of an oligo.
+// Only stems of contiguous Watson-Crick base pairs are considered,
+// and the minimum loop size is 3.
+func (c *OligoConditions) Hairpin(s []byte) float64 {
+	l := len(s)
+	var dg, dH, dS, g, loop float64
+	var i, j, k int
+	for i = 0; i < l; i++ {
+		for j = i + 4; j < l; j++ { // the innermost pair (i, j)
+			if oligoWCComp[s[i]] == 0 || oligoWCComp[s[i]] != s[j] {
+				continue
+			}
+			loop = c.hairpinLoopDG(j - i - 1)
+			dH, dS = 0, 0
+			for k = 1; i-k >= 0 && j+k < l; k++ {
+				if oligoWCComp[s[i-k]] == 0 || oligoWCComp[s[i-k]] != s[j+k] {
+					break
+				}
+				dH += oligoNN[s[i-k]][s[i-k+1]][0]
+				dS += oligoNN[s[i-k]][s[i-k+1]][1]
+				g = c.duplexDG(dH, dS, k+1) + loop
+				if g < dg {
+					dg = g
+				}
+			}
+		}
+	}
+	return dg
+}
+
+// hairpinLoopDG returns ΔG (kcal/mol) of a hairpin loop of n bases.
+func (c *OligoConditions) hairpinLoopDG(n int) float64 {
+	var g float64
+	loops := oligoHairpinLoops
+	last := loops[len(loops)-1]
+	if float64(n) >= last[0] {
+		g = last[1] + 2.44*oligoR/1000*310.15*math.Log(float64(n)/last[0])
+	} else {
+		for i := 1; i < len(loops); i++ {
+			if float64(n) <= loops[i][0] {
+				g = loops[i-1][1] + (loops[i][1]-loops[i-1][1])*
+					(float64(n)-loops[i-1][0])/(loops[i][0]-loops[i-1][0])
+				break
+			}
+		}
+	}
+	// loops are entropic
+	return g * c.kelvin() / 310.15
+}
@@ -0,0 +1,506 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shenwei356/bio/seq"
+	"github.com/shenwei356/bio/seqio/fastx"
+	"github.com/shenwei356/util/byteutil"
+	"github.com/shenwei356/xopen"
+	"github.com/spf13/cobra"
+)
+
+// orfCmd represents the orf command
+var orfCmd = &cobra.Command{
+	GroupID: "basic",
+
+	Use:   "orf",
+	Short: "find open reading frames (ORFs) in DNA/RNA sequences",
+	Long: `find open reading frames (ORFs) in DNA/RNA sequences
+
+Attention:
+
+  1. ORFs are searched in the given frames using the translate table
+     of -T/--transl-table, type 'seqkit translate --help' for details.
+  2. Start codons (-s/--start-codon):
+       atg  only ATG (AUG).
+       alt  all initiation codons of the translate table, e.g., ATG, GTG
+            and TTG for table 11.
+       any  any codon, i.e., ORFs are regions between two stop codons.
+  3. By default, only the longest ORF of each stop codon is reported,
+     use -n/--nested to report all ORFs starting from every in-frame start
+     codon upstream of the stop codon.
+  4. The length of an ORF includes the stop codon, and ORFs running off
+     the sequence end without a stop codon are only reported with
+     -p/--partial (ignored for circular sequences).
+  5. Coordinates are 1-based and the begin position is always smaller than
+     the end position, no matter which strand the ORF is on.
+  6. When using flag -c/--circular, ORFs crossing the sequence end are
+     also reported, where the end position would be greater than the
+     sequence length, just like 'seqkit locate -c'.
+
+Output formats:
+
+  default  FASTA of nucleotide sequences.
+  -a       FASTA of amino acid sequences, the stop codon is not included.
+  --bed    BED6, with ORF ID (<seqID>_ORF<N>) as the name.
+  --gtf    GTF, with ORF ID (<seqID>_ORF<N>) as gene_id.
+
+  The sequence ID of FASTA records is the same as 'seqkit translate -s -F':
+    <seqID>_frame=<frame>_begin=<begin>_end=<end>
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := getConfigs(cmd)
+		alphabet := config.Alphabet
+		idRegexp := config.IDRegexp
+		lineWidth := config.LineWidth
+		outFile := config.OutFile
+		seq.AlphabetGuessSeqLengthThreshold = config.AlphabetGuessSeqLength
+		seq.ValidateSeq = false
+		runtime.GOMAXPROCS(config.Threads)
+
+		translTable := getFlagPositiveInt(cmd, "transl-table")
+		table, ok := seq.CodonTables[translTable]
+		if !ok {
+			checkError(fmt.Errorf("invalid translate table: %d", translTable))
+		}
+		frames := parseFrames(getFlagStringSlice(cmd, "frame"))
+
+		startCodon := strings.ToLower(getFlagString(cmd, "start-codon"))
+		var startCodons map[string]struct{}
+		switch startCodon {
+		case "atg":
+			startCodons = map[string]struct{}{"ATG": {}}
+		case "alt":
+			startCodons = table.InitCodons
+		case "any":
+		default:
+			checkError(fmt.Errorf("invalid value of flag -s/--start-codon: %s. available: atg, alt, any", startCodon))
+		}
+
+		minLen := getFlagNonNegativeInt(cmd, "min-len")
+		maxLen := getFlagNonNegativeInt(cmd, "max-len")
+		nested := getFlagBool(cmd, "nested")
+		partial := getFlagBool(cmd, "partial")
+		circular := getFlagBool(cmd, "circular")
+		outProtein := getFlagBool(cmd, "protein")
+		outFmtBED := getFlagBool(cmd, "bed")
+		outFmtGTF := getFlagBool(cmd, "gtf")
+		if outFmtBED && outFmtGTF {
+			checkError(fmt.Errorf("flag --bed and --gtf are not compatible"))
+		}
+		if (outFmtBED || outFmtGTF) && outProtein {
+			checkError(fmt.Errorf("flag -a/--protein is not compatible with --bed or --gtf"))
+		}
+		if circular && partial {
+			log.Warningf("flag -p/--partial is ignored for circular sequences")
+			partial = false
+		}
+
+		finder := &ORFFinder{
+			Table:       table,
+			StartCodons: startCodons,
+			Frames:      frames,
+			MinLen:      minLen,
+			MaxLen:      maxLen,
+			Nested:      nested,
+			Partial:     partial,
+			Circular:    circular,
+		}
+
+		files := getFileListFromArgsAndFile(cmd, args, true, "infile-list", !config.SkipFileCheck)
+		if !config.SkipFileCheck {
+			for _, file := range files {
+				checkIfFilesAreTheSame(file, outFile, "input", "output")
+			}
+		}
+
+		outfh, err := xopen.Wopen(outFile)
+		checkError(err)
+		defer outfh.Close()
+
+		var record *fastx.Record
+		var orfs []ORF
+		var orf ORF
+		var i int
+		var sORF []byte
+		var name string
+		once := true
+		for _, file := range files {
+			fastxReader, err := fastx.NewReader(alphabet, file, idRegexp)
+			checkError(err)
+
+			for {
+				record, err = fastxReader.Read()
+				if err != nil {
+					if err == io.EOF {
+						break
+					}
+					checkError(err)
+					break
+				}
+
+				if once {
+					if !(record.Seq.Alphabet == seq.DNA || record.Seq.Alphabet == seq.DNAredundant ||
+						record.Seq.Alphabet == seq.RNA || record.Seq.Alphabet == seq.RNAredundant) {
+						checkError(fmt.Errorf(`command 'seqkit orf' only apply to DNA/RNA sequences`))
+					}
+					once = false
+				}
+
+				orfs = finder.Find(record.Seq.Seq)
+
+				for i, orf = range orfs {
+					if outFmtBED {
+						outfh.WriteString(fmt.Sprintf("%s\t%d\t%d\t%s_ORF%d\t%d\t%c\n",
+							record.ID,
+							orf.Begin-1,
+							orf.End,
+							record.ID, i+1,
+							0,
+							orf.Strand()))
+						continue
+					} else if outFmtGTF {
+						outfh.WriteString(fmt.Sprintf("%s\t%s\t%s\t%d\t%d\t%s\t%c\t%d\tgene_id \"%s_ORF%d\"; \n",
+							record.ID,
+							"SeqKit",
+							"ORF",
+							orf.Begin,
+							orf.End,
+							".",
+							orf.Strand(),
+							0,
+							record.ID, i+1))
+						continue
+					}
+
+					sORF = orf.Subseq(record.Seq)
+					if outProtein {
+						sORF, err = table.Translate(sORF, 1, false, false, true, startCodons != nil)
+						checkError(err)
+						if !orf.NoStop && len(sORF) > 0 && sORF[len(sORF)-1] == '*' {
+							sORF = sORF[:len(sORF)-1]
+						}
+					}
+
+					name = fmt.Sprintf("%s_frame=%d_begin=%d_end=%d", record.ID, orf.Frame, orf.Begin, orf.End)
+					if len(record.Desc) > 0 {
+						outfh.WriteString(fmt.Sprintf(">%s %s\n", name, record.Desc))
+					} else {
+						outfh.WriteString(fmt.Sprintf(">%s\n", name))
+					}
+					outfh.Write(byteutil.WrapByteSlice(sORF, lineWidth))
+					outfh.WriteString("\n")
+				}
+			}
+			fastxReader.Close()
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(orfCmd)
+
+	orfCmd.Flags().IntP("transl-table", "T", 1, `translate table/genetic code, type 'seqkit translate --help' for more details`)
+	orfCmd.Flags().StringSliceP("frame", "f", []string{"6"}, "frame(s) to search, available value: 1, 2, 3, -1, -2, -3, and 6 for all six frames")
+	orfCmd.Flags().StringP("start-codon", "s", "atg", `start codon: atg, alt (all initiation codons of the translate table), any (stop-to-stop)`)
+	orfCmd.Flags().IntP("min-len", "m", 75, `minimum length (nt) of ORF, stop codon included`)
+	orfCmd.Flags().IntP("max-len", "M", 0, `maximum length (nt) of ORF, stop codon included, 0 for no limit`)
+	orfCmd.Flags().BoolP("nested", "n", false, `report nested ORFs starting from all in-frame start codons, rather than only the longest one`)
+	orfCmd.Flags().BoolP("partial", "p", false, `report ORFs without a stop codon at the sequence end`)
+	orfCmd.Flags().BoolP("circular", "c", false, `circular genome. type "seqkit orf -h" for details`)
+	orfCmd.Flags().BoolP("protein", "a", false, `output amino acid sequences instead of nucleotide sequences`)
+	orfCmd.Flags().BoolP("bed", "", false, "output in BED6 format")
+	orfCmd.Flags().BoolP("gtf", "", false, "output in GTF format")
+}
+
+// parseFrames parses frames in the form of translate's -f/--frame.
+func parseFrames(_frames []string) []int {
+	frames := make([]int, 0, len(_frames))
+	for _, _frame := range _frames {
+		frame, err := strconv.Atoi(_frame)
+		if err != nil {
+			checkError(fmt.Errorf("invalid frame(s): %s. available: 1, 2, 3, -1, -2, -3, and 6 for all. multiple frames should be separated by comma", _frame))
+		}
+		if !(frame == 1 || frame == 2 || frame == 3 || frame == -1 || frame == -2 || frame == -3 || frame == 6) {
+			checkError(fmt.Errorf("invalid frame: %d. available: 1, 2, 3, -1, -2, -3, and 6 for all", frame))
+		}
+		if frame == 6 {
+			return []int{1, 2, 3, -1, -2, -3}
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// ORF is an open reading frame on a sequence.
+type ORF struct {
+	Frame  int  // 1, 2, 3, -1, -2, -3
+	Begin  int  // 1-based, Begin <= End
+	End    int  // 1-based, might be greater than the sequence length for circular sequences
+	NoStop bool // running off the sequence end without a stop codon
+}
+
+// Strand returns the strand of the ORF.
+func (orf ORF) Strand() byte {
+	if orf.Frame < 0 {
+		return '-'
+	}
+	return '+'
+}
+
+// Len returns the length of the ORF.
+func (orf ORF) Len() int {
+	return orf.End - orf.Begin + 1
+}
+
+// Subseq returns the nucleotide sequence of the ORF,
+// the reverse complement sequence is returned for ORFs on the negative strand.
+func (orf ORF) Subseq(s *seq.Seq) []byte {
+	l := len(s.Seq)
+	var sub []byte
+	if orf.End > l { // crossing the sequence end
+		sub = make([]byte, 0, orf.Len())
+		sub = append(sub, s.Seq[orf.Begin-1:]...)
+		sub = append(sub, s.Seq[:orf.End-l]...)
+	} else {
+		sub = make([]byte, orf.Len())
+		copy(sub, s.Seq[orf.Begin-1:orf.End])
+	}
+	if orf.Frame < 0 {
+		rc, _ := seq.NewSeqWithoutValidation(s.Alphabet, sub)
+		return rc.RevComInplace().Seq
+	}
+	return sub
+}
+
+// ORFFinder finds ORFs with given criteria.
+type ORFFinder struct {
+	Table       *seq.CodonTable
+	StartCodons map[string]struct{} // upper-case DNA codons, nil for any codon
+	Frames      []int
+	MinLen      int // nt, stop codon included
+	MaxLen      int // nt, stop codon included, 0 for no limit
+	Nested      bool
+	Partial     bool
+	Circular    bool
+}
+
+// Find returns ORFs of a sequence, sorted by begin and end positions.
+func (f *ORFFinder) Find(s []byte) []ORF {
+	l := len(s)
+	orfs := make([]ORF, 0, 8)
+	if l < 3 {
+		return orfs
+	}
+
+	var pos, neg bool
+	for _, frame := range f.Frames {
+		if frame > 0 {
+			pos = true
+		} else {
+			neg = true
+		}
+	}
+
+	var t []byte
+	if pos {
+		t = s
+		if f.Circular { // concat two copies of sequence
+			t = make([]byte, 0, l<<1)
+			t = append(t, s...)
+			t = append(t, s...)
+		}
+		for _, frame := range f.Frames {
+			if frame < 0 {
+				continue
+			}
+			orfs = f.findOnStrand(t, l, frame, orfs)
+		}
+	}
+	if neg {
+		rc, _ := seq.NewSeqWithoutValidation(seq.DNAredundant, s)
+		t = rc.RevCom().Seq
+		if f.Circular {
+			t = append(t, t...)
+		}
+		for _, frame := range f.Frames {
+			if frame > 0 {
+				continue
+			}
+			orfs = f.findOnStrand(t, l, frame, orfs)
+		}
+	}
+
+	if !f.Nested {
+		orfs = longestORFs(orfs, l)
+	}
+
+	sort.Slice(orfs, func(i, j int) bool {
+		if orfs[i].Begin == orfs[j].Begin {
+			if orfs[i].End == orfs[j].End {
+				return orfs[i].Frame > orfs[j].Frame
+			}
+			return orfs[i].End < orfs[j].End
+		}
+		return orfs[i].Begin < orfs[j].Begin
+	})
+	return orfs
+}
+
+// findOnStrand searches ORFs on one strand. t is the sequence (or its reverse
+// complement), which is two copies of the original one of length l for
+// circular sequences.
+func (f *ORFFinder) findOnStrand(t []byte, l int, frame int, orfs []ORF) []ORF {
+	negative := frame < 0
+	offset := frame - 1
+	if negative {
+		offset = -frame - 1
+	}
+
+	starts := make([]int, 0, 8)
+	var aa byte
+	var i, start, end, n int
+	var ok bool
+	codon := make([]byte, 3)
+
+	// a stop-to-stop ORF starts from the beginning of a linear sequence,
+	// while it must start after a stop codon for a circular one.
+	anyStart := offset
+	if f.Circular {
+		anyStart = -1
+	}
+
+	n = len(t)
+	for i = offset; i+3 <= n; i += 3 {
+		aa, _ = f.Table.Get(t[i:i+3], true)
+		if aa == '*' {
+			if f.StartCodons == nil {
+				if anyStart >= 0 && anyStart < i {
+					starts = append(starts, anyStart)
+				}
+				anyStart = i + 3
+			}
+
+			end = i + 3
+			for _, start = range starts {
+				orfs = f.appendORF(orfs, start, end, l, frame, negative, false)
+			}
+			starts = starts[:0]
+			continue
+		}
+
+		if f.StartCodons == nil || (f.Circular && i >= l) {
+			continue
+		}
+
+		codon[0], codon[1], codon[2] = upperDNABase(t[i]), upperDNABase(t[i+1]), upperDNABase(t[i+2])
+		if _, ok = f.StartCodons[string(codon)]; ok {
+			if f.Nested || len(starts) == 0 {
+				starts = append(starts, i)
+			}
+		}
+	}
+
+	if !f.Partial {
+		return orfs
+	}
+
+	// ORFs without stop codons
+	if f.StartCodons == nil && anyStart >= 0 && anyStart < i {
+		starts = append(starts, anyStart)
+	}
+	end = i
+	for _, start = range starts {
+		orfs = f.appendORF(orfs, start, end, l, frame, negative, true)
+	}
+	return orfs
+}
+
+// appendORF checks the ORF (0-based [start, end) on one strand) and
+// converts it into an ORF object with 1-based coordinates on the positive strand.
+func (f *ORFFinder) appendORF(orfs []ORF, start, end, l int, frame int, negative bool, noStop bool) []ORF {
+	if f.Circular && start >= l { // 2nd clone of original part
+		return orfs
+	}
+	n := end - start
+	if n < f.MinLen || (f.MaxLen > 0 && n > f.MaxLen) || (f.Circular && n > l) {
+		return orfs
+	}
+	if !negative {
+		return append(orfs, ORF{Frame: frame, Begin: start + 1, End: end, NoStop: noStop})
+	}
+
+	begin := l - end + 1
+	_end := l - start
+	if begin <= 0 { // crossing the sequence end
+		begin += l
+		_end += l
+	}
+	return append(orfs, ORF{Frame: frame, Begin: begin, End: _end, NoStop: noStop})
+}
+
+// longestORFs keeps the longest ORF of every stop codon.
+func longestORFs(orfs []ORF, l int) []ORF {
+	type key struct {
+		strand byte
+		stop   int
+	}
+	m := make(map[key]int, len(orfs))
+	var k key
+	var j int
+	var ok bool
+	for i, orf := range orfs {
+		if orf.Frame > 0 {
+			k = key{strand: '+', stop: orf.End % l}
+		} else {
+			k = key{strand: '-', stop: (orf.Begin - 1) % l}
+		}
+		if j, ok = m[k]; !ok || orf.Len() > orfs[j].Len() {
+			m[k] = i
+		}
+	}
+	longest := make([]ORF, 0, len(m))
+	for _, j = range m {
+		longest = append(longest, orfs[j])
+	}
+	return longest
+}
+
+// upperDNABase returns the upper-case DNA base, 'U' is converted to 'T'.
+func upperDNABase(b byte) byte {
+	switch b {
+	case 'u', 'U':
+		return 'T'
+	}
+	if b >= 'a' && b <= 'z' {
+		return b - 32
+	}
+	return b
+}
@@ -0,0 +1,923 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/biogo/hts/bam"
+	"github.com/biogo/hts/sam"
+	"github.com/shenwei356/bio/seq"
+	"github.com/shenwei356/bio/seqio/fastx"
+	"github.com/shenwei356/xopen"
+	"github.com/spf13/cobra"
+)
+
+// primerTrimCmd represents the primer-trim command
+var primerTrimCmd = &cobra.Command{
+	GroupID: "edit",
+
+	Use:   "primer-trim",
+	Short: "trim primers from amplicon sequencing reads",
+	Long: `trim primers from amplicon sequencing reads
+
+Primer schemes:
+
+  -p/--primer-file  3-column tab-delimited primer file, the same as
+                    'seqkit amplicon': amplicon name, forward primer, and
+                    reverse primer (5'-primer-3').
+  -b/--primer-bed   ARTIC-style primer BED file with 6 or 7 columns:
+                    chrom, start, end, primer name, pool, strand, [sequence].
+                    Primer names should be in the format of
+                    <amplicon>_LEFT[_suffix] or <amplicon>_RIGHT[_suffix],
+                    e.g., SARS-CoV-2_1_LEFT and SARS-CoV-2_1_LEFT_alt1.
+                    Primer sequences are read from the 7th column, or
+                    extracted from the reference genome (-r/--ref).
+
+FASTA/FASTQ reads:
+
+  1. Primers are searched at both ends of reads, i.e., a primer should start
+     within the first -W/--max-offset bases of a read, or the reverse
+     complement sequence of a primer should end within the last
+     -W/--max-offset bases. Reads can be in either orientation.
+  2. Mismatches (-m/--max-mismatch) and degenerate bases in primers are
+     allowed at the same time. The best-matched primer is chosen with
+     the fewest mismatches.
+  3. Bases of primers and those beyond them are trimmed, and the amplicon
+     ID (and the pool for BED) is appended to the sequence header, e.g.,
+       >read1 amplicon=SARS-CoV-2_1 pool=1
+  4. Reads are dropped if:
+       a) no primer is found at either end, or no primers at both ends
+          when -B/--both-ends is given,
+       b) primers at two ends come from different amplicons or the same
+          direction,
+       c) the trimmed read is shorter than -l/--min-len,
+       d) for BED, the trimmed read is longer than the region between
+          primers of the amplicon by more than -W/--max-offset bases,
+          e.g., reads with a primer at only one end and running past
+          the amplicon.
+
+BAM (--bam, requiring -b/--primer-bed):
+
+  1. Only primary alignments are kept, and each one is assigned to the
+     amplicon whose region (from the start of the left primers to the end
+     of the right primers) contains the alignment, with a tolerance of
+     -W/--max-offset bases. The amplicon with the closest ends is chosen
+     if multiple amplicons qualify.
+  2. Aligned bases overlapping with primers are soft-clipped, the position
+     is updated, and the amplicon ID is saved in the tag "am:Z", which
+     replaces the existing one.
+  3. Alignments are dropped if no amplicon is assigned, or no aligned bases
+     are left after clipping. The output BAM should be re-sorted if needed.
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := getConfigs(cmd)
+		alphabet := config.Alphabet
+		idRegexp := config.IDRegexp
+		outFile := config.OutFile
+		seq.AlphabetGuessSeqLengthThreshold = config.AlphabetGuessSeqLength
+		seq.ValidateSeq = false
+		runtime.GOMAXPROCS(config.Threads)
+
+		primerFile := getFlagString(cmd, "primer-file")
+		primerBed := getFlagString(cmd, "primer-bed")
+		refFile := getFlagString(cmd, "ref")
+		maxMismatch := getFlagNonNegativeInt(cmd, "max-mismatch")
+		maxOffset := getFlagNonNegativeInt(cmd, "max-offset")
+		bothEnds := getFlagBool(cmd, "both-ends")
+		minLen := getFlagPositiveInt(cmd, "min-len")
+		inBam := getFlagBool(cmd, "bam")
+
+		if (primerFile == "") == (primerBed == "") {
+			checkError(fmt.Errorf("one and only one of -p/--primer-file and -b/--primer-bed should be given"))
+		}
+		if inBam && primerBed == "" {
+			checkError(fmt.Errorf("flag -b/--primer-bed needed for BAM input (--bam)"))
+		}
+
+		var scheme *PrimerScheme
+		var err error
+		if primerFile != "" {
+			scheme, err = PrimerSchemeFromPrimerFile(primerFile)
+		} else {
+			scheme, err = PrimerSchemeFromBED(primerBed, refFile, !inBam)
+		}
+		checkError(err)
+		if !config.Quiet {
+			log.Infof("%d primers of %d amplicons loaded", len(scheme.Primers), len(scheme.Amplicons))
+		}
+
+		files := getFileListFromArgsAndFile(cmd, args, true, "infile-list", !config.SkipFileCheck)
+		if !config.SkipFileCheck {
+			for _, file := range files {
+				checkIfFilesAreTheSame(file, outFile, "input", "output")
+			}
+		}
+
+		if inBam {
+			if len(files) > 1 {
+				checkError(fmt.Errorf("only one BAM file is allowed"))
+			}
+			primerTrimBam(files[0], outFile, scheme, maxOffset, config.Threads, config.Quiet)
+			return
+		}
+
+		outfh, err := xopen.Wopen(outFile)
+		checkError(err)
+		defer outfh.Close()
+
+		matcher := NewPrimerMatcher(scheme.Primers, maxMismatch, maxOffset)
+
+		var record *fastx.Record
+		var m5, m3 *PrimerMatch
+		var amp *SchemeAmplicon
+		var b, e int
+		var nReads, nKept, nNoPrimer, nInvalid, nShort, nLong int
+		for _, file := range files {
+			fastxReader, err := fastx.NewReader(alphabet, file, idRegexp)
+			checkError(err)
+
+			for {
+				record, err = fastxReader.Read()
+				if err != nil {
+					if err == io.EOF {
+						break
+					}
+					checkError(err)
+					break
+				}
+				if fastxReader.IsFastq {
+					if !config.LineWidthChanged {
+						config.LineWidth = 0
+					}
+					fastx.ForcelyOutputFastq = true
+				}
+				nReads++
+
+				m5, m3 = matcher.Match(record.Seq.Seq)
+				if (m5 == nil && m3 == nil) || (bothEnds && (m5 == nil || m3 == nil)) {
+					nNoPrimer++
+					continue
+				}
+				if m5 != nil && m3 != nil &&
+					(m5.Primer.Amplicon != m3.Primer.Amplicon || m5.Primer.Dir == m3.Primer.Dir) {
+					nInvalid++
+					continue
+				}
+
+				b, e = 0, len(record.Seq.Seq)
+				if m5 != nil {
+					b = m5.End
+					amp = scheme.Amplicons[m5.Primer.Amplicon]
+				}
+				if m3 != nil {
+					e = m3.Begin
+					amp = scheme.Amplicons[m3.Primer.Amplicon]
+				}
+				if e-b < minLen {
+					nShort++
+					continue
+				}
+				if amp.InsertLen > 0 && e-b > amp.InsertLen+maxOffset { // spanning no valid amplicon
+					nLong++
+					continue
+				}
+
+				record.Seq.SubSeqInplace(b+1, e)
+				if amp.Pool != "" {
+					record.Name = []byte(fmt.Sprintf("%s amplicon=%s pool=%s", record.Name, amp.ID, amp.Pool))
+				} else {
+					record.Name = []byte(fmt.Sprintf("%s amplicon=%s", record.Name, amp.ID))
+				}
+				record.FormatToWriter(outfh, config.LineWidth)
+				nKept++
+			}
+			fastxReader.Close()
+		}
+
+		if !config.Quiet {
+			log.Infof("%d reads processed, %d trimmed and kept, %d dropped (no primers: %d, invalid primer combination: %d, too short: %d, too long: %d)",
+				nReads, nKept, nReads-nKept, nNoPrimer, nInvalid, nShort, nLong)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(primerTrimCmd)
+
+	primerTrimCmd.Flags().StringP("primer-file", "p", "", "3-column tabular primer file, with first column as amplicon name, the same as 'seqkit amplicon'")
+	primerTrimCmd.Flags().StringP("primer-bed", "b", "", "ARTIC-style primer BED file with pool information")
+	primerTrimCmd.Flags().StringP("ref", "r", "", "reference genome (FASTA) for extracting primer sequences when no sequence column in the BED file")
+	primerTrimCmd.Flags().IntP("max-mismatch", "m", 1, "max mismatches when matching primers, degenerate bases allowed")
+	primerTrimCmd.Flags().IntP("max-offset", "W", 5, "max distance between primers and read ends, or tolerance of alignment ends for BAM")
+	primerTrimCmd.Flags().BoolP("both-ends", "B", false, "require primers at both ends, e.g., for long reads covering whole amplicons")
+	primerTrimCmd.Flags().IntP("min-len", "l", 1, "minimum length of trimmed reads")
+	primerTrimCmd.Flags().BoolP("bam", "", false, "input is a BAM file, aligned bases overlapping with primers are soft-clipped")
+}
+
+// SchemePrimer is a primer in a primer scheme.
+type SchemePrimer struct {
+	Name     string
+	Amplicon string
+	Dir      byte // F or R
+	Seq      []byte
+
+	// only for BED
+	Chrom      string
+	Start, End int // 0-based, end exclusive
+}
+
+// SchemeAmplicon is an amplicon in a primer scheme.
+type SchemeAmplicon struct {
+	ID   string
+	Pool string
+
+	// only for BED
+	Chrom                 string
+	LeftStart, LeftEnd    int // 0-based, end exclusive
+	RightStart, RightEnd  int // 0-based, end exclusive
+	InsertLen             int // max length of the region between left and right primers
+	hasLeft, hasRight     bool
+	firstIndex, lastIndex int
+}
+
+// PrimerScheme is a set of primers.
+type PrimerScheme struct {
+	Primers   []*SchemePrimer
+	Amplicons map[string]*SchemeAmplicon
+	IDs       []string // amplicon IDs in input order
+}
+
+// PrimerSchemeFromPrimerFile reads primers from a primer file of amplicon.
+func PrimerSchemeFromPrimerFile(file string) (*PrimerScheme, error) {
+	list, err := loadPrimers(file)
+	if err != nil {
+		return nil, err
+	}
+	scheme := &PrimerScheme{Amplicons: make(map[string]*SchemeAmplicon, len(list))}
+	for _, items := range list {
+		if items[2] == "" {
+			return nil, fmt.Errorf("both forward and reverse primers needed: %s", items[0])
+		}
+		if _, ok := scheme.Amplicons[items[0]]; ok {
+			return nil, fmt.Errorf("duplicated amplicon name: %s", items[0])
+		}
+		for k, dir := range []byte{'F', 'R'} {
+			s := bytes.ToUpper([]byte(items[k+1]))
+			if seq.DNAredundant.IsValid(s) != nil {
+				return nil, fmt.Errorf("invalid primer sequence: %s", s)
+			}
+			scheme.Primers = append(scheme.Primers, &SchemePrimer{
+				Name:     fmt.Sprintf("%s_%c", items[0], dir),
+				Amplicon: items[0],
+				Dir:      dir,
+				Seq:      s,
+			})
+		}
+		scheme.Amplicons[items[0]] = &SchemeAmplicon{ID: items[0]}
+		scheme.IDs = append(scheme.IDs, items[0])
+	}
+	if len(scheme.Primers) == 0 {
+		return nil, fmt.Errorf("no primers found in file: %s", file)
+	}
+	return scheme, nil
+}
+
+var rePrimerBedName = regexp.MustCompile(`^(.+)_(LEFT|RIGHT)(_.+)?$`)
+
+// PrimerSchemeFromBED reads primers from an ARTIC-style primer BED file.
+// Primer sequences are read from the 7th column or extracted from the reference.
+func PrimerSchemeFromBED(file string, refFile string, needSeq bool) (*PrimerScheme, error) {
+	fh, err := xopen.Ropen(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	var refs map[string]*fastx.Record
+
+	scheme := &PrimerScheme{Amplicons: make(map[string]*SchemeAmplicon, 128)}
+	scanner := bufio.NewScanner(fh)
+	var line string
+	var items, m []string
+	var start, end int
+	var p *SchemePrimer
+	var amp *SchemeAmplicon
+	var ok bool
+	for scanner.Scan() {
+		line = strings.TrimRight(scanner.Text(), "\r\n")
+		if line == "" || line[0] == '#' || strings.HasPrefix(line, "track") || strings.HasPrefix(line, "browser") {
+			continue
+		}
+		items = strings.Split(line, "\t")
+		if len(items) < 6 {
+			return nil, fmt.Errorf("at least 6 columns needed in primer BED file: %s", line)
+		}
+		start, err = strconv.Atoi(items[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid start position: %s", line)
+		}
+		end, err = strconv.Atoi(items[2])
+		if err != nil || end <= start {
+			return nil, fmt.Errorf("invalid end position: %s", line)
+		}
+		m = rePrimerBedName.FindStringSubmatch(items[3])
+		if m == nil {
+			return nil, fmt.Errorf("invalid primer name, <amplicon>_LEFT[_suffix] or <amplicon>_RIGHT[_suffix] expected: %s", items[3])
+		}
+
+		p = &SchemePrimer{Name: items[3], Amplicon: m[1], Chrom: items[0], Start: start, End: end, Dir: 'F'}
+		if m[2] == "RIGHT" {
+			p.Dir = 'R'
+		}
+
+		if needSeq {
+			if len(items) >= 7 && items[6] != "" {
+				p.Seq = bytes.ToUpper([]byte(items[6]))
+			} else {
+				if refFile == "" {
+					return nil, fmt.Errorf("no primer sequences in the BED file, please give the reference genome via -r/--ref")
+				}
+				if refs == nil {
+					refs, err = fastx.GetSeqsMap(refFile, seq.DNAredundant, 1, 10, "")
+					if err != nil {
+						return nil, err
+					}
+				}
+				var r *fastx.Record
+				if r, ok = refs[p.Chrom]; !ok {
+					return nil, fmt.Errorf("sequence %s not found in the reference: %s", p.Chrom, refFile)
+				}
+				if end > len(r.Seq.Seq) {
+					return nil, fmt.Errorf("primer %s out of the range of %s", p.Name, p.Chrom)
+				}
+				p.Seq = bytes.ToUpper(r.Seq.SubSeq(start+1, end).Seq)
+				if p.Dir == 'R' {
+					s, _ := seq.NewSeqWithoutValidation(seq.DNAredundant, p.Seq)
+					p.Seq = s.RevCom().Seq
+				}
+			}
+			if seq.DNAredundant.IsValid(p.Seq) != nil {
+				return nil, fmt.Errorf("invalid primer sequence of %s: %s", p.Name, p.Seq)
+			}
+		}
+
+		if amp, ok = scheme.Amplicons[p.Amplicon]; !ok {
+			amp = &SchemeAmplicon{ID: p.Amplicon, Pool: items[4], Chrom: p.Chrom}
+			scheme.Amplicons[p.Amplicon] = amp
+			scheme.IDs = append(scheme.IDs, p.Amplicon)
+		} else if amp.Chrom != p.Chrom {
+			return nil, fmt.Errorf("primers of amplicon %s on different sequences: %s, %s", amp.ID, amp.Chrom, p.Chrom)
+		}
+		if p.Dir == 'F' {
+			if !amp.hasLeft || start < amp.LeftStart {
+				amp.LeftStart = start
+			}
+			if !amp.hasLeft || end > amp.LeftEnd {
+				amp.LeftEnd = end
+			}
+			amp.hasLeft = true
+		} else {
+			if !amp.hasRight || start < amp.RightStart {
+				amp.RightStart = start
+			}
+			if !amp.hasRight || end > amp.RightEnd {
+				amp.RightEnd = end
+			}
+			amp.hasRight = true
+		}
+
+		scheme.Primers = append(scheme.Primers, p)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range scheme.IDs {
+		amp = scheme.Amplicons[id]
+		if !amp.hasLeft || !amp.hasRight {
+			return nil, fmt.Errorf("both left and right primers needed for amplicon: %s", id)
+		}
+		if amp.LeftStart >= amp.RightEnd {
+			return nil, fmt.Errorf("left primers should be upstream of right primers: %s", id)
+		}
+	}
+
+	// the max insert length is from the left primer ending first
+	// to the right primer starting last.
+	leftEnds := make(map[string]int, len(scheme.IDs))
+	rightStarts := make(map[string]int, len(scheme.IDs))
+	for _, p = range scheme.Primers {
+		if p.Dir == 'F' {
+			if end, ok = leftEnds[p.Amplicon]; !ok || p.End < end {
+				leftEnds[p.Amplicon] = p.End
+			}
+		} else if start, ok = rightStarts[p.Amplicon]; !ok || p.Start > start {
+			rightStarts[p.Amplicon] = p.Start
+		}
+	}
+	for _, id := range scheme.IDs {
+		amp = scheme.Amplicons[id]
+		amp.InsertLen = max(rightStarts[id]-leftEnds[id], 0)
+	}
+	if len(scheme.Primers) == 0 {
+		return nil, fmt.Errorf("no primers found in file: %s", file)
+	}
+	return scheme, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// PrimerMatch is a match of a primer in a read.
+type PrimerMatch struct {
+	Primer     *SchemePrimer
+	Begin, End int // 0-based, end exclusive
+	Mismatches int
+}
+
+type primerKmerHit struct {
+	primer int
+	pos    int
+}
+
+// PrimerMatcher finds primers at the ends of reads, using a k-mer index
+// of primers to find candidate locations, which are then verified with
+// mismatches and degenerate bases allowed.
+type PrimerMatcher struct {
+	Primers     []*SchemePrimer
+	MaxMismatch int
+	MaxOffset   int
+
+	k       int
+	rcs     [][]byte
+	maxLen  int
+	idx5    map[uint64][]primerKmerHit // k-mers of primers
+	idx3    map[uint64][]primerKmerHit // k-mers of reverse complement sequences of primers
+	noIdx   []int                      // primers not indexed
+	visited map[[2]int]struct{}
+	buf     []byte
+}
+
+// primerBaseMatch[p][b] is true if base b matches the degenerate base p.
+var primerBaseMatch [256][256]bool
+
+func init() {
+	for code, bases := range oligoIUPAC {
+		for i := 0; i < len(bases); i++ {
+			primerBaseMatch[code][bases[i]] = true
+		}
+	}
+}
+
+var primerBase2code = map[byte]uint64{'A': 0, 'C': 1, 'G': 2, 'T': 3}
+
+// NewPrimerMatcher creates a PrimerMatcher.
+func NewPrimerMatcher(primers []*SchemePrimer, maxMismatch, maxOffset int) *PrimerMatcher {
+	m := &PrimerMatcher{
+		Primers:     primers,
+		MaxMismatch: maxMismatch,
+		MaxOffset:   maxOffset,
+		rcs:         make([][]byte, len(primers)),
+		idx5:        make(map[uint64][]primerKmerHit, 1024),
+		idx3:        make(map[uint64][]primerKmerHit, 1024),
+		visited:     make(map[[2]int]struct{}, 64),
+	}
+
+	// by the pigeonhole principle, a primer with n mismatches must have
+	// an exact k-mer match if len(primer) >= (n+1)*k
+	minLen := len(primers[0].Seq)
+	for i, p := range primers {
+		s, _ := seq.NewSeqWithoutValidation(seq.DNAredundant, p.Seq)
+		m.rcs[i] = s.RevCom().Seq
+		if len(p.Seq) < minLen {
+			minLen = len(p.Seq)
+		}
+		if len(p.Seq) > m.maxLen {
+			m.maxLen = len(p.Seq)
+		}
+	}
+	m.k = minLen / (maxMismatch + 1)
+	if m.k > 12 {
+		m.k = 12
+	}
+	if m.k < 4 { // too short to index
+		for i := range primers {
+			m.noIdx = append(m.noIdx, i)
+		}
+		return m
+	}
+
+	for i, p := range primers {
+		if !primerIndexKmers(m.idx5, i, p.Seq, m.k) || !primerIndexKmers(m.idx3, i, m.rcs[i], m.k) {
+			m.noIdx = append(m.noIdx, i)
+		}
+	}
+	return m
+}
+
+// primerIndexKmers adds all k-mers of a primer into the index,
+// degenerate bases are expanded. It returns false if there are too many
+// expanded k-mers.
+func primerIndexKmers(idx map[uint64][]primerKmerHit, i int, s []byte, k int) bool {
+	const maxExpand = 256
+	codes := make([]uint64, 0, maxExpand)
+	var tmp []uint64
+	var bases string
+	for pos := 0; pos+k <= len(s); pos++ {
+		codes = append(codes[:0], 0)
+		for _, b := range s[pos : pos+k] {
+			bases = oligoIUPAC[b]
+			if len(codes)*len(bases) > maxExpand {
+				return false
+			}
+			tmp = tmp[:0]
+			for _, c := range codes {
+				for j := 0; j < len(bases); j++ {
+					tmp = append(tmp, c<<2|primerBase2code[bases[j]])
+				}
+			}
+			codes = append(codes[:0], tmp...)
+		}
+		for _, c := range codes {
+			idx[c] = append(idx[c], primerKmerHit{primer: i, pos: pos})
+		}
+	}
+	return true
+}
+
+// primerMismatches counts mismatches between a primer and a sequence of
+// the same length, it stops when the number exceeds max.
+func primerMismatches(primer, s []byte, max int) int {
+	var n int
+	for i, p := range primer {
+		if !primerBaseMatch[p][s[i]] {
+			n++
+			if n > max {
+				return n
+			}
+		}
+	}
+	return n
+}
+
+// Match returns the best-matched primers at the 5' and 3' ends of a read.
+func (m *PrimerMatcher) Match(read []byte) (*PrimerMatch, *PrimerMatch) {
+	m.buf = append(m.buf[:0], read...)
+	s := bytes.ToUpper(m.buf)
+	return m.match(s, false), m.match(s, true)
+}
+
+func (m *PrimerMatcher) better(best *PrimerMatch, p *SchemePrimer, begin, mis int, end3 bool) bool {
+	if best == nil || mis < best.Mismatches {
+		return true
+	}
+	if mis > best.Mismatches {
+		return false
+	}
+	if len(p.Seq) != len(best.Primer.Seq) {
+		return len(p.Seq) > len(best.Primer.Seq)
+	}
+	if end3 {
+		return begin > best.Begin
+	}
+	return begin < best.Begin
+}
+
+func (m *PrimerMatcher) match(s []byte, end3 bool) *PrimerMatch {
+	l := len(s)
+	var best *PrimerMatch
+
+	check := func(i, begin int) {
+		var q []byte
+		if end3 {
+			q = m.rcs[i]
+		} else {
+			q = m.Primers[i].Seq
+		}
+		end := begin + len(q)
+		if begin < 0 || end > l {
+			return
+		}
+		if end3 {
+			if l-end > m.MaxOffset {
+				return
+			}
+		} else if begin > m.MaxOffset {
+			return
+		}
+		mis := primerMismatches(q, s[begin:end], m.MaxMismatch)
+		if mis > m.MaxMismatch {
+			return
+		}
+		if m.better(best, m.Primers[i], begin, mis, end3) {
+			best = &PrimerMatch{Primer: m.Primers[i], Begin: begin, End: end, Mismatches: mis}
+		}
+	}
+
+	// window to search
+	w := m.maxLen + m.MaxOffset
+	var wb, we int
+	if end3 {
+		wb, we = l-w, l
+		if wb < 0 {
+			wb = 0
+		}
+	} else {
+		wb, we = 0, w
+		if we > l {
+			we = l
+		}
+	}
+
+	// unindexed primers
+	for _, i := range m.noIdx {
+		for offset := 0; offset <= m.MaxOffset; offset++ {
+			if end3 {
+				check(i, l-offset-len(m.rcs[i]))
+			} else {
+				check(i, offset)
+			}
+		}
+	}
+
+	if m.k < 4 {
+		return best
+	}
+
+	idx := m.idx5
+	if end3 {
+		idx = m.idx3
+	}
+	clear(m.visited)
+	var code, c uint64
+	var n, begin int
+	var ok bool
+	mask := uint64(1)<<(uint(m.k)*2) - 1
+	for pos := wb; pos < we; pos++ {
+		if c, ok = primerBase2code[s[pos]]; !ok {
+			n = 0
+			code = 0
+			continue
+		}
+		code = (code<<2 | c) & mask
+		n++
+		if n < m.k {
+			continue
+		}
+		for _, hit := range idx[code] {
+			begin = pos - m.k + 1 - hit.pos
+			if _, ok = m.visited[[2]int{hit.primer, begin}]; ok {
+				continue
+			}
+			m.visited[[2]int{hit.primer, begin}] = struct{}{}
+			check(hit.primer, begin)
+		}
+	}
+	return best
+}
+
+// -----------------------------------------------------------------------------
+
+func primerTrimBam(file string, outFile string, scheme *PrimerScheme, tolerance int, threads int, quiet bool) {
+	bamReader, fhBam := NewBamReader(file, threads)
+	if fhBam != os.Stdin {
+		defer fhBam.Close()
+	}
+	header := bamReader.Header()
+
+	outw := os.Stdout
+	if outFile != "-" {
+		fh, err := os.Create(outFile)
+		checkError(err)
+		outw = fh
+	}
+	defer outw.Close()
+	outfh := bufio.NewWriter(outw)
+	defer outfh.Flush()
+	bamWriter, err := bam.NewWriter(outfh, header, threads)
+	checkError(err)
+	defer bamWriter.Close()
+
+	// amplicons of each chromosome, sorted by start positions
+	amplicons := make(map[string][]*SchemeAmplicon, 8)
+	maxAmpLen := 0
+	for _, id := range scheme.IDs {
+		amp := scheme.Amplicons[id]
+		amplicons[amp.Chrom] = append(amplicons[amp.Chrom], amp)
+		if amp.RightEnd-amp.LeftStart > maxAmpLen {
+			maxAmpLen = amp.RightEnd - amp.LeftStart
+		}
+	}
+	for _, amps := range amplicons {
+		sort.Slice(amps, func(i, j int) bool { return amps[i].LeftStart < amps[j].LeftStart })
+	}
+
+	tag := sam.NewTag("am")
+	var nReads, nKept, nOther, nNoAmplicon, nEmpty int
+	var amps []*SchemeAmplicon
+	var amp, best *SchemeAmplicon
+	var start, end, score, bestScore, i int
+	var cigar sam.Cigar
+	var pos int
+	var ok, replaced bool
+	var aux sam.Aux
+	for {
+		record, err := bamReader.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			checkError(err)
+		}
+		nReads++
+
+		if record.Flags&(sam.Unmapped|sam.Secondary|sam.Supplementary) != 0 || record.Ref == nil {
+			nOther++
+			continue
+		}
+
+		start, end = record.Start(), record.End()
+		best = nil
+		amps = amplicons[record.Ref.Name()]
+		i = sort.Search(len(amps), func(i int) bool { return amps[i].LeftStart >= start-tolerance-maxAmpLen })
+		for ; i < len(amps); i++ {
+			amp = amps[i]
+			if amp.LeftStart > start+tolerance {
+				break
+			}
+			if start < amp.LeftStart-tolerance || end > amp.RightEnd+tolerance {
+				continue
+			}
+			score = min(abs(start-amp.LeftStart), abs(end-amp.RightEnd))
+			if best == nil || score < bestScore {
+				best, bestScore = amp, score
+			}
+		}
+		if best == nil {
+			nNoAmplicon++
+			continue
+		}
+
+		cigar, pos, ok = softClipCigar(record.Cigar, record.Pos, best.LeftEnd, best.RightStart)
+		if !ok {
+			nEmpty++
+			continue
+		}
+		record.Cigar, record.Pos = cigar, pos
+
+		aux, err = sam.NewAux(tag, best.ID)
+		checkError(err)
+		replaced = false
+		for j, f := range record.AuxFields { // the tag might exist in a BAM trimmed before
+			if f.Tag() == tag {
+				record.AuxFields[j] = aux
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			record.AuxFields = append(record.AuxFields, aux)
+		}
+
+		checkError(bamWriter.Write(record))
+		nKept++
+	}
+
+	if !quiet {
+		log.Infof("%d alignments processed, %d trimmed and kept, %d dropped (unmapped/secondary/supplementary: %d, no amplicon: %d, no bases left: %d)",
+			nReads, nKept, nReads-nKept, nOther, nNoAmplicon, nEmpty)
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// softClipCigar soft-clips aligned bases on reference positions < leftEnd
+// or >= rightStart, and returns the new CIGAR and position.
+// It returns false if no aligned bases are left.
+func softClipCigar(cigar sam.Cigar, pos int, leftEnd, rightStart int) (sam.Cigar, int, bool) {
+	// expand the CIGAR
+	n := 0
+	for _, op := range cigar {
+		n += op.Len()
+	}
+	ops := make([]sam.CigarOpType, 0, n)
+	refs := make([]int, 0, n) // reference position of each operation, -1 for those not consuming reference
+	ref := pos
+	for _, op := range cigar {
+		t := op.Type()
+		c := t.Consumes()
+		for j := 0; j < op.Len(); j++ {
+			ops = append(ops, t)
+			if c.Reference > 0 {
+				refs = append(refs, ref)
+				ref++
+			} else {
+				refs = append(refs, -1)
+			}
+		}
+	}
+
+	const removed = sam.CigarOpType(255)
+	isAligned := func(t sam.CigarOpType) bool {
+		return t == sam.CigarMatch || t == sam.CigarEqual || t == sam.CigarMismatch
+	}
+	// clip one operation: bases consuming query become soft-clipped, others are removed
+	clip := func(i int) {
+		switch ops[i] {
+		case sam.CigarHardClipped, sam.CigarSoftClipped:
+		case sam.CigarMatch, sam.CigarEqual, sam.CigarMismatch, sam.CigarInsertion:
+			ops[i] = sam.CigarSoftClipped
+		default:
+			ops[i] = removed
+		}
+	}
+
+	// left
+	var i int
+	for i = 0; i < len(ops); i++ {
+		if refs[i] >= 0 && refs[i] >= leftEnd {
+			break
+		}
+		clip(i)
+	}
+	for ; i < len(ops) && !isAligned(ops[i]); i++ { // no leading insertions or deletions
+		clip(i)
+	}
+	// right
+	for i = len(ops) - 1; i >= 0; i-- {
+		if ops[i] == sam.CigarHardClipped || ops[i] == sam.CigarSoftClipped || ops[i] == removed {
+			continue
+		}
+		if refs[i] >= 0 && refs[i] < rightStart {
+			break
+		}
+		clip(i)
+	}
+	for ; i >= 0 && !isAligned(ops[i]) && ops[i] != sam.CigarSoftClipped; i-- {
+		clip(i)
+	}
+
+	// new position and CIGAR
+	newPos := -1
+	for i = 0; i < len(ops); i++ {
+		if isAligned(ops[i]) {
+			newPos = refs[i]
+			break
+		}
+	}
+	if newPos < 0 {
+		return nil, pos, false
+	}
+
+	newCigar := make(sam.Cigar, 0, len(cigar)+2)
+	var last sam.CigarOpType = removed
+	var count int
+	for _, t := range ops {
+		if t == removed {
+			continue
+		}
+		if t != last && count > 0 {
+			newCigar = append(newCigar, sam.NewCigarOp(last, count))
+			count = 0
+		}
+		last = t
+		count++
+	}
+	if count > 0 {
+		newCigar = append(newCigar, sam.NewCigarOp(last, count))
+	}
+	return newCigar, newPos, true
+}
@@ -0,0 +1,299 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"math"
+)
+
+// Physicochemical properties of proteins, the methods and parameters are
+// the same as those of ExPASy ProtParam (https://web.expasy.org/protparam/)
+// and Biopython (Bio.SeqUtils.ProtParam).
+
+// residue masses (average and monoisotopic), i.e., masses of amino acids
+// minus water.
+var aaMassAverage, aaMassMonoisotopic [256]float64
+
+const waterMassAverage = 18.01524
+const waterMassMonoisotopic = 18.01056
+
+// hydropathy values of Kyte & Doolittle (1982).
+var aaKyteDoolittle [256]float64
+
+// isStandardAA marks the 20 standard amino acids, and also U and O.
+var isStandardAA [256]bool
+
+// is20StandardAA marks the 20 standard amino acids, without U and O,
+// which have no hydropathy values.
+var is20StandardAA [256]bool
+
+// pKa values of Bjellqvist et al. (1993), used for computing pI and charge.
+var pKaPositive = map[byte]float64{'K': 10.0, 'R': 12.0, 'H': 5.98}
+var pKaNegative = map[byte]float64{'D': 4.05, 'E': 4.45, 'C': 9.0, 'Y': 10.0}
+var pKaNTerm = map[byte]float64{'A': 7.59, 'M': 7.0, 'S': 6.93, 'P': 8.36, 'T': 6.82, 'V': 7.44, 'E': 7.7}
+var pKaCTerm = map[byte]float64{'D': 4.55, 'E': 4.75}
+
+const pKaNTermDefault = 7.5
+const pKaCTermDefault = 3.55
+
+// dipeptide instability weight values (DIWV) of Guruprasad et al. (1990).
+var aaDIWV [256][256]float64
+
+func init() {
+	masses := map[byte][2]float64{
+		'A': {71.0788, 71.03711},
+		'R': {156.1875, 156.10111},
+		'N': {114.1038, 114.04293},
+		'D': {115.0886, 115.02694},
+		'C': {103.1388, 103.00919},
+		'E': {129.1155, 129.04259},
+		'Q': {128.1307, 128.05858},
+		'G': {57.0519, 57.02146},
+		'H': {137.1411, 137.05891},
+		'I': {113.1594, 113.08406},
+		'L': {113.1594, 113.08406},
+		'K': {128.1741, 128.09496},
+		'M': {131.1926, 131.04049},
+		'F': {147.1766, 147.06841},
+		'P': {97.1167, 97.05276},
+		'S': {87.0782, 87.03203},
+		'T': {101.1051, 101.04768},
+		'W': {186.2132, 186.07931},
+		'Y': {163.1760, 163.06333},
+		'V': {99.1326, 99.06841},
+		'U': {150.0388, 150.95364},
+		'O': {237.3018, 237.14773},
+	}
+	for aa, m := range masses {
+		aaMassAverage[aa], aaMassMonoisotopic[aa] = m[0], m[1]
+		aaMassAverage[aa+32], aaMassMonoisotopic[aa+32] = m[0], m[1]
+		isStandardAA[aa], isStandardAA[aa+32] = true, true
+	}
+	// ambiguous residues: average of possible ones
+	for _, amb := range [][3]byte{{'B', 'D', 'N'}, {'Z', 'E', 'Q'}, {'J', 'I', 'L'}} {
+		aaMassAverage[amb[0]] = (aaMassAverage[amb[1]] + aaMassAverage[amb[2]]) / 2
+		aaMassMonoisotopic[amb[0]] = (aaMassMonoisotopic[amb[1]] + aaMassMonoisotopic[amb[2]]) / 2
+		aaMassAverage[amb[0]+32] = aaMassAverage[amb[0]]
+		aaMassMonoisotopic[amb[0]+32] = aaMassMonoisotopic[amb[0]]
+	}
+
+	kd := map[byte]float64{
+		'A': 1.8, 'R': -4.5, 'N': -3.5, 'D': -3.5, 'C': 2.5,
+		'Q': -3.5, 'E': -3.5, 'G': -0.4, 'H': -3.2, 'I': 4.5,
+		'L': 3.8, 'K': -3.9, 'M': 1.9, 'F': 2.8, 'P': -1.6,
+		'S': -0.8, 'T': -0.7, 'W': -0.9, 'Y': -1.3, 'V': 4.2,
+	}
+	for aa, v := range kd {
+		aaKyteDoolittle[aa], aaKyteDoolittle[aa+32] = v, v
+		is20StandardAA[aa], is20StandardAA[aa+32] = true, true
+	}
+
+	diwv := map[byte]map[byte]float64{
+		'A': {'A': 1.0, 'C': 44.94, 'E': 1.0, 'D': -7.49, 'G': 1.0, 'F': 1.0, 'I': 1.0, 'H': -7.49, 'K': 1.0, 'M': 1.0, 'L': 1.0, 'N': 1.0, 'Q': 1.0, 'P': 20.26, 'S': 1.0, 'R': 1.0, 'T': 1.0, 'W': 1.0, 'V': 1.0, 'Y': 1.0},
+		'C': {'A': 1.0, 'C': 1.0, 'E': 1.0, 'D': 20.26, 'G': 1.0, 'F': 1.0, 'I': 1.0, 'H': 33.60, 'K': 1.0, 'M': 33.60, 'L': 20.26, 'N': 1.0, 'Q': -6.54, 'P': 20.26, 'S': 1.0, 'R': 1.0, 'T': 33.60, 'W': 24.68, 'V': -6.54, 'Y': 1.0},
+		'E': {'A': 1.0, 'C': 44.94, 'E': 33.60, 'D': 20.26, 'G': 1.0, 'F': 1.0, 'I': 20.26, 'H': -6.54, 'K': 1.0, 'M': 1.0, 'L': 1.0, 'N': 1.0, 'Q': 20.26, 'P': 20.26, 'S': 20.26, 'R': 1.0, 'T': 1.0, 'W': -14.03, 'V': 1.0, 'Y': 1.0},
+		'D': {'A': 1.0, 'C': 1.0, 'E': 1.0, 'D': 1.0, 'G': 1.0, 'F': -6.54, 'I': 1.0, 'H': 1.0, 'K': -7.49, 'M': 1.0, 'L': 1.0, 'N': 1.0, 'Q': 1.0, 'P': 1.0, 'S': 20.26, 'R': -6.54, 'T': -14.03, 'W': 1.0, 'V': 1.0, 'Y': 1.0},
+		'G': {'A': -7.49, 'C': 1.0, 'E': -6.54, 'D': 1.0, 'G': 13.34, 'F': 1.0, 'I': -7.49, 'H': 1.0, 'K': -7.49, 'M': 1.0, 'L': 1.0, 'N': -7.49, 'Q': 1.0, 'P': 1.0, 'S': 1.0, 'R': 1.0, 'T': -7.49, 'W': 13.34, 'V': 1.0, 'Y': -7.49},
+		'F': {'A': 1.0, 'C': 1.0, 'E': 1.0, 'D': 13.34, 'G': 1.0, 'F': 1.0, 'I': 1.0, 'H': 1.0, 'K': -14.03, 'M': 1.0, 'L': 1.0, 'N': 1.0, 'Q': 1.0, 'P': 20.26, 'S': 1.0, 'R': 1.0, 'T': 1.0, 'W': 1.0, 'V': 1.0, 'Y': 33.601},
+		'I': {'A': 1.0, 'C': 1.0, 'E': 44.94, 'D': 1.0, 'G': 1.0, 'F': 1.0, 'I': 1.0, 'H': 13.34, 'K': -7.49, 'M': 1.0, 'L': 20.26, 'N': 1.0, 'Q': 1.0, 'P': -1.88, 'S': 1.0, 'R': 1.0, 'T': 1.0, 'W': 1.0, 'V': -7.49, 'Y': 1.0},
+		'H': {'A': 1.0, 'C': 1.0, 'E': 1.0, 'D': 1.0, 'G': -9.37, 'F': -9.37, 'I': 44.94, 'H': 1.0, 'K': 24.68, 'M': 1.0, 'L': 1.0, 'N': 24.68, 'Q': 1.0, 'P': -1.88, 'S': 1.0, 'R': 1.0, 'T': -6.54, 'W': -1.88, 'V': 1.0, 'Y': 44.94},
+		'K': {'A': 1.0, 'C': 1.0, 'E': 1.0, 'D': 1.0, 'G': -7.49, 'F': 1.0, 'I': -7.49, 'H': 1.0, 'K': 1.0, 'M': 33.60, 'L': -7.49, 'N': 1.0, 'Q': 24.64, 'P': -6.54, 'S': 1.0, 'R': 33.60, 'T': 1.0, 'W': 1.0, 'V': -7.49, 'Y': 1.0},
+		'M': {'A': 13.34, 'C': 1.0, 'E': 1.0, 'D': 1.0, 'G': 1.0, 'F': 1.0, 'I': 1.0, 'H': 58.28, 'K': 1.0, 'M': -1.88, 'L': 1.0, 'N': 1.0, 'Q': -6.54, 'P': 44.94, 'S': 44.94, 'R': -6.54, 'T': -1.88, 'W': 1.0, 'V': 1.0, 'Y': 24.68},
+		'L': {'A': 1.0, 'C': 1.0, 'E': 1.0, 'D': 1.0, 'G': 1.0, 'F': 1.0, 'I': 1.0, 'H': 1.0, 'K': -7.49, 'M': 1.0, 'L': 1.0, 'N': 1.0, 'Q': 33.60, 'P': 20.26, 'S': 1.0, 'R': 20.26, 'T': 1.0, 'W': 24.68, 'V': 1.0, 'Y': 1.0},
+		'N': {'A': 1.0, 'C': -1.88, 'E': 1.0, 'D': 1.0, 'G': -14.03, 'F': -14.03, 'I': 44.94, 'H': 1.0, 'K': 24.68, 'M': 1.0, 'L': 1.0, 'N': 1.0, 'Q': -6.54, 'P': -1.88, 'S': 1.0, 'R': 1.0, 'T': -7.49, 'W': -9.37, 'V': 1.0, 'Y': 1.0},
+		'Q': {'A': 1.0, 'C': -6.54, 'E': 20.26, 'D': 20.26, 'G': 1.0, 'F': -6.54, 'I': 1.0, 'H': 1.0, 'K': 1.0, 'M': 1.0, 'L': 1.0, 'N': 1.0, 'Q': 20.26, 'P': 20.26, 'S': 44.94, 'R': 1.0, 'T': 1.0, 'W': 1.0, 'V': -6.54, 'Y': -6.54},
+		'P': {'A': 20.26, 'C': -6.54, 'E': 18.38, 'D': -6.54, 'G': 1.0, 'F': 20.26, 'I': 1.0, 'H': 1.0, 'K': 1.0, 'M': -6.54, 'L': 1.0, 'N': 1.0, 'Q': 20.26, 'P': 20.26, 'S': 20.26, 'R': -6.54, 'T': 1.0, 'W': -1.88, 'V': 20.26, 'Y': 1.0},
+		'S': {'A': 1.0, 'C': 33.60, 'E': 20.26, 'D': 1.0, 'G': 1.0, 'F': 1.0, 'I': 1.0, 'H': 1.0, 'K': 1.0, 'M': 1.0, 'L': 1.0, 'N': 1.0, 'Q': 20.26, 'P': 44.94, 'S': 20.26, 'R': 20.26, 'T': 1.0, 'W': 1.0, 'V': 1.0, 'Y': 1.0},
+		'R': {'A': 1.0, 'C': 1.0, 'E': 1.0, 'D': 1.0, 'G': -7.49, 'F': 1.0, 'I': 1.0, 'H': 20.26, 'K': 1.0, 'M': 1.0, 'L': 1.0, 'N': 13.34, 'Q': 20.26, 'P': 20.26, 'S': 44.94, 'R': 58.28, 'T': 1.0, 'W': 58.28, 'V': 1.0, 'Y': -6.54},
+		'T': {'A': 1.0, 'C': 1.0, 'E': 20.26, 'D': 1.0, 'G': -7.49, 'F': 13.34, 'I': 1.0, 'H': 1.0, 'K': 1.0, 'M': 1.0, 'L': 1.0, 'N': -14.03, 'Q': -6.54, 'P': 1.0, 'S': 1.0, 'R': 1.0, 'T': 1.0, 'W': -14.03, 'V': 1.0, 'Y': 1.0},
+		'W': {'A': -14.03, 'C': 1.0, 'E': 1.0, 'D': 1.0, 'G': -9.37, 'F': 1.0, 'I': 1.0, 'H': 24.68, 'K': 1.0, 'M': 24.68, 'L': 13.34, 'N': 13.34, 'Q': 1.0, 'P': 1.0, 'S': 1.0, 'R': 1.0, 'T': -14.03, 'W': 1.0, 'V': -7.49, 'Y': 1.0},
+		'V': {'A': 1.0, 'C': 1.0, 'E': 1.0, 'D': -14.03, 'G': -7.49, 'F': 1.0, 'I': 1.0, 'H': 1.0, 'K': -1.88, 'M': 1.0, 'L': 1.0, 'N': 1.0, 'Q': 1.0, 'P': 20.26, 'S': 1.0, 'R': 1.0, 'T': -7.49, 'W': 1.0, 'V': 1.0, 'Y': -6.54},
+		'Y': {'A': 24.68, 'C': 1.0, 'E': -6.54, 'D': 24.68, 'G': -7.49, 'F': 1.0, 'I': 1.0, 'H': 13.34, 'K': 1.0, 'M': 44.94, 'L': 1.0, 'N': 1.0, 'Q': 1.0, 'P': 13.34, 'S': 1.0, 'R': -15.91, 'T': -7.49, 'W': -9.37, 'V': 1.0, 'Y': 13.34},
+	}
+	for a, m := range diwv {
+		for b, v := range m {
+			aaDIWV[a][b] = v
+			aaDIWV[a+32][b] = v
+			aaDIWV[a][b+32] = v
+			aaDIWV[a+32][b+32] = v
+		}
+	}
+}
+
+// ProteinMolWeight returns the molecular weight of a protein sequence.
+// Gaps and stop symbols are ignored, and so are unknown residues like 'X'.
+func ProteinMolWeight(s []byte, monoisotopic bool) float64 {
+	masses := &aaMassAverage
+	water := waterMassAverage
+	if monoisotopic {
+		masses = &aaMassMonoisotopic
+		water = waterMassMonoisotopic
+	}
+	var w float64
+	var n int
+	for _, a := range s {
+		if masses[a] > 0 {
+			w += masses[a]
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return w + water
+}
+
+// ProteinCharge returns the net charge of a protein sequence at a pH.
+func ProteinCharge(s []byte, pH float64) float64 {
+	var first, last byte
+	counts := make(map[byte]int, 8)
+	var a byte
+	for _, a = range s {
+		if !isStandardAA[a] {
+			continue
+		}
+		if a >= 'a' {
+			a -= 32
+		}
+		if first == 0 {
+			first = a
+		}
+		last = a
+		counts[a]++
+	}
+	if first == 0 {
+		return 0
+	}
+
+	pKN, ok := pKaNTerm[first]
+	if !ok {
+		pKN = pKaNTermDefault
+	}
+	pKC, ok := pKaCTerm[last]
+	if !ok {
+		pKC = pKaCTermDefault
+	}
+
+	charge := 1/(1+math.Pow(10, pH-pKN)) - 1/(1+math.Pow(10, pKC-pH))
+	for a, pK := range pKaPositive {
+		charge += float64(counts[a]) / (1 + math.Pow(10, pH-pK))
+	}
+	for a, pK := range pKaNegative {
+		charge -= float64(counts[a]) / (1 + math.Pow(10, pK-pH))
+	}
+	return charge
+}
+
+// ProteinIsoelectricPoint returns the isoelectric point of a protein sequence,
+// computed with bisection.
+func ProteinIsoelectricPoint(s []byte) float64 {
+	lo, hi := 0.0, 14.0
+	var pH, charge float64
+	for hi-lo > 0.0001 {
+		pH = (lo + hi) / 2
+		charge = ProteinCharge(s, pH)
+		if charge > 0 {
+			lo = pH
+		} else {
+			hi = pH
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// ProteinGRAVY returns the grand average of hydropathy (GRAVY) of a protein sequence.
+// U and O are excluded, the same as ProtParam and Biopython.
+func ProteinGRAVY(s []byte) float64 {
+	var sum float64
+	var n int
+	for _, a := range s {
+		if is20StandardAA[a] {
+			sum += aaKyteDoolittle[a]
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// ProteinAromaticity returns the relative frequency of F, W and Y (Lobry 1994).
+// U and O are excluded, the same as ProtParam and Biopython.
+func ProteinAromaticity(s []byte) float64 {
+	var n, m int
+	for _, a := range s {
+		if !is20StandardAA[a] {
+			continue
+		}
+		n++
+		switch a {
+		case 'F', 'W', 'Y', 'f', 'w', 'y':
+			m++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return float64(m) / float64(n)
+}
+
+// ProteinInstabilityIndex returns the instability index (Guruprasad et al. 1990)
+// of a protein sequence. A protein whose instability index is smaller than 40
+// is predicted as stable.
+func ProteinInstabilityIndex(s []byte) float64 {
+	var sum float64
+	var n int
+	var prev byte
+	for _, a := range s {
+		if !isStandardAA[a] {
+			continue
+		}
+		if n > 0 {
+			sum += aaDIWV[prev][a]
+		}
+		prev = a
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return 10 / float64(n) * sum
+}
+
+// ProteinExtinctionCoefficient returns the molar extinction coefficients at
+// 280 nm of a protein sequence in water (Pace et al. 1995), assuming all Cys
+// residues are reduced, or all pairs of Cys residues form cystines.
+func ProteinExtinctionCoefficient(s []byte) (reduced int, cystines int) {
+	var nY, nW, nC int
+	for _, a := range s {
+		switch a {
+		case 'Y', 'y':
+			nY++
+		case 'W', 'w':
+			nW++
+		case 'C', 'c':
+			nC++
+		}
+	}
+	reduced = nY*1490 + nW*5500
+	return reduced, reduced + nC/2*125
+}
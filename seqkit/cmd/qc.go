@@ -0,0 +1,313 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/shenwei356/bio/seq"
+	"github.com/shenwei356/bio/seqio/fastx"
+	"github.com/shenwei356/util/pathutil"
+	"github.com/shenwei356/xopen"
+	"github.com/spf13/cobra"
+)
+
+// qcCmd represents the qc command
+var qcCmd = &cobra.Command{
+	GroupID: "basic",
+
+	Use:   "qc",
+	Short: "quality control report with per-position profiles (FastQC-style)",
+	Long: `quality control report with per-position profiles (FastQC-style).
+
+A summary table of all files is written to stdout (or -o/--out-file), and
+the report of each file is saved in the output directory (-O/--out-dir),
+as TSV files and a JSON file, with the prefix of the file name:
+
+  <prefix>.per_position_quality.tsv       quality quantiles (P10, Q1, median, Q3, P90)
+                                          and mean quality of each position (FASTQ only)
+  <prefix>.per_position_base_content.tsv  percentages of A, C, G, T, N and other bases of each position
+  <prefix>.per_sequence_gc.tsv            histogram of per-sequence GC content (%)
+  <prefix>.length_distribution.tsv        sequence length distribution
+  <prefix>.duplication_levels.tsv         estimated duplication levels
+  <prefix>.overrepresented_sequences.tsv  overrepresented sequences and possible sources
+  <prefix>.adapter_content.tsv            cumulative percentages of reads with adapters of each position
+  <prefix>.json                           all the above
+  <prefix>.<module>.png                   plots, with -p/--plot
+
+Duplication levels and overrepresented sequences are estimated with the
+first N (-d/--dup-track) distinct sequences, and sequences longer than 75 bp
+are truncated to 50 bp, the same as FastQC.
+
+Adapters (only the first 12 bases are searched, the same as FastQC):
+  Illumina Universal Adapter      AGATCGGAAGAG
+  Illumina Small RNA 3' Adapter   TGGAATTCTCGG
+  Illumina Small RNA 5' Adapter   GATCGTCGGACT
+  Nextera Transposase Sequence    CTGTCTCTTATA
+  PolyA                           AAAAAAAAAAAA
+  PolyG                           GGGGGGGGGGGG
+More adapters could be given via -a/--adapter.
+
+Columns of the summary table:
+  file, format, num_seqs, sum_len, min_len, avg_len, max_len,
+  GC(%), Q20(%), Q30(%), AvgQual, dedup(%), overrep_seqs, max_adapter(%)
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := getConfigs(cmd)
+		outFile := config.OutFile
+		quiet := config.Quiet
+		seq.AlphabetGuessSeqLengthThreshold = config.AlphabetGuessSeqLength
+		seq.ValidateSeq = false
+		runtime.GOMAXPROCS(config.Threads)
+
+		files := getFileListFromArgsAndFile(cmd, args, true, "infile-list", !config.SkipFileCheck)
+
+		outDir := getFlagString(cmd, "out-dir")
+		force := getFlagBool(cmd, "force")
+		plot := getFlagBool(cmd, "plot")
+		plotFormat := strings.ToLower(getFlagString(cmd, "plot-format"))
+		qBase := getFlagPositiveInt(cmd, "qual-ascii-base")
+		dupTrack := getFlagPositiveInt(cmd, "dup-track")
+		overrepMinPct := getFlagFloat64(cmd, "overrep-min-pct")
+		adapterList := getFlagStringSlice(cmd, "adapter")
+
+		switch plotFormat {
+		case "png", "pdf", "svg":
+		default:
+			checkError(fmt.Errorf("unsupported plot format: %s. available: png, pdf, svg", plotFormat))
+		}
+		if overrepMinPct <= 0 || overrepMinPct > 100 {
+			checkError(fmt.Errorf("the value of --overrep-min-pct should be in range of (0, 100]"))
+		}
+
+		adapters, err := parseQCAdapters(adapterList)
+		checkError(err)
+		adapters = append(append([]QCAdapter{}, QCDefaultAdapters...), adapters...)
+
+		// prefixes of output files
+		prefixes := make([]string, len(files))
+		prefixesMap := make(map[string]string, len(files))
+		for i, file := range files {
+			if isStdin(file) {
+				prefixes[i] = "stdin"
+			} else {
+				prefixes[i], _ = filepathTrimExtension(filepath.Base(file))
+			}
+			if f, ok := prefixesMap[prefixes[i]]; ok {
+				checkError(fmt.Errorf("files with the same base name: %s, %s", f, file))
+			}
+			prefixesMap[prefixes[i]] = file
+		}
+
+		// output directory
+		existed, err := pathutil.DirExists(outDir)
+		checkError(err)
+		if existed {
+			empty, err := pathutil.IsEmpty(outDir)
+			checkError(err)
+			if !empty {
+				if force {
+					checkError(os.RemoveAll(outDir))
+					checkError(os.MkdirAll(outDir, 0755))
+				} else {
+					log.Warningf("outdir not empty: %s, you can use --force to overwrite", outDir)
+				}
+			}
+		} else {
+			checkError(os.MkdirAll(outDir, 0755))
+		}
+
+		outfh, err := xopen.Wopen(outFile)
+		checkError(err)
+		defer outfh.Close()
+
+		// files are processed in parallel
+		reports := make([]*QCReport, len(files))
+		var wg sync.WaitGroup
+		tokens := make(chan int, config.Threads)
+		for i, file := range files {
+			wg.Add(1)
+			tokens <- 1
+			go func(i int, file string) {
+				defer func() {
+					wg.Done()
+					<-tokens
+				}()
+
+				c := NewQCCollector(file, qBase, adapters, dupTrack)
+				fastxReader, err := fastx.NewReader(config.Alphabet, file, config.IDRegexp)
+				checkError(err)
+				var record *fastx.Record
+				checkFormat := true
+				for {
+					record, err = fastxReader.Read()
+					if err != nil {
+						if err == io.EOF {
+							break
+						}
+						checkError(err)
+						break
+					}
+					if checkFormat {
+						c.IsFastq = fastxReader.IsFastq
+						checkFormat = false
+					}
+					c.Add(record)
+				}
+				fastxReader.Close()
+
+				r := c.Report(overrepMinPct)
+				checkError(writeQCReport(r, outDir, prefixes[i]))
+				if plot {
+					_, err = PlotQCReport(r, outDir, prefixes[i], plotFormat)
+					checkError(err)
+				}
+				reports[i] = r
+				if !quiet {
+					log.Infof("report of %s saved to %s", file, filepath.Join(outDir, prefixes[i]+".*"))
+				}
+			}(i, file)
+		}
+		wg.Wait()
+
+		outfh.WriteString("file\tformat\tnum_seqs\tsum_len\tmin_len\tavg_len\tmax_len\tGC(%)\tQ20(%)\tQ30(%)\tAvgQual\tdedup(%)\toverrep_seqs\tmax_adapter(%)\n")
+		for _, r := range reports {
+			s := r.Summary
+			fmt.Fprintf(outfh, "%s\t%s\t%d\t%d\t%d\t%.1f\t%d\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%d\t%.2f\n",
+				s.File, s.Format, s.NumSeqs, s.SumLen, s.MinLen, s.AvgLen, s.MaxLen,
+				s.GC, s.Q20, s.Q30, s.AvgQual, s.PctDeduplicated, s.Overrepresented, s.MaxAdapter)
+		}
+	},
+}
+
+// writeQCReport writes modules of a QC report into TSV files and a JSON file.
+func writeQCReport(r *QCReport, outDir, prefix string) error {
+	write := func(module string, fn func(w *xopen.Writer)) error {
+		outfh, err := xopen.Wopen(filepath.Join(outDir, prefix+"."+module+".tsv"))
+		if err != nil {
+			return err
+		}
+		fn(outfh)
+		return outfh.Close()
+	}
+
+	var err error
+	if err = write("per_position_quality", func(w *xopen.Writer) {
+		w.WriteString("position\tmean\tp10\tq1\tmedian\tq3\tp90\n")
+		for _, q := range r.PerPositionQuality {
+			fmt.Fprintf(w, "%d\t%.2f\t%d\t%d\t%d\t%d\t%d\n", q.Position, q.Mean, q.P10, q.Q1, q.Median, q.Q3, q.P90)
+		}
+	}); err != nil {
+		return err
+	}
+
+	if err = write("per_position_base_content", func(w *xopen.Writer) {
+		w.WriteString("position\tA\tC\tG\tT\tN\tothers\n")
+		for _, b := range r.PerPositionBaseContent {
+			fmt.Fprintf(w, "%d\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\n", b.Position, b.A, b.C, b.G, b.T, b.N, b.Others)
+		}
+	}); err != nil {
+		return err
+	}
+
+	if err = write("per_sequence_gc", func(w *xopen.Writer) {
+		w.WriteString("gc\tcount\n")
+		for _, c := range r.PerSequenceGC {
+			fmt.Fprintf(w, "%d\t%d\n", c.Value, c.Count)
+		}
+	}); err != nil {
+		return err
+	}
+
+	if err = write("length_distribution", func(w *xopen.Writer) {
+		w.WriteString("length\tcount\n")
+		for _, c := range r.LengthDistribution {
+			fmt.Fprintf(w, "%d\t%d\n", c.Value, c.Count)
+		}
+	}); err != nil {
+		return err
+	}
+
+	if err = write("duplication_levels", func(w *xopen.Writer) {
+		fmt.Fprintf(w, "#total_deduplicated_percentage\t%.2f\n", r.Summary.PctDeduplicated)
+		w.WriteString("level\tpct_of_deduplicated\tpct_of_total\n")
+		for _, d := range r.DuplicationLevels {
+			fmt.Fprintf(w, "%s\t%.2f\t%.2f\n", d.Level, d.PctDeduplicated, d.PctTotal)
+		}
+	}); err != nil {
+		return err
+	}
+
+	if err = write("overrepresented_sequences", func(w *xopen.Writer) {
+		w.WriteString("sequence\tcount\tpercentage\tpossible_source\n")
+		for _, o := range r.Overrepresented {
+			fmt.Fprintf(w, "%s\t%d\t%.4f\t%s\n", o.Seq, o.Count, o.Pct, o.Source)
+		}
+	}); err != nil {
+		return err
+	}
+
+	if err = write("adapter_content", func(w *xopen.Writer) {
+		w.WriteString("position\t" + strings.Join(r.Adapters, "\t") + "\n")
+		for _, a := range r.AdapterContent {
+			fmt.Fprintf(w, "%d", a.Position)
+			for _, v := range a.Pcts {
+				fmt.Fprintf(w, "\t%.2f", v)
+			}
+			w.WriteString("\n")
+		}
+	}); err != nil {
+		return err
+	}
+
+	outfh, err := xopen.Wopen(filepath.Join(outDir, prefix+".json"))
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(outfh)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(r); err != nil {
+		outfh.Close()
+		return err
+	}
+	return outfh.Close()
+}
+
+func init() {
+	RootCmd.AddCommand(qcCmd)
+
+	qcCmd.Flags().StringP("out-dir", "O", "qc", "output directory")
+	qcCmd.Flags().BoolP("force", "f", false, "overwrite output directory")
+	qcCmd.Flags().BoolP("plot", "p", false, "plot modules of the report")
+	qcCmd.Flags().StringP("plot-format", "F", "png", "plot format: png, pdf, svg")
+	qcCmd.Flags().IntP("qual-ascii-base", "b", 33, "ASCII BASE, 33 for Phred+33")
+	qcCmd.Flags().IntP("dup-track", "d", 100000, "number of distinct sequences tracked for estimating duplication levels and overrepresented sequences")
+	qcCmd.Flags().Float64P("overrep-min-pct", "m", 0.1, "minimum percentage of overrepresented sequences")
+	qcCmd.Flags().StringSliceP("adapter", "a", []string{}, `more adapters in the format of "name:sequence", multiple values supported`)
+}
@@ -0,0 +1,188 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"path/filepath"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+)
+
+// qcSeries is a line in a plot.
+type qcSeries struct {
+	Name string
+	Ys   []float64
+}
+
+// qcLinePlot draws lines sharing the same x values, and saves the plot
+// into a file, the format of which is decided by the file extension.
+func qcLinePlot(file, title, xLabel, yLabel string, xs []float64, series []qcSeries, xNames []string) error {
+	p := plot.New()
+	p.Title.Text = title
+	p.X.Label.Text = xLabel
+	p.Y.Label.Text = yLabel
+	p.Add(plotter.NewGrid())
+	p.Legend.Top = true
+
+	for i, s := range series {
+		pts := make(plotter.XYs, len(xs))
+		for j, x := range xs {
+			pts[j].X = x
+			pts[j].Y = s.Ys[j]
+		}
+		l, err := plotter.NewLine(pts)
+		if err != nil {
+			return err
+		}
+		l.Color = plotutil.Color(i)
+		l.Width = vg.Points(1.5)
+		p.Add(l)
+		if s.Name != "" {
+			p.Legend.Add(s.Name, l)
+		}
+	}
+	if len(xNames) > 0 {
+		p.NominalX(xNames...)
+	}
+
+	return p.Save(10*vg.Inch, 6*vg.Inch, file)
+}
+
+// PlotQCReport plots modules of a QC report, and returns the plot files.
+func PlotQCReport(r *QCReport, outDir, prefix, format string) ([]string, error) {
+	files := make([]string, 0, 7)
+	name := func(module string) string {
+		file := filepath.Join(outDir, prefix+"."+module+"."+format)
+		files = append(files, file)
+		return file
+	}
+	var xs []float64
+
+	// per-position quality
+	if len(r.PerPositionQuality) > 0 {
+		xs = make([]float64, len(r.PerPositionQuality))
+		series := []qcSeries{{Name: "P10"}, {Name: "Q1"}, {Name: "Median"}, {Name: "Q3"}, {Name: "P90"}, {Name: "Mean"}}
+		for i := range series {
+			series[i].Ys = make([]float64, len(xs))
+		}
+		for i, q := range r.PerPositionQuality {
+			xs[i] = float64(q.Position)
+			series[0].Ys[i] = float64(q.P10)
+			series[1].Ys[i] = float64(q.Q1)
+			series[2].Ys[i] = float64(q.Median)
+			series[3].Ys[i] = float64(q.Q3)
+			series[4].Ys[i] = float64(q.P90)
+			series[5].Ys[i] = q.Mean
+		}
+		if err := qcLinePlot(name("per_position_quality"), "Per-position quality - "+r.Summary.File,
+			"Position (bp)", "Quality", xs, series, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	// per-position base content
+	if len(r.PerPositionBaseContent) > 0 {
+		xs = make([]float64, len(r.PerPositionBaseContent))
+		series := []qcSeries{{Name: "A"}, {Name: "C"}, {Name: "G"}, {Name: "T"}, {Name: "N"}}
+		for i := range series {
+			series[i].Ys = make([]float64, len(xs))
+		}
+		for i, b := range r.PerPositionBaseContent {
+			xs[i] = float64(b.Position)
+			series[0].Ys[i] = b.A
+			series[1].Ys[i] = b.C
+			series[2].Ys[i] = b.G
+			series[3].Ys[i] = b.T
+			series[4].Ys[i] = b.N
+		}
+		if err := qcLinePlot(name("per_position_base_content"), "Per-position base content - "+r.Summary.File,
+			"Position (bp)", "Percentage (%)", xs, series, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	// per-sequence GC and length distribution
+	for _, m := range []struct {
+		module, title, xLabel string
+		counts                []QCCount
+	}{
+		{"per_sequence_gc", "Per-sequence GC content", "GC content (%)", r.PerSequenceGC},
+		{"length_distribution", "Length distribution", "Length (bp)", r.LengthDistribution},
+	} {
+		if len(m.counts) == 0 {
+			continue
+		}
+		xs = make([]float64, len(m.counts))
+		ys := make([]float64, len(m.counts))
+		for i, c := range m.counts {
+			xs[i] = float64(c.Value)
+			ys[i] = float64(c.Count)
+		}
+		if err := qcLinePlot(name(m.module), m.title+" - "+r.Summary.File,
+			m.xLabel, "Count", xs, []qcSeries{{Ys: ys}}, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	// duplication levels
+	if len(r.DuplicationLevels) > 0 {
+		xs = make([]float64, len(r.DuplicationLevels))
+		labels := make([]string, len(xs))
+		series := []qcSeries{{Name: "% of deduplicated"}, {Name: "% of total"}}
+		for i := range series {
+			series[i].Ys = make([]float64, len(xs))
+		}
+		for i, d := range r.DuplicationLevels {
+			xs[i] = float64(i)
+			labels[i] = d.Level
+			series[0].Ys[i] = d.PctDeduplicated
+			series[1].Ys[i] = d.PctTotal
+		}
+		if err := qcLinePlot(name("duplication_levels"), "Duplication levels - "+r.Summary.File,
+			"Duplication level", "Percentage (%)", xs, series, labels); err != nil {
+			return nil, err
+		}
+	}
+
+	// adapter content
+	if len(r.AdapterContent) > 0 {
+		xs = make([]float64, len(r.AdapterContent))
+		series := make([]qcSeries, len(r.Adapters))
+		for i, a := range r.Adapters {
+			series[i] = qcSeries{Name: a, Ys: make([]float64, len(xs))}
+		}
+		for i, a := range r.AdapterContent {
+			xs[i] = float64(a.Position)
+			for k, v := range a.Pcts {
+				series[k].Ys[i] = v
+			}
+		}
+		if err := qcLinePlot(name("adapter_content"), "Adapter content - "+r.Summary.File,
+			"Position (bp)", "Percentage (%)", xs, series, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,510 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/shenwei356/bio/seq"
+	"github.com/shenwei356/bio/seqio/fastx"
+)
+
+// QCAdapter is an adapter sequence searched in the adapter content module.
+type QCAdapter struct {
+	Name string
+	Seq  []byte
+}
+
+// QCDefaultAdapters are the adapters used by FastQC, in which only the
+// first 12 bases are searched.
+var QCDefaultAdapters = []QCAdapter{
+	{"Illumina Universal Adapter", []byte("AGATCGGAAGAG")},
+	{"Illumina Small RNA 3' Adapter", []byte("TGGAATTCTCGG")},
+	{"Illumina Small RNA 5' Adapter", []byte("GATCGTCGGACT")},
+	{"Nextera Transposase Sequence", []byte("CTGTCTCTTATA")},
+	{"PolyA", []byte("AAAAAAAAAAAA")},
+	{"PolyG", []byte("GGGGGGGGGGGG")},
+}
+
+// levels of sequence duplication, the same as FastQC
+var qcDupLevels = []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 50, 100, 500, 1000, 5000, 10000}
+var qcDupLevelLabels = []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", ">10", ">50", ">100", ">500", ">1k", ">5k", ">10k"}
+
+const qcMaxQual = 93
+
+// qcBases are bases counted in the per-position base content module.
+const qcBases = "ACGTN"
+
+var qcBaseIndex = func() [256]int8 {
+	var m [256]int8
+	for i := range m {
+		m[i] = -1
+	}
+	for i, b := range []byte(qcBases) {
+		m[b] = int8(i)
+		m[b+'a'-'A'] = int8(i)
+	}
+	m['U'], m['u'] = 3, 3
+	return m
+}()
+
+// QCCollector collects statistics of reads for the QC report.
+type QCCollector struct {
+	File     string
+	IsFastq  bool
+	qBase    int
+	adapters []QCAdapter
+
+	numSeqs        int64
+	sumLen         int64
+	minLen, maxLen int
+	lenCounts      map[int]int64
+
+	qualHist   [][qcMaxQual + 1]int64 // position -> quality -> count
+	baseCount  [][len(qcBases) + 1]int64
+	gcHist     [101]int64
+	nGC, nACGT int64
+	q20, q30   int64
+	errSum     float64
+
+	// duplication, the same strategy as FastQC: only the first
+	// dupTrackLimit distinct sequences are tracked.
+	dupTrackLimit int
+	dupCounts     map[string]int64
+	countAtLimit  int64
+
+	adapterStart [][]int64 // adapter -> position of the first match -> count
+}
+
+// NewQCCollector creates a QCCollector.
+func NewQCCollector(file string, qBase int, adapters []QCAdapter, dupTrackLimit int) *QCCollector {
+	c := &QCCollector{
+		File:          file,
+		qBase:         qBase,
+		adapters:      adapters,
+		minLen:        -1,
+		lenCounts:     make(map[int]int64, 1024),
+		dupTrackLimit: dupTrackLimit,
+		dupCounts:     make(map[string]int64, min(dupTrackLimit, 1<<16)),
+		adapterStart:  make([][]int64, len(adapters)),
+	}
+	return c
+}
+
+// Add adds a record.
+func (c *QCCollector) Add(record *fastx.Record) {
+	s := record.Seq.Seq
+	qual := record.Seq.Qual
+	l := len(s)
+
+	c.numSeqs++
+	c.sumLen += int64(l)
+	if c.minLen < 0 || l < c.minLen {
+		c.minLen = l
+	}
+	if l > c.maxLen {
+		c.maxLen = l
+	}
+	c.lenCounts[l]++
+
+	if l > len(c.baseCount) {
+		c.baseCount = append(c.baseCount, make([][len(qcBases) + 1]int64, l-len(c.baseCount))...)
+	}
+	if len(qual) > 0 && l > len(c.qualHist) {
+		c.qualHist = append(c.qualHist, make([][qcMaxQual + 1]int64, l-len(c.qualHist))...)
+	}
+
+	// bases and qualities
+	var i int
+	var b byte
+	var j int8
+	var gc, acgt int
+	for i, b = range s {
+		j = qcBaseIndex[b]
+		if j < 0 {
+			c.baseCount[i][len(qcBases)]++
+			continue
+		}
+		c.baseCount[i][j]++
+		if j < 4 {
+			acgt++
+			if j == 1 || j == 2 {
+				gc++
+			}
+		}
+	}
+	if acgt > 0 {
+		c.gcHist[int(math.Round(float64(gc)/float64(acgt)*100))]++
+	}
+	c.nGC += int64(gc)
+	c.nACGT += int64(acgt)
+
+	var q int
+	for i, b = range qual {
+		q = int(b) - c.qBase
+		if q < 0 {
+			q = 0
+		} else if q > qcMaxQual {
+			q = qcMaxQual
+		}
+		c.qualHist[i][q]++
+		if q >= 20 {
+			c.q20++
+			if q >= 30 {
+				c.q30++
+			}
+		}
+		c.errSum += seq.QUAL_MAP[q]
+	}
+
+	upper := bytes.ToUpper(s)
+
+	// duplication, long sequences are truncated to 50 bp like FastQC
+	key := upper
+	if len(key) > 75 {
+		key = key[:50]
+	}
+	if n, ok := c.dupCounts[string(key)]; ok {
+		c.dupCounts[string(key)] = n + 1
+		if len(c.dupCounts) < c.dupTrackLimit {
+			c.countAtLimit = c.numSeqs
+		}
+	} else if len(c.dupCounts) < c.dupTrackLimit {
+		c.dupCounts[string(key)] = 1
+		c.countAtLimit = c.numSeqs
+	}
+
+	// adapters
+	for k, a := range c.adapters {
+		if i = bytes.Index(upper, a.Seq); i >= 0 {
+			if i >= len(c.adapterStart[k]) {
+				c.adapterStart[k] = append(c.adapterStart[k], make([]int64, i-len(c.adapterStart[k])+1)...)
+			}
+			c.adapterStart[k][i]++
+		}
+	}
+}
+
+// QCPositionQuality is the quality distribution at a position.
+type QCPositionQuality struct {
+	Position int     `json:"position"`
+	Mean     float64 `json:"mean"`
+	P10      int     `json:"p10"`
+	Q1       int     `json:"q1"`
+	Median   int     `json:"median"`
+	Q3       int     `json:"q3"`
+	P90      int     `json:"p90"`
+}
+
+// QCPositionBases is the base content (percentages) at a position.
+type QCPositionBases struct {
+	Position int     `json:"position"`
+	A        float64 `json:"A"`
+	C        float64 `json:"C"`
+	G        float64 `json:"G"`
+	T        float64 `json:"T"`
+	N        float64 `json:"N"`
+	Others   float64 `json:"others"`
+}
+
+// QCCount is a value with its count.
+type QCCount struct {
+	Value int   `json:"value"`
+	Count int64 `json:"count"`
+}
+
+// QCDuplicationLevel is the proportion of a duplication level.
+type QCDuplicationLevel struct {
+	Level           string  `json:"level"`
+	PctDeduplicated float64 `json:"pct_of_deduplicated"`
+	PctTotal        float64 `json:"pct_of_total"`
+}
+
+// QCOverrepresented is an overrepresented sequence.
+type QCOverrepresented struct {
+	Seq    string  `json:"sequence"`
+	Count  int64   `json:"count"`
+	Pct    float64 `json:"percentage"`
+	Source string  `json:"possible_source"`
+}
+
+// QCAdapterContent is the cumulative percentages of reads containing
+// each adapter at a position.
+type QCAdapterContent struct {
+	Position int       `json:"position"`
+	Pcts     []float64 `json:"percentages"`
+}
+
+// QCSummary is the summary of a file.
+type QCSummary struct {
+	File            string  `json:"file"`
+	Format          string  `json:"format"`
+	NumSeqs         int64   `json:"num_seqs"`
+	SumLen          int64   `json:"sum_len"`
+	MinLen          int     `json:"min_len"`
+	AvgLen          float64 `json:"avg_len"`
+	MaxLen          int     `json:"max_len"`
+	GC              float64 `json:"gc"`
+	Q20             float64 `json:"q20"`
+	Q30             float64 `json:"q30"`
+	AvgQual         float64 `json:"avg_qual"`
+	PctDeduplicated float64 `json:"pct_deduplicated"`
+	Overrepresented int     `json:"overrepresented_seqs"`
+	MaxAdapter      float64 `json:"max_adapter_content"`
+}
+
+// QCReport is the QC report of a file.
+type QCReport struct {
+	Summary                QCSummary            `json:"summary"`
+	PerPositionQuality     []QCPositionQuality  `json:"per_position_quality"`
+	PerPositionBaseContent []QCPositionBases    `json:"per_position_base_content"`
+	PerSequenceGC          []QCCount            `json:"per_sequence_gc"`
+	LengthDistribution     []QCCount            `json:"length_distribution"`
+	DuplicationLevels      []QCDuplicationLevel `json:"duplication_levels"`
+	Overrepresented        []QCOverrepresented  `json:"overrepresented_sequences"`
+	Adapters               []string             `json:"adapters"`
+	AdapterContent         []QCAdapterContent   `json:"adapter_content"`
+}
+
+// Report computes the report. Sequences with a proportion >= overrepMinPct
+// (percentage) are reported as overrepresented sequences.
+func (c *QCCollector) Report(overrepMinPct float64) *QCReport {
+	r := &QCReport{}
+	pct := func(a, b int64) float64 {
+		if b == 0 {
+			return 0
+		}
+		return float64(a) / float64(b) * 100
+	}
+
+	// summary
+	s := &r.Summary
+	s.File = c.File
+	s.Format = "FASTA"
+	if c.IsFastq {
+		s.Format = "FASTQ"
+	}
+	s.NumSeqs = c.numSeqs
+	s.SumLen = c.sumLen
+	s.MinLen = max(c.minLen, 0)
+	s.MaxLen = c.maxLen
+	if c.numSeqs > 0 {
+		s.AvgLen = float64(c.sumLen) / float64(c.numSeqs)
+	}
+	s.GC = pct(c.nGC, c.nACGT)
+	if c.IsFastq && c.sumLen > 0 {
+		s.Q20 = pct(c.q20, c.sumLen)
+		s.Q30 = pct(c.q30, c.sumLen)
+		s.AvgQual = -10 * math.Log10(c.errSum/float64(c.sumLen))
+	}
+
+	// per-position quality
+	var n, acc int64
+	var sum float64
+	quantile := func(h *[qcMaxQual + 1]int64, n int64, p float64) int {
+		target := int64(math.Ceil(float64(n) * p))
+		if target < 1 {
+			target = 1
+		}
+		acc = 0
+		for q, m := range h {
+			acc += m
+			if acc >= target {
+				return q
+			}
+		}
+		return qcMaxQual
+	}
+	for i := range c.qualHist {
+		h := &c.qualHist[i]
+		n, sum = 0, 0
+		for q, m := range h {
+			n += m
+			sum += float64(q) * float64(m)
+		}
+		if n == 0 {
+			continue
+		}
+		r.PerPositionQuality = append(r.PerPositionQuality, QCPositionQuality{
+			Position: i + 1,
+			Mean:     sum / float64(n),
+			P10:      quantile(h, n, 0.1),
+			Q1:       quantile(h, n, 0.25),
+			Median:   quantile(h, n, 0.5),
+			Q3:       quantile(h, n, 0.75),
+			P90:      quantile(h, n, 0.9),
+		})
+	}
+
+	// per-position base content
+	for i, counts := range c.baseCount {
+		n = 0
+		for _, m := range counts {
+			n += m
+		}
+		r.PerPositionBaseContent = append(r.PerPositionBaseContent, QCPositionBases{
+			Position: i + 1,
+			A:        pct(counts[0], n),
+			C:        pct(counts[1], n),
+			G:        pct(counts[2], n),
+			T:        pct(counts[3], n),
+			N:        pct(counts[4], n),
+			Others:   pct(counts[5], n),
+		})
+	}
+
+	// per-sequence GC
+	for gc, m := range c.gcHist {
+		r.PerSequenceGC = append(r.PerSequenceGC, QCCount{Value: gc, Count: m})
+	}
+
+	// length distribution
+	r.LengthDistribution = make([]QCCount, 0, len(c.lenCounts))
+	for l, m := range c.lenCounts {
+		r.LengthDistribution = append(r.LengthDistribution, QCCount{Value: l, Count: m})
+	}
+	sort.Slice(r.LengthDistribution, func(i, j int) bool {
+		return r.LengthDistribution[i].Value < r.LengthDistribution[j].Value
+	})
+
+	// duplication levels
+	r.DuplicationLevels, s.PctDeduplicated = c.duplicationLevels()
+
+	// overrepresented sequences
+	for key, m := range c.dupCounts {
+		if p := pct(m, c.numSeqs); p >= overrepMinPct && m > 1 {
+			r.Overrepresented = append(r.Overrepresented, QCOverrepresented{
+				Seq: key, Count: m, Pct: p, Source: c.possibleSource([]byte(key)),
+			})
+		}
+	}
+	sort.Slice(r.Overrepresented, func(i, j int) bool {
+		if r.Overrepresented[i].Count != r.Overrepresented[j].Count {
+			return r.Overrepresented[i].Count > r.Overrepresented[j].Count
+		}
+		return r.Overrepresented[i].Seq < r.Overrepresented[j].Seq
+	})
+	s.Overrepresented = len(r.Overrepresented)
+
+	// adapter content
+	r.Adapters = make([]string, len(c.adapters))
+	for k, a := range c.adapters {
+		r.Adapters[k] = a.Name
+	}
+	accs := make([]int64, len(c.adapters))
+	for i := 0; i < c.maxLen; i++ {
+		a := QCAdapterContent{Position: i + 1, Pcts: make([]float64, len(c.adapters))}
+		for k := range c.adapters {
+			if i < len(c.adapterStart[k]) {
+				accs[k] += c.adapterStart[k][i]
+			}
+			a.Pcts[k] = pct(accs[k], c.numSeqs)
+			if a.Pcts[k] > s.MaxAdapter {
+				s.MaxAdapter = a.Pcts[k]
+			}
+		}
+		r.AdapterContent = append(r.AdapterContent, a)
+	}
+
+	return r
+}
+
+// duplicationLevels estimates the duplication levels with tracked
+// sequences, using the correction of FastQC for sequences not tracked.
+func (c *QCCollector) duplicationLevels() ([]QCDuplicationLevel, float64) {
+	levels := make([]QCDuplicationLevel, len(qcDupLevels))
+	for i := range levels {
+		levels[i].Level = qcDupLevelLabels[i]
+	}
+	if len(c.dupCounts) == 0 {
+		return levels, 0
+	}
+
+	// number of distinct sequences of each duplication count
+	obs := make(map[int64]int64, 128)
+	for _, m := range c.dupCounts {
+		obs[m]++
+	}
+
+	dedup := make([]float64, len(qcDupLevels))
+	total := make([]float64, len(qcDupLevels))
+	var sumDedup, sumTotal, corrected float64
+	var k int
+	for level, m := range obs {
+		corrected = qcCorrectedCount(c.countAtLimit, c.numSeqs, level, m)
+		k = sort.Search(len(qcDupLevels), func(i int) bool { return qcDupLevels[i] > level }) - 1
+		dedup[k] += corrected
+		total[k] += corrected * float64(level)
+		sumDedup += corrected
+		sumTotal += corrected * float64(level)
+	}
+	for i := range levels {
+		levels[i].PctDeduplicated = dedup[i] / sumDedup * 100
+		levels[i].PctTotal = total[i] / sumTotal * 100
+	}
+	return levels, sumDedup / sumTotal * 100
+}
+
+// qcCorrectedCount estimates the real number of distinct sequences with a
+// duplication level, given the number observed in the first countAtLimit
+// sequences, the same as FastQC.
+func qcCorrectedCount(countAtLimit, totalCount, level, observed int64) float64 {
+	if countAtLimit == totalCount || totalCount-observed < countAtLimit {
+		return float64(observed)
+	}
+	pNotSeeing := 1.0
+	limitOfCaring := 1 - float64(observed)/(float64(observed)+0.01)
+	for i := int64(0); i < countAtLimit; i++ {
+		pNotSeeing *= float64(totalCount-i-level) / float64(totalCount-i)
+		if pNotSeeing < limitOfCaring {
+			pNotSeeing = 0
+			break
+		}
+	}
+	return float64(observed) / (1 - pNotSeeing)
+}
+
+// possibleSource returns the name of the adapter contained in the sequence.
+func (c *QCCollector) possibleSource(s []byte) string {
+	for _, a := range c.adapters {
+		if bytes.Contains(s, a.Seq) {
+			return a.Name
+		}
+	}
+	return "No Hit"
+}
+
+// parseQCAdapters parses adapters in the format of "name:sequence".
+func parseQCAdapters(list []string) ([]QCAdapter, error) {
+	adapters := make([]QCAdapter, 0, len(list))
+	for _, s := range list {
+		i := strings.LastIndex(s, ":")
+		if i <= 0 || i == len(s)-1 {
+			return nil, fmt.Errorf(`invalid adapter: %s. it should be in the format of "name:sequence"`, s)
+		}
+		adapters = append(adapters, QCAdapter{Name: s[:i], Seq: bytes.ToUpper([]byte(s[i+1:]))})
+	}
+	return adapters, nil
+}
@@ -0,0 +1,154 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// GA4GH refget (https://ga4gh.github.io/refget/sequences/) and
+// sequence collections (https://ga4gh.github.io/refget/seqcols/).
+
+// sha512t24u returns the GA4GH digest of data: base64url encoding of
+// the first 24 bytes of the SHA-512 digest.
+func sha512t24u(data []byte) string {
+	sum := sha512.Sum512(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:24])
+}
+
+// RefgetDigests returns the refget sha512t24u identifier ("SQ." prefixed)
+// and the MD5 digest of a sequence, which should be in upper case.
+func RefgetDigests(s []byte) (string, string) {
+	sum := md5.Sum(s)
+	return "SQ." + sha512t24u(s), hex.EncodeToString(sum[:])
+}
+
+// SeqCollection holds the level-2 attributes of a sequence collection.
+type SeqCollection struct {
+	Names     []string
+	Lengths   []int
+	Sequences []string // refget identifiers
+}
+
+// NewSeqCollection returns an empty sequence collection.
+func NewSeqCollection() *SeqCollection {
+	return &SeqCollection{
+		Names:     make([]string, 0, 128),
+		Lengths:   make([]int, 0, 128),
+		Sequences: make([]string, 0, 128),
+	}
+}
+
+// Add adds a sequence, which should be in upper case.
+func (c *SeqCollection) Add(name string, s []byte) {
+	c.Names = append(c.Names, name)
+	c.Lengths = append(c.Lengths, len(s))
+	c.Sequences = append(c.Sequences, "SQ."+sha512t24u(s))
+}
+
+// SeqCollectionDigests holds level-0 and level-1 digests of a sequence collection.
+type SeqCollectionDigests struct {
+	Level0 string
+
+	Names                 string
+	Lengths               string
+	Sequences             string
+	SortedNameLengthPairs string
+}
+
+// Digests computes the level-1 digests of attributes, and the level-0 digest
+// from the level-1 digests of the inherent attributes (names and sequences).
+func (c *SeqCollection) Digests() *SeqCollectionDigests {
+	d := &SeqCollectionDigests{
+		Names:     sha512t24u(canonicalJSONStrings(c.Names)),
+		Lengths:   sha512t24u(canonicalJSONInts(c.Lengths)),
+		Sequences: sha512t24u(canonicalJSONStrings(c.Sequences)),
+	}
+
+	// sorted digests of the objects {"length":..., "name":...}
+	pairs := make([]string, len(c.Names))
+	var buf bytes.Buffer
+	for i, name := range c.Names {
+		buf.Reset()
+		buf.WriteString(`{"length":`)
+		buf.WriteString(strconv.Itoa(c.Lengths[i]))
+		buf.WriteString(`,"name":`)
+		buf.Write(canonicalJSONString(name))
+		buf.WriteString(`}`)
+		pairs[i] = sha512t24u(buf.Bytes())
+	}
+	sort.Strings(pairs)
+	d.SortedNameLengthPairs = sha512t24u(canonicalJSONStrings(pairs))
+
+	// keys are sorted
+	buf.Reset()
+	buf.WriteString(`{"names":`)
+	buf.Write(canonicalJSONString(d.Names))
+	buf.WriteString(`,"sequences":`)
+	buf.Write(canonicalJSONString(d.Sequences))
+	buf.WriteString(`}`)
+	d.Level0 = sha512t24u(buf.Bytes())
+
+	return d
+}
+
+// canonicalJSONString returns a JSON string in the RFC-8785 style, where
+// HTML characters are not escaped.
+func canonicalJSONString(s string) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return bytes.TrimRight(buf.Bytes(), "\n")
+}
+
+func canonicalJSONStrings(a []string) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, s := range a {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(canonicalJSONString(s))
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
+func canonicalJSONInts(a []int) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, v := range a {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(strconv.Itoa(v))
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
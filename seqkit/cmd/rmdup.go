@@ -24,11 +24,15 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
+	"os"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
 
 	"github.com/cespare/xxhash/v2"
+	"github.com/pkg/errors"
 	"github.com/shenwei356/bio/seq"
 	"github.com/shenwei356/bio/seqio/fastx"
 	"github.com/shenwei356/xopen"
@@ -48,7 +52,45 @@ Attention:
      compared. Switch on -P/--only-positive-strand for considering the
      positive strand only.
   2. Only the first record is saved for duplicates.
-     
+  3. For huge inputs, e.g., billions of reads, use --max-mem to limit the
+     memory usage of hash values, which are then sorted in chunks and
+     saved in the temporary directory (--tmp-dir). Input files are read
+     twice, and the data from stdin is saved in the temporary directory.
+     The results are identical to those of the in-memory mode, while
+     about 1 bit per record and IDs of duplicated records (-D) are still
+     kept in the memory.
+
+Paired-end reads (-1/--read1 and -2/--read2):
+  1. Read pairs are compared by sequences of both mates, and a pair is
+     duplicated if the sequences of R1+R2 are the same as those of a
+     previous pair. Pairs in the opposite orientation, i.e., R2+R1, are
+     also regarded as duplicates unless -P/--only-positive-strand is given.
+  2. Use -N/--first-bases to only compare the first N bases of each mate,
+     which tolerates sequencing errors and adapter trimming at 3' ends.
+  3. The first pair is kept by default. Use -Q/--keep-best-qual to keep
+     the pair with the highest average quality, which reads the files twice.
+  4. Reads in the two files should be paired, you may use 'seqkit pair' first.
+  5. Outputs are saved in the directory of read1 with a suffix "rmdup",
+     e.g., read_1.rmdup.fq.gz, or in the given directory (-O/--out-dir)
+     with original names. Duplicated pairs (-d) are saved into two files,
+     e.g., -d dup.fq.gz creates dup_1.fq.gz and dup_2.fq.gz, and IDs of
+     read1 are used in the file of duplication numbers (-D).
+
+Optical duplicates (--optical-dist):
+  1. Sequence-identical reads (-s or paired-end reads) on the same tile,
+     with distances of x and y coordinates both within the given pixel
+     distance, are regarded as optical duplicates. Other duplicates are
+     library (PCR) duplicates. Recommended distances are 100 for
+     non-patterned flowcells, and 2500 for patterned flowcells
+     (HiSeq X/4000, NovaSeq).
+  2. Lanes, tiles, and coordinates are parsed from Illumina read IDs:
+       instrument:run:flowcell:lane:tile:x:y, or
+       instrument:lane:tile:x:y#index/read.
+  3. Duplicates are removed as usual, and duplication rates of each tile
+     and lane are saved to the file given by --optical-report, with
+     columns: lane, tile, reads, dups, optical_dups, library_dups,
+     dup_rate, optical_dup_rate, library_dup_rate.
+
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		config := getConfigs(cmd)
@@ -67,6 +109,13 @@ Attention:
 		dupFile := getFlagString(cmd, "dup-seqs-file")
 		numFile := getFlagString(cmd, "dup-num-file")
 
+		maxMemS := getFlagString(cmd, "max-mem")
+		tmpDir := filepath.Clean(getFlagString(cmd, "tmp-dir"))
+		maxMem, err := ParseByteSize(maxMemS)
+		if err != nil {
+			checkError(fmt.Errorf("invalid value of flag --max-mem: %s", maxMemS))
+		}
+
 		saveDupFile := dupFile != ""
 		saveNumFile := numFile != ""
 
@@ -77,9 +126,63 @@ Attention:
 			checkError(fmt.Errorf("only one/none of the flags -s (--by-seq) and -n (--by-name) is allowed"))
 		}
 
+		opticalDist := getFlagNonNegativeInt(cmd, "optical-dist")
+		opticalReport := getFlagString(cmd, "optical-report")
+		var optical *OpticalDupCounter
+		if opticalDist > 0 {
+			if maxMem > 0 {
+				checkError(fmt.Errorf("flag --optical-dist is not supported when using --max-mem"))
+			}
+			optical = NewOpticalDupCounter(opticalDist)
+		} else if opticalReport != "" {
+			checkError(fmt.Errorf("flag --optical-dist needed when using --optical-report"))
+		}
+
+		read1 := getFlagString(cmd, "read1")
+		read2 := getFlagString(cmd, "read2")
+		if read1 != "" || read2 != "" {
+			if read1 == "" || read2 == "" {
+				checkError(fmt.Errorf("flag -1/--read1 and -2/--read2 needed"))
+			}
+			if read1 == read2 {
+				checkError(fmt.Errorf("values of flag -1/--read1 and -2/--read2 can not be the same"))
+			}
+			if len(args) > 0 {
+				checkError(fmt.Errorf("no positional arguments are allowed for paired-end reads: %s", strings.Join(args, " ")))
+			}
+			if byName {
+				checkError(fmt.Errorf("flag -n (--by-name) is not supported for paired-end reads, which are compared by sequences"))
+			}
+			if maxMem > 0 {
+				checkError(fmt.Errorf("flag --max-mem is not supported for paired-end reads"))
+			}
+
+			opt := &rmdupPairedOptions{
+				read1:      read1,
+				read2:      read2,
+				outDir:     getFlagString(cmd, "out-dir"),
+				firstBases: getFlagNonNegativeInt(cmd, "first-bases"),
+				keepBest:   getFlagBool(cmd, "keep-best-qual"),
+				qBase:      getFlagPositiveInt(cmd, "qual-ascii-base"),
+				ignoreCase: ignoreCase,
+				revcom:     !getFlagBool(cmd, "only-positive-strand"),
+				dupFile:    dupFile,
+				numFile:    numFile,
+				optical:    optical,
+			}
+			rmdupPaired(config, opt)
+			if optical != nil {
+				reportOpticalDups(optical, opticalReport, quiet)
+			}
+			return
+		}
+
 		if !revcom && !bySeq {
 			checkError(fmt.Errorf("flag -s (--by-seq) needed when using -P (--only-positive-strand)"))
 		}
+		if optical != nil && !bySeq {
+			checkError(fmt.Errorf("flag -s (--by-seq) needed when using --optical-dist"))
+		}
 
 		files := getFileListFromArgsAndFile(cmd, args, true, "infile-list", !config.SkipFileCheck)
 		if !config.SkipFileCheck {
@@ -98,6 +201,15 @@ Attention:
 			defer outfhDup.Close()
 		}
 
+		if maxMem > 0 {
+			removed := rmdupOnDisk(files, config, bySeq, byName, ignoreCase, revcom,
+				outfh, outfhDup, numFile, maxMem, tmpDir)
+			if !quiet {
+				log.Infof("%d duplicated records removed", removed)
+			}
+			return
+		}
+
 		counter := make(map[uint64]int)
 		names := make(map[uint64][]string)
 
@@ -158,6 +270,9 @@ Attention:
 					if saveNumFile {
 						names[subject] = append(names[subject], string(record.ID))
 					}
+					if optical != nil {
+						checkError(optical.Add(subject, record.ID, false))
+					}
 
 					continue
 				}
@@ -178,6 +293,9 @@ Attention:
 						if saveNumFile {
 							names[subject] = append(names[subject], string(record.ID))
 						}
+						if optical != nil {
+							checkError(optical.Add(subject, record.ID, false))
+						}
 						continue
 					}
 				}
@@ -188,6 +306,9 @@ Attention:
 				if saveNumFile {
 					names[subject] = []string{string(record.ID)}
 				}
+				if optical != nil {
+					checkError(optical.Add(subject, record.ID, true))
+				}
 			}
 			fastxReader.Close()
 
@@ -216,6 +337,9 @@ Attention:
 		if !quiet {
 			log.Infof("%d duplicated records removed", removed)
 		}
+		if optical != nil {
+			reportOpticalDups(optical, opticalReport, quiet)
+		}
 	},
 }
 
@@ -229,6 +353,164 @@ func init() {
 	rmdupCmd.Flags().StringP("dup-num-file", "D", "", "file to save numbers and ID lists of duplicated seqs")
 	// rmdupCmd.Flags().BoolP("consider-revcom", "r", false, "considering the reverse compelment sequence")
 	rmdupCmd.Flags().BoolP("only-positive-strand", "P", false, "only considering positive strand when comparing by sequence")
+	rmdupCmd.Flags().StringP("max-mem", "", "", `maximum memory for storing hash values, supported units: K, M, G. Hash values are sorted on disk when exceeded`)
+	rmdupCmd.Flags().StringP("tmp-dir", "", "./", "temporary directory for saving sorted hash values and data from stdin when using --max-mem")
+
+	rmdupCmd.Flags().StringP("read1", "1", "", "(gzipped) read1 file of paired-end reads")
+	rmdupCmd.Flags().StringP("read2", "2", "", "(gzipped) read2 file of paired-end reads")
+	rmdupCmd.Flags().StringP("out-dir", "O", "", "output directory for paired-end reads (default value is the directory of read1)")
+	rmdupCmd.Flags().IntP("first-bases", "N", 0, "only compare the first N bases of each mate of paired-end reads, 0 for whole reads")
+	rmdupCmd.Flags().BoolP("keep-best-qual", "Q", false, "keep the read pair with the highest average quality instead of the first one")
+	rmdupCmd.Flags().IntP("qual-ascii-base", "b", 33, "ASCII BASE, 33 for Phred+33")
+
+	rmdupCmd.Flags().IntP("optical-dist", "", 0, "max pixel distance of optical duplicates on the same tile, 0 for disabling. Recommended: 100 for non-patterned flowcells, 2500 for patterned flowcells")
+	rmdupCmd.Flags().StringP("optical-report", "", "", "file to save optical and library duplication rates of each tile and lane")
+}
+
+// rmdupHash computes the hash value of a record for comparison.
+// When comparing sequences on both strands, the smaller one of hash values
+// of the sequence and its reverse complement sequence is returned.
+func rmdupHash(record *fastx.Record, bySeq, byName, ignoreCase, revcom bool) uint64 {
+	var s []byte
+	if bySeq {
+		s = record.Seq.Seq
+	} else if byName {
+		s = record.Name
+	} else {
+		s = record.ID
+	}
+	if ignoreCase {
+		s = bytes.ToLower(s)
+	}
+	h := xxhash.Sum64(s)
+	if bySeq && revcom {
+		s = record.Seq.RevCom().Seq
+		if ignoreCase {
+			s = bytes.ToLower(s)
+		}
+		if hRC := xxhash.Sum64(s); hRC < h {
+			h = hRC
+		}
+	}
+	return h
+}
+
+// rmdupOnDisk removes duplicated records with hash values sorted on disk.
+// Records are read twice: the first pass computes and sorts hash values,
+// and the second pass outputs records not marked as duplicates.
+func rmdupOnDisk(files []string, config Config, bySeq, byName, ignoreCase, revcom bool,
+	outfh, outfhDup *xopen.Writer, numFile string, maxMem int64, tmpDir string) int {
+
+	sorter, err := newHashSorter(tmpDir, maxMem)
+	checkError(err)
+	defer func() {
+		checkError(sorter.Close())
+	}()
+
+	files, err = copyStdinForRereading(files, sorter.Dir(), config.Quiet)
+	checkError(err)
+
+	saveNumFile := numFile != ""
+	var record *fastx.Record
+	var idx uint64
+	var name []byte
+	for _, file := range files {
+		fastxReader, err := fastx.NewReader(config.Alphabet, file, config.IDRegexp)
+		checkError(err)
+		for {
+			record, err = fastxReader.Read()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				checkError(err)
+				break
+			}
+			if saveNumFile {
+				name = record.ID
+			}
+			checkError(sorter.Add(rmdupHash(record, bySeq, byName, ignoreCase, revcom), 0, idx, name))
+			idx++
+		}
+		fastxReader.Close()
+	}
+	if !config.Quiet {
+		log.Infof("%d records read, %d chunks of hash values saved in temporary directory", idx, sorter.Chunks())
+	}
+
+	// only the first record of each group is kept
+	var dups bitSet
+	list := new(listOfStringSlice)
+	checkError(sorter.Groups(func(group []hashEntry) error {
+		if len(group) == 1 {
+			return nil
+		}
+		for _, e := range group[1:] {
+			dups.Set(e.Index)
+		}
+		if saveNumFile {
+			l := make([]string, len(group))
+			for i, e := range group {
+				l[i] = string(e.Name)
+			}
+			list.data = append(list.data, l)
+		}
+		return nil
+	}))
+
+	lineWidth := config.LineWidth
+	var removed int
+	idx = 0
+	for _, file := range files {
+		fastxReader, err := fastx.NewReader(config.Alphabet, file, config.IDRegexp)
+		checkError(err)
+		checkAlphabet := true
+		for {
+			record, err = fastxReader.Read()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				checkError(err)
+				break
+			}
+			if checkAlphabet {
+				if fastxReader.IsFastq {
+					if !config.LineWidthChanged {
+						config.LineWidth = 0
+					}
+					fastx.ForcelyOutputFastq = true
+				}
+				checkAlphabet = false
+			}
+
+			if dups.Has(idx) {
+				removed++
+				if outfhDup != nil {
+					outfhDup.Write(record.Format(config.LineWidth))
+				}
+			} else {
+				record.FormatToWriter(outfh, config.LineWidth)
+			}
+			idx++
+		}
+		fastxReader.Close()
+
+		config.LineWidth = lineWidth
+	}
+
+	if saveNumFile {
+		outfhNum, err := xopen.Wopen(numFile)
+		checkError(err)
+		defer outfhNum.Close()
+
+		sort.Sort(list)
+		for _, l := range list.data {
+			outfhNum.WriteString(fmt.Sprintf("%d\t%s\n", len(l), strings.Join(l, ", ")))
+		}
+	}
+
+	return removed
 }
 
 type listOfStringSlice struct {
@@ -238,3 +520,244 @@ type listOfStringSlice struct {
 func (l listOfStringSlice) Len() int           { return len(l.data) }
 func (l listOfStringSlice) Less(i, j int) bool { return len(l.data[i]) > len(l.data[j]) }
 func (l listOfStringSlice) Swap(i, j int)      { l.data[i], l.data[j] = l.data[j], l.data[i] }
+
+type rmdupPairedOptions struct {
+	read1, read2 string
+	outDir       string
+	firstBases   int
+	keepBest     bool
+	qBase        int
+	ignoreCase   bool
+	revcom       bool
+	dupFile      string
+	numFile      string
+
+	optical *OpticalDupCounter
+}
+
+// rmdupPairHash computes the hash value of a read pair from sequences of
+// both mates. When revcom is true, the smaller one of hash values of R1+R2
+// and R2+R1 is returned, as the two pairs come from the same fragment.
+func rmdupPairHash(r1, r2 *fastx.Record, opt *rmdupPairedOptions) uint64 {
+	s1, s2 := r1.Seq.Seq, r2.Seq.Seq
+	if opt.firstBases > 0 {
+		if len(s1) > opt.firstBases {
+			s1 = s1[:opt.firstBases]
+		}
+		if len(s2) > opt.firstBases {
+			s2 = s2[:opt.firstBases]
+		}
+	}
+	if opt.ignoreCase {
+		s1, s2 = bytes.ToLower(s1), bytes.ToLower(s2)
+	}
+
+	d := xxhash.New()
+	d.Write(s1)
+	d.Write(_mark_newline)
+	d.Write(s2)
+	h := d.Sum64()
+	if !opt.revcom {
+		return h
+	}
+
+	d.Reset()
+	d.Write(s2)
+	d.Write(_mark_newline)
+	d.Write(s1)
+	if h2 := d.Sum64(); h2 < h {
+		h = h2
+	}
+	return h
+}
+
+// rmdupPairQual returns the average quality of a read pair.
+func rmdupPairQual(r1, r2 *fastx.Record, qBase int) float64 {
+	var sum float64
+	var n, v int
+	for _, q := range [][]byte{r1.Seq.Qual, r2.Seq.Qual} {
+		for _, b := range q {
+			if v = int(b) - qBase; v < 0 {
+				v = 0
+			}
+			sum += seq.QUAL_MAP[v]
+		}
+		n += len(q)
+	}
+	if n == 0 {
+		return 0
+	}
+	return -10 * math.Log10(sum/float64(n))
+}
+
+// rmdupReadPairs reads read pairs from two files, and calls fn for each pair.
+func rmdupReadPairs(config Config, read1, read2 string, fn func(r1, r2 *fastx.Record, isFastq bool)) {
+	reader1, err := fastx.NewReader(config.Alphabet, read1, config.IDRegexp)
+	checkError(errors.Wrap(err, read1))
+	defer reader1.Close()
+	reader2, err := fastx.NewReader(config.Alphabet, read2, config.IDRegexp)
+	checkError(errors.Wrap(err, read2))
+	defer reader2.Close()
+
+	var r1, r2 *fastx.Record
+	var err1, err2 error
+	for {
+		r1, err1 = reader1.Read()
+		r2, err2 = reader2.Read()
+		if err1 == io.EOF && err2 == io.EOF {
+			break
+		}
+		if err1 == io.EOF || err2 == io.EOF {
+			checkError(fmt.Errorf("unequal numbers of reads in %s and %s, please use 'seqkit pair' first", read1, read2))
+		}
+		checkError(errors.Wrap(err1, read1))
+		checkError(errors.Wrap(err2, read2))
+
+		if !bytes.Equal(r1.ID, r2.ID) {
+			checkError(fmt.Errorf("unpaired reads: %s and %s, please use 'seqkit pair' first", r1.ID, r2.ID))
+		}
+		fn(r1, r2, reader1.IsFastq)
+	}
+}
+
+// rmdupPaired removes duplicated read pairs.
+func rmdupPaired(config Config, opt *rmdupPairedOptions) {
+	lineWidth := config.LineWidth
+
+	// output files
+	outDir := opt.outDir
+	addSuffix := outDir == ""
+	if addSuffix {
+		outDir = filepath.Dir(opt.read1)
+	} else {
+		checkError(os.MkdirAll(outDir, 0755))
+	}
+	outFiles := make([]string, 2)
+	for i, file := range []string{opt.read1, opt.read2} {
+		if addSuffix {
+			base, suffix := filepathTrimExtension(filepath.Base(file))
+			outFiles[i] = filepath.Join(outDir, base+".rmdup"+suffix)
+		} else {
+			outFiles[i] = filepath.Join(outDir, filepath.Base(file))
+		}
+		if !config.SkipFileCheck {
+			checkIfFilesAreTheSame(opt.read1, outFiles[i], "input", "output")
+			checkIfFilesAreTheSame(opt.read2, outFiles[i], "input", "output")
+		}
+	}
+
+	outfh1, err := xopen.Wopen(outFiles[0])
+	checkError(errors.Wrap(err, outFiles[0]))
+	defer outfh1.Close()
+	outfh2, err := xopen.Wopen(outFiles[1])
+	checkError(errors.Wrap(err, outFiles[1]))
+	defer outfh2.Close()
+
+	var outfhDup1, outfhDup2 *xopen.Writer
+	if opt.dupFile != "" {
+		base, suffix := filepathTrimExtension(opt.dupFile)
+		outfhDup1, err = xopen.Wopen(base + "_1" + suffix)
+		checkError(err)
+		defer outfhDup1.Close()
+		outfhDup2, err = xopen.Wopen(base + "_2" + suffix)
+		checkError(err)
+		defer outfhDup2.Close()
+	}
+
+	saveNumFile := opt.numFile != ""
+	names := make(map[uint64][]string)
+
+	// for -Q/--keep-best-qual: hash -> index and quality of the best pair
+	type bestPair struct {
+		idx  uint64
+		qual float64
+	}
+	var best map[uint64]*bestPair
+	if opt.keepBest {
+		best = make(map[uint64]*bestPair)
+
+		var idx uint64
+		var h uint64
+		var q float64
+		var b *bestPair
+		var ok bool
+		rmdupReadPairs(config, opt.read1, opt.read2, func(r1, r2 *fastx.Record, isFastq bool) {
+			h = rmdupPairHash(r1, r2, opt)
+			q = rmdupPairQual(r1, r2, opt.qBase)
+			if b, ok = best[h]; !ok {
+				best[h] = &bestPair{idx: idx, qual: q}
+			} else if q > b.qual {
+				b.idx, b.qual = idx, q
+			}
+			idx++
+		})
+	}
+
+	counter := make(map[uint64]struct{})
+	var idx, n, removed uint64
+	var h uint64
+	var dup, ok bool
+	checkFormat := true
+	rmdupReadPairs(config, opt.read1, opt.read2, func(r1, r2 *fastx.Record, isFastq bool) {
+		if checkFormat {
+			if isFastq {
+				if !config.LineWidthChanged {
+					lineWidth = 0
+				}
+				fastx.ForcelyOutputFastq = true
+			}
+			checkFormat = false
+		}
+		n++
+
+		h = rmdupPairHash(r1, r2, opt)
+		if opt.keepBest {
+			dup = best[h].idx != idx
+		} else if _, ok = counter[h]; ok {
+			dup = true
+		} else {
+			dup = false
+			counter[h] = struct{}{}
+		}
+		idx++
+
+		if saveNumFile {
+			names[h] = append(names[h], string(r1.ID))
+		}
+		if opt.optical != nil {
+			checkError(opt.optical.Add(h, r1.ID, !dup))
+		}
+
+		if dup {
+			removed++
+			if outfhDup1 != nil {
+				r1.FormatToWriter(outfhDup1, lineWidth)
+				r2.FormatToWriter(outfhDup2, lineWidth)
+			}
+			return
+		}
+		r1.FormatToWriter(outfh1, lineWidth)
+		r2.FormatToWriter(outfh2, lineWidth)
+	})
+
+	if saveNumFile {
+		outfhNum, err := xopen.Wopen(opt.numFile)
+		checkError(err)
+		defer outfhNum.Close()
+
+		list := new(listOfStringSlice)
+		for _, l := range names {
+			if len(l) > 1 {
+				list.data = append(list.data, l)
+			}
+		}
+		sort.Sort(list)
+		for _, l := range list.data {
+			outfhNum.WriteString(fmt.Sprintf("%d\t%s\n", len(l), strings.Join(l, ", ")))
+		}
+	}
+
+	if !config.Quiet {
+		log.Infof("%d duplicated read pairs removed, %d read pairs saved to %s and %s", removed, n-removed, outFiles[0], outFiles[1])
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/shenwei356/xopen"
+)
+
+// IlluminaLocation is the location of a cluster on an Illumina flowcell.
+type IlluminaLocation struct {
+	Lane, Tile int
+	X, Y       int
+}
+
+// ParseIlluminaLocation parses lane, tile, and x/y coordinates from an
+// Illumina read ID, in the format of
+//
+//	instrument:run:flowcell:lane:tile:x:y (Casava 1.8+), or
+//	instrument:lane:tile:x:y[#index/read] (older versions).
+func ParseIlluminaLocation(id []byte) (IlluminaLocation, error) {
+	var loc IlluminaLocation
+	fields := bytes.Split(id, []byte{':'})
+	var items [][]byte
+	switch {
+	case len(fields) >= 7:
+		items = fields[3:7]
+	case len(fields) == 5:
+		items = fields[1:5]
+		if i := bytes.IndexAny(items[3], "#/"); i >= 0 {
+			items[3] = items[3][:i]
+		}
+	default:
+		return loc, fmt.Errorf("failed to parse tile and coordinates from the read ID: %s", id)
+	}
+
+	var values [4]int
+	var err error
+	for i, item := range items {
+		values[i], err = strconv.Atoi(string(item))
+		if err != nil {
+			return loc, fmt.Errorf("failed to parse tile and coordinates from the read ID: %s", id)
+		}
+	}
+	loc.Lane, loc.Tile, loc.X, loc.Y = values[0], values[1], values[2], values[3]
+	return loc, nil
+}
+
+type opticalRead struct {
+	IlluminaLocation
+	idx  int  // index in the group
+	kept bool // the read is kept, i.e., not a duplicate
+}
+
+// OpticalDupStats is the duplication statistics of a tile.
+type OpticalDupStats struct {
+	Lane, Tile int
+	Reads      int
+	Dups       int // all duplicates
+	Optical    int // optical duplicates
+}
+
+// OpticalDupCounter classifies duplicates into optical duplicates and
+// library (PCR) duplicates. Reads in a group of duplicates, which are on the
+// same tile and within a pixel distance in both x and y, form a cluster, and
+// all but one read of a cluster are counted as optical duplicates. The kept
+// read is chosen as the representative of its cluster if possible.
+type OpticalDupCounter struct {
+	Dist int
+
+	groups map[uint64][]*opticalRead
+	stats  map[[2]int]*OpticalDupStats
+}
+
+// NewOpticalDupCounter creates a OpticalDupCounter with a pixel distance.
+func NewOpticalDupCounter(dist int) *OpticalDupCounter {
+	return &OpticalDupCounter{
+		Dist:   dist,
+		groups: make(map[uint64][]*opticalRead, 1024),
+		stats:  make(map[[2]int]*OpticalDupStats, 128),
+	}
+}
+
+// Add adds a read with the hash value of the sequence and whether it is kept.
+func (o *OpticalDupCounter) Add(h uint64, id []byte, kept bool) error {
+	loc, err := ParseIlluminaLocation(id)
+	if err != nil {
+		return err
+	}
+	key := [2]int{loc.Lane, loc.Tile}
+	s, ok := o.stats[key]
+	if !ok {
+		s = &OpticalDupStats{Lane: loc.Lane, Tile: loc.Tile}
+		o.stats[key] = s
+	}
+	s.Reads++
+	if !kept {
+		s.Dups++
+	}
+
+	g := o.groups[h]
+	o.groups[h] = append(g, &opticalRead{IlluminaLocation: loc, idx: len(g), kept: kept})
+	return nil
+}
+
+// Stats computes and returns statistics of all tiles, sorted by lane and tile.
+func (o *OpticalDupCounter) Stats() []*OpticalDupStats {
+	var parent []int
+	var find func(i int) int
+	find = func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+
+	var a, b *opticalRead
+	var i, j, ri, rj int
+	var rep map[int]*opticalRead
+	for _, g := range o.groups {
+		if len(g) == 1 {
+			continue
+		}
+
+		// clusters of nearby reads, with a sweep along x on each tile
+		sort.Slice(g, func(i, j int) bool {
+			if g[i].Lane != g[j].Lane {
+				return g[i].Lane < g[j].Lane
+			}
+			if g[i].Tile != g[j].Tile {
+				return g[i].Tile < g[j].Tile
+			}
+			return g[i].X < g[j].X
+		})
+		parent = parent[:0]
+		for i = range g {
+			parent = append(parent, i)
+		}
+		for i, a = range g {
+			for j = i + 1; j < len(g); j++ {
+				b = g[j]
+				if b.Lane != a.Lane || b.Tile != a.Tile || b.X-a.X > o.Dist {
+					break
+				}
+				if abs(b.Y-a.Y) <= o.Dist {
+					if ri, rj = find(i), find(j); ri != rj {
+						parent[ri] = rj
+					}
+				}
+			}
+		}
+
+		// one representative for each cluster: the kept read or the first one
+		rep = make(map[int]*opticalRead, len(g))
+		for i, a = range g {
+			ri = find(i)
+			if b = rep[ri]; b == nil || (a.kept && !b.kept) || (a.kept == b.kept && a.idx < b.idx) {
+				rep[ri] = a
+			}
+		}
+		for i, a = range g {
+			if rep[find(i)] != a {
+				o.stats[[2]int{a.Lane, a.Tile}].Optical++
+			}
+		}
+	}
+
+	list := make([]*OpticalDupStats, 0, len(o.stats))
+	for _, s := range o.stats {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Lane != list[j].Lane {
+			return list[i].Lane < list[j].Lane
+		}
+		return list[i].Tile < list[j].Tile
+	})
+	return list
+}
+
+// WriteOpticalDupReport writes the statistics of each tile, each lane,
+// and all reads to a file, and returns the total statistics.
+func WriteOpticalDupReport(file string, list []*OpticalDupStats) (*OpticalDupStats, error) {
+	total := &OpticalDupStats{}
+	lanes := make(map[int]*OpticalDupStats, 8)
+	var l *OpticalDupStats
+	var ok bool
+	for _, s := range list {
+		if l, ok = lanes[s.Lane]; !ok {
+			l = &OpticalDupStats{Lane: s.Lane}
+			lanes[s.Lane] = l
+		}
+		for _, t := range []*OpticalDupStats{l, total} {
+			t.Reads += s.Reads
+			t.Dups += s.Dups
+			t.Optical += s.Optical
+		}
+	}
+	if file == "" {
+		return total, nil
+	}
+
+	outfh, err := xopen.Wopen(file)
+	if err != nil {
+		return nil, err
+	}
+	defer outfh.Close()
+
+	rate := func(n, d int) float64 {
+		if d == 0 {
+			return 0
+		}
+		return float64(n) / float64(d)
+	}
+	write := func(lane, tile string, s *OpticalDupStats) {
+		fmt.Fprintf(outfh, "%s\t%s\t%d\t%d\t%d\t%d\t%.6f\t%.6f\t%.6f\n",
+			lane, tile, s.Reads, s.Dups, s.Optical, s.Dups-s.Optical,
+			rate(s.Dups, s.Reads), rate(s.Optical, s.Reads), rate(s.Dups-s.Optical, s.Reads))
+	}
+
+	outfh.WriteString("lane\ttile\treads\tdups\toptical_dups\tlibrary_dups\tdup_rate\toptical_dup_rate\tlibrary_dup_rate\n")
+	for _, s := range list {
+		write(strconv.Itoa(s.Lane), strconv.Itoa(s.Tile), s)
+	}
+	laneIDs := make([]int, 0, len(lanes))
+	for lane := range lanes {
+		laneIDs = append(laneIDs, lane)
+	}
+	sort.Ints(laneIDs)
+	for _, lane := range laneIDs {
+		write(strconv.Itoa(lane), "all", lanes[lane])
+	}
+	write("all", "all", total)
+	return total, nil
+}
+
+// reportOpticalDups writes the report of optical duplicates and logs the summary.
+func reportOpticalDups(o *OpticalDupCounter, file string, quiet bool) {
+	total, err := WriteOpticalDupReport(file, o.Stats())
+	checkError(err)
+	if quiet {
+		return
+	}
+	var rate float64
+	if total.Reads > 0 {
+		rate = float64(total.Optical) / float64(total.Reads) * 100
+	}
+	log.Infof("%d optical duplicates (%.2f%%) and %d library duplicates in %d reads (pixel distance: %d)",
+		total.Optical, rate, total.Dups-total.Optical, total.Reads, o.Dist)
+	if file != "" {
+		log.Infof("duplication rates of each tile saved to: %s", file)
+	}
+}
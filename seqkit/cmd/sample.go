@@ -43,12 +43,30 @@ var sampleCmd = &cobra.Command{
 
 'seqkit sample2' is more accurate and memory efficient.
 
+Reservoir sampling (-R/--reservoir):
+  It returns exactly N records (or all records if there are fewer) in one
+  pass, holding only N records in memory, and stdin is supported.
+  Sampled records are outputted in their input order.
+
+  Weighted reservoir sampling (-W/--weight-by, Efraimidis-Spirakis A-Res)
+  is equivalent to choosing records one by one without replacement, each
+  time with probabilities proportional to the weights of the remaining
+  records. So records with bigger weights are more likely to be chosen,
+  but the final inclusion probabilities are not proportional to the
+  weights when N > 1. Weights:
+    length    sequence length, favouring long reads.
+    qual      average quality (FASTQ only).
+
 Attention:
 1. Do not use '-n' on large FASTQ files, it loads all seqs into memory!
-   use 'seqkit sample -p 0.1 seqs.fq.gz | seqkit head -n N' instead!
+   use 'seqkit sample -p 0.1 seqs.fq.gz | seqkit head -n N' instead,
+   or use the reservoir sampling mode (-R/--reservoir).
 2. By default, the output is deterministic; that is, given the same input and random seed,
    seqkit shuf will always generate identical results across different runs.
    For 'true randomness', please add '-r/--non-deterministic', which uses a time-based seed.
+3. For paired-end reads, reservoir sampling with the same seed chooses the same
+   read pairs, while weighted reservoir sampling does not, as weights of
+   the two mates are different.
 
 `,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -83,6 +101,28 @@ Attention:
 		twoPass := getFlagBool(cmd, "two-pass")
 		number := getFlagInt64(cmd, "number")
 		proportion := getFlagFloat64(cmd, "proportion")
+		reservoir := getFlagBool(cmd, "reservoir")
+		weightBy := getFlagString(cmd, "weight-by")
+		qBase := getFlagPositiveInt(cmd, "qual-ascii-base")
+
+		switch weightBy {
+		case "":
+		case "length", "qual":
+			reservoir = true
+		default:
+			checkError(fmt.Errorf("invalid value of -W/--weight-by: %s. available: length, qual", weightBy))
+		}
+		if reservoir {
+			if number <= 0 {
+				checkError(fmt.Errorf("flag -n (--number) needed in the reservoir sampling mode"))
+			}
+			if proportion > 0 {
+				checkError(fmt.Errorf("flag -p (--proportion) is not allowed in the reservoir sampling mode"))
+			}
+			if twoPass {
+				checkError(fmt.Errorf("flag -2 (--two-pass) is not needed in the reservoir sampling mode"))
+			}
+		}
 
 		file := files[0]
 
@@ -114,7 +154,16 @@ Attention:
 
 		n := int64(0)
 		var record *fastx.Record
-		if number > 0 { // by number
+		if reservoir {
+			if !quiet {
+				if weightBy != "" {
+					log.Infof("sample by number with weighted reservoir sampling (weight: %s)", weightBy)
+				} else {
+					log.Info("sample by number with reservoir sampling")
+				}
+			}
+			n = sampleByReservoir(file, config, number, weightBy, qBase, _rand, outfh)
+		} else if number > 0 { // by number
 			if !quiet {
 				log.Info("sample by number")
 			}
@@ -251,4 +300,7 @@ func init() {
 	sampleCmd.Flags().Int64P("number", "n", 0, "sample by number (result may not exactly match), DO NOT use on large FASTQ files.")
 	sampleCmd.Flags().Float64P("proportion", "p", 0, "sample by proportion")
 	sampleCmd.Flags().BoolP("two-pass", "2", false, "2-pass mode read files twice to lower memory usage. Not allowed when reading from stdin")
+	sampleCmd.Flags().BoolP("reservoir", "R", false, "reservoir sampling: return exactly N records (-n) in one pass, holding only N records in memory. stdin supported")
+	sampleCmd.Flags().StringP("weight-by", "W", "", `weighted reservoir sampling by "length" or "qual" (average quality), implies -R/--reservoir`)
+	sampleCmd.Flags().IntP("qual-ascii-base", "", 33, "ASCII BASE, 33 for Phred+33, for weighting by quality")
 }
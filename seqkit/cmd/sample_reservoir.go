@@ -0,0 +1,145 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/shenwei356/bio/seqio/fastx"
+	"github.com/shenwei356/xopen"
+)
+
+type reservoirItem struct {
+	idx    int64   // index of the record in input
+	key    float64 // key of weighted sampling
+	record *fastx.Record
+}
+
+// reservoirHeap is a min-heap of keys, the item with the smallest key
+// is replaced first.
+type reservoirHeap []*reservoirItem
+
+func (h reservoirHeap) Len() int           { return len(h) }
+func (h reservoirHeap) Less(i, j int) bool { return h[i].key < h[j].key }
+func (h reservoirHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *reservoirHeap) Push(x any)        { *h = append(*h, x.(*reservoirItem)) }
+func (h *reservoirHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// sampleWeight returns the weight of a record for weighted sampling.
+func sampleWeight(record *fastx.Record, weightBy string, qBase int) float64 {
+	switch weightBy {
+	case "length":
+		return float64(len(record.Seq.Seq))
+	case "qual":
+		if len(record.Seq.Qual) == 0 {
+			checkError(fmt.Errorf("weighting by quality only supports FASTQ format"))
+		}
+		return record.Seq.AvgQual(qBase)
+	}
+	return 1
+}
+
+// sampleByReservoir samples exactly number records (or all records if
+// there are fewer) in one pass, holding at most number records in memory.
+// Without weightBy, it uses Algorithm R where every record has the same
+// probability. Otherwise, it uses Algorithm A-Res (Efraimidis and Spirakis,
+// 2006), which is equivalent to choosing records one by one without
+// replacement, each time with probabilities proportional to the weights
+// of the remaining records, i.e., the sequence length or the average quality.
+// Note that the inclusion probabilities are not proportional to the weights
+// when number > 1.
+// Sampled records are written in their input order.
+func sampleByReservoir(file string, config Config, number int64, weightBy string, qBase int,
+	_rand *rand.Rand, outfh *xopen.Writer) int64 {
+
+	fastxReader, err := fastx.NewReader(config.Alphabet, file, config.IDRegexp)
+	checkError(err)
+	defer fastxReader.Close()
+
+	reservoir := make(reservoirHeap, 0, min(number, 1<<20))
+	var record *fastx.Record
+	var i, j int64
+	var w, key float64
+	checkAlphabet := true
+	for i = 0; ; i++ {
+		record, err = fastxReader.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			checkError(err)
+			break
+		}
+
+		if checkAlphabet {
+			if fastxReader.IsFastq {
+				if !config.LineWidthChanged {
+					config.LineWidth = 0
+				}
+				fastx.ForcelyOutputFastq = true
+			}
+			checkAlphabet = false
+		}
+
+		if weightBy == "" { // Algorithm R
+			if i < number {
+				reservoir = append(reservoir, &reservoirItem{idx: i, record: record.Clone()})
+				continue
+			}
+			if j = _rand.Int63n(i + 1); j < number {
+				reservoir[j] = &reservoirItem{idx: i, record: record.Clone()}
+			}
+			continue
+		}
+
+		// Algorithm A-Res, with keys of log(u)/w, which keep the same order as u^(1/w)
+		w = sampleWeight(record, weightBy, qBase)
+		if w <= 0 { // never chosen
+			continue
+		}
+		key = math.Log(1-_rand.Float64()) / w
+		if int64(len(reservoir)) < number {
+			heap.Push(&reservoir, &reservoirItem{idx: i, key: key, record: record.Clone()})
+			continue
+		}
+		if key > reservoir[0].key {
+			reservoir[0] = &reservoirItem{idx: i, key: key, record: record.Clone()}
+			heap.Fix(&reservoir, 0)
+		}
+	}
+
+	sort.Slice(reservoir, func(i, j int) bool { return reservoir[i].idx < reservoir[j].idx })
+	for _, item := range reservoir {
+		item.record.FormatToWriter(outfh, config.LineWidth)
+	}
+	return int64(len(reservoir))
+}
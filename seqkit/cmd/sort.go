@@ -24,6 +24,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"regexp"
 	"runtime"
@@ -57,9 +58,45 @@ seqkit will write the sequences to temporary files, and create FASTA index.
 Secondly, seqkit sorts sequence by head and length information
 and extracts sequences by FASTA index.
 
+For FASTQ files or huge files which can not fit in memory, use the external
+merge sort mode via --max-mem. Records are buffered in memory until the
+limit is reached, and then they are sorted and written to a LZ4-compressed
+temporary file (sorted run) in --tmp-dir. At last, all runs are merged.
+
+Sorting by multiple keys (-K/--key):
+  Keys are given in the format of "field[:type][:order],...", e.g.,
+  "size:num:desc,id:natural", which sorts records by the header field
+  "size" in descending numeric order, and then by IDs in natural order.
+
+  Fields:
+    id        sequence ID
+    name      full name
+    seq       sequence
+    length    sequence length (numeric)
+    bases     number of non-gap bases (numeric)
+    gc        GC content (numeric)
+    qual      average quality (numeric), FASTQ only
+    others    header fields extracted by regular expressions given via
+              -R/--key-regexp, in the format of "field:regexp", with the
+              value captured by the first capture group. By default,
+              "field=value" (separated by spaces, ';', or '|') are matched,
+              e.g., "size=123" in "uniq1;size=123", or "start_time=2024-01-01T00:00:00Z".
+  Types:  str (default), num, natural.
+  Orders: asc (default), desc.
+
+  Records missing a field are placed at the end. Records with all keys
+  being the same keep their input order, and duplicated IDs are allowed.
+
 Attention:
   1. For the two-pass mode (-2/--two-pass), The flag -U/--update-faidx is recommended to
      ensure the .fai file matches the FASTA file.
+  2. In the external merge sort mode (--max-mem):
+     a) Both FASTA and FASTQ formats, and stdin, are supported.
+     b) The sorting is stable, i.e., records with the same key keep their
+        input order, and duplicated IDs are allowed.
+     c) The memory limit only counts the buffered records, the actual memory
+        usage is a little higher.
+     d) It can also be used with -K/--key.
 
 `,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -122,6 +159,64 @@ Attention:
 			checkError(fmt.Errorf("only one of the flags -l (--by-length), -n (--by-name) and -s (--by-seq) is allowed"))
 		}
 
+		keySpec := getFlagString(cmd, "key")
+		keyRegexps := getFlagStringArray(cmd, "key-regexp")
+		qBase := getFlagPositiveInt(cmd, "qual-ascii-base")
+		var keys []*sortKey
+		var err error
+		if keySpec != "" {
+			if n > 0 || inNaturalOrder {
+				checkError(fmt.Errorf("flag -K/--key is incompatible with -l/--by-length, -b/--by-bases, -n/--by-name, -s/--by-seq, and -N/--natural-order"))
+			}
+			if twoPass {
+				checkError(fmt.Errorf("flag -K/--key is incompatible with -2/--two-pass"))
+			}
+			keys, err = parseSortKeys(keySpec, keyRegexps)
+			checkError(err)
+		} else if len(keyRegexps) > 0 {
+			checkError(fmt.Errorf("flag -R/--key-regexp should be used along with -K/--key"))
+		}
+
+		maxMemS := getFlagString(cmd, "max-mem")
+		tmpDir := getFlagString(cmd, "tmp-dir")
+		if maxMemS != "" || keys != nil {
+			if twoPass {
+				checkError(fmt.Errorf("flag --max-mem is incompatible with -2/--two-pass"))
+			}
+			var maxMem int64 = math.MaxInt64 // sorting in memory
+			if maxMemS != "" {
+				maxMem, err = ParseByteSize(maxMemS)
+				if err != nil || maxMem <= 0 {
+					checkError(fmt.Errorf("invalid value of flag --max-mem: %s", maxMemS))
+				}
+			}
+
+			outfh, err := xopen.Wopen(outFile)
+			checkError(err)
+			defer outfh.Close()
+
+			err = sortExternal(files, config, &externalSortOptions{
+				byName:          byName,
+				bySeq:           bySeq,
+				byLength:        byLength,
+				byBases:         byBases,
+				gapLetters:      gapLetters,
+				seqPrefixLength: seqPrefixLength,
+				reverse:         reverse,
+				ignoreCase:      ignoreCase,
+				naturalOrder:    inNaturalOrder,
+				keys:            keys,
+				qBase:           qBase,
+				maxMem:          maxMem,
+				tmpDir:          tmpDir,
+			}, outfh)
+			if err != nil {
+				outfh.Close()
+				checkError(err)
+			}
+			return
+		}
+
 		byID := true
 		if bySeq || byLength {
 			byID = false
@@ -141,7 +236,6 @@ Attention:
 		// for indexing when output and duplicated sequences checking
 		id2name := make(map[string][]byte)
 		var record *fastx.Record
-		var err error
 
 		if !twoPass { // read all records into memory
 			sequences := make(map[string]*fastx.Record)
@@ -506,4 +600,9 @@ func init() {
 	sortCmd.Flags().BoolP("update-faidx", "U", false, "update the fasta index file if it exists. Use this if you are not sure whether the fasta file changed")
 	sortCmd.Flags().BoolP("keep-temp", "k", false, "keep temporary FASTA and .fai file when using 2-pass mode")
 	sortCmd.Flags().IntP("seq-prefix-length", "L", 10000, "length of sequence prefix on which seqkit sorts by sequences (0 for whole sequence)")
+	sortCmd.Flags().StringP("key", "K", "", `sort by multiple keys in the format of "field[:type][:order],...", e.g., "size:num:desc,id:natural". see the usage`)
+	sortCmd.Flags().StringArrayP("key-regexp", "R", []string{}, `regular expression with a capture group for extracting a header field, in the format of "field:regexp", e.g., "size:size=(\d+)". multiple values supported`)
+	sortCmd.Flags().IntP("qual-ascii-base", "", 33, "ASCII BASE, 33 for Phred+33, for the key of average quality")
+	sortCmd.Flags().StringP("max-mem", "", "", `external merge sort mode: maximum memory for buffering records, e.g., 500M, 4G. Sorted runs are saved in --tmp-dir when exceeded. FASTQ and stdin supported`)
+	sortCmd.Flags().StringP("tmp-dir", "", "./", "directory for saving temporary files in the external merge sort mode")
 }
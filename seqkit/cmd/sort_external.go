@@ -0,0 +1,475 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/shenwei356/bio/seq"
+	"github.com/shenwei356/bio/seqio/fastx"
+	"github.com/shenwei356/natsort"
+	"github.com/shenwei356/xopen"
+)
+
+// externalSortOptions contains options of the external merge sort.
+type externalSortOptions struct {
+	byName, bySeq, byLength, byBases bool
+	gapLetters                       string
+	seqPrefixLength                  int // only compare the prefix of sequences, 0 for whole sequences
+	reverse                          bool
+	ignoreCase                       bool
+	naturalOrder                     bool
+
+	keys  []*sortKey // multiple sorting keys, overriding the options above
+	qBase int        // ASCII base of quality, for the key of average quality
+
+	maxMem int64
+	tmpDir string
+}
+
+// externalSortItem is a record with its sorting key.
+type externalSortItem struct {
+	idx    uint64 // index of the record in input, for stable sorting
+	key    string // ID or full name
+	length int
+	values []sortKeyValue // values of multiple sorting keys
+	record *fastx.Record
+}
+
+// estimated memory occupation of an externalSortItem, excluding the record data
+const externalSortItemSize = 160
+
+func (opt *externalSortOptions) newItem(idx uint64, record *fastx.Record) (*externalSortItem, error) {
+	item := &externalSortItem{idx: idx, record: record}
+	if len(opt.keys) > 0 {
+		var err error
+		item.values, err = sortKeyValues(opt.keys, record, opt.ignoreCase, opt.gapLetters, opt.qBase)
+		return item, err
+	}
+	if opt.byName {
+		item.key = string(record.Name)
+	} else {
+		item.key = string(record.ID)
+	}
+	if opt.ignoreCase {
+		item.key = strings.ToLower(item.key)
+	}
+	if opt.byLength {
+		if opt.byBases {
+			item.length = record.Seq.Bases(opt.gapLetters)
+		} else {
+			item.length = len(record.Seq.Seq)
+		}
+	}
+	return item, nil
+}
+
+// less compares two items in the same way as the in-memory mode, and
+// items with the same key are kept in input order.
+func (opt *externalSortOptions) less(a, b *externalSortItem) bool {
+	var c int
+	switch {
+	case len(opt.keys) > 0:
+		c = compareSortKeys(opt.keys, a.values, b.values)
+	case opt.bySeq:
+		sa, sb := opt.seqPrefix(a.record.Seq.Seq), opt.seqPrefix(b.record.Seq.Seq)
+		if opt.ignoreCase {
+			c = compareIgnoreCase(sa, sb)
+		} else {
+			c = bytes.Compare(sa, sb)
+		}
+		if opt.reverse {
+			c = -c
+		}
+	case opt.byLength:
+		if a.length < b.length {
+			c = -1
+		} else if a.length > b.length {
+			c = 1
+		}
+		if opt.reverse {
+			c = -c
+		}
+		if c == 0 { // IDs are always in ascending order, the same as the in-memory mode
+			c = strings.Compare(a.key, b.key)
+		}
+	default:
+		if opt.naturalOrder {
+			if natsort.Compare(a.key, b.key, false) {
+				c = -1
+			} else if natsort.Compare(b.key, a.key, false) {
+				c = 1
+			}
+		} else {
+			c = strings.Compare(a.key, b.key)
+		}
+		if opt.reverse {
+			c = -c
+		}
+	}
+	if c != 0 {
+		return c < 0
+	}
+	return a.idx < b.idx
+}
+
+// seqPrefix returns the prefix of a sequence used in sorting by sequences,
+// the same as the in-memory mode.
+func (opt *externalSortOptions) seqPrefix(s []byte) []byte {
+	if opt.seqPrefixLength == 0 || len(s) <= opt.seqPrefixLength {
+		return s
+	}
+	return s[:opt.seqPrefixLength]
+}
+
+// compareIgnoreCase compares two byte slices in lower case.
+func compareIgnoreCase(a, b []byte) int {
+	n := min(len(a), len(b))
+	var x, y byte
+	for i := 0; i < n; i++ {
+		x, y = a[i], b[i]
+		if 'A' <= x && x <= 'Z' {
+			x += 'a' - 'A'
+		}
+		if 'A' <= y && y <= 'Z' {
+			y += 'a' - 'A'
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	if len(a) < len(b) {
+		return -1
+	}
+	if len(a) > len(b) {
+		return 1
+	}
+	return 0
+}
+
+// writeSortRun writes sorted items into a LZ4-compressed temporary file.
+func writeSortRun(file string, items []*externalSortItem) error {
+	outfh, err := xopen.Wopen(file)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		writeSortRunItem(outfh, item)
+	}
+	return outfh.Close()
+}
+
+// writeSortRunItem writes an item into a temporary file.
+func writeSortRunItem(outfh *xopen.Writer, item *externalSortItem) {
+	var buf [8]byte
+	le := binary.LittleEndian
+	le.PutUint64(buf[:], item.idx)
+	outfh.Write(buf[:])
+	for _, data := range [][]byte{item.record.Name, item.record.Seq.Seq, item.record.Seq.Qual} {
+		le.PutUint32(buf[:4], uint32(len(data)))
+		outfh.Write(buf[:4])
+		outfh.Write(data)
+	}
+}
+
+type sortRunReader struct {
+	fh   *xopen.Reader
+	opt  *externalSortOptions
+	idRe *regexp.Regexp
+
+	alphabet *seq.Alphabet
+	item     *externalSortItem
+}
+
+// next reads the next item, it returns io.EOF at the end of the file.
+func (r *sortRunReader) next() error {
+	var buf [8]byte
+	le := binary.LittleEndian
+	if _, err := io.ReadFull(r.fh, buf[:]); err != nil {
+		return err
+	}
+	idx := le.Uint64(buf[:])
+
+	var fields [3][]byte
+	for i := range fields {
+		if _, err := io.ReadFull(r.fh, buf[:4]); err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		fields[i] = make([]byte, le.Uint32(buf[:4]))
+		if _, err := io.ReadFull(r.fh, fields[i]); err != nil {
+			return io.ErrUnexpectedEOF
+		}
+	}
+
+	var s *seq.Seq
+	var err error
+	if len(fields[2]) == 0 { // FASTA
+		s, err = seq.NewSeqWithoutValidation(r.alphabet, fields[1])
+	} else {
+		s, err = seq.NewSeqWithQualWithoutValidation(r.alphabet, fields[1], fields[2])
+	}
+	if err != nil {
+		return err
+	}
+	record := &fastx.Record{
+		ID:   fastx.ParseHeadID(r.idRe, fields[0]),
+		Name: fields[0],
+		Seq:  s,
+	}
+	r.item, err = r.opt.newItem(idx, record)
+	return err
+}
+
+type sortRunHeap struct {
+	readers []*sortRunReader
+	opt     *externalSortOptions
+}
+
+func (h sortRunHeap) Len() int { return len(h.readers) }
+func (h sortRunHeap) Less(i, j int) bool {
+	return h.opt.less(h.readers[i].item, h.readers[j].item)
+}
+func (h sortRunHeap) Swap(i, j int) { h.readers[i], h.readers[j] = h.readers[j], h.readers[i] }
+func (h *sortRunHeap) Push(x any)   { h.readers = append(h.readers, x.(*sortRunReader)) }
+func (h *sortRunHeap) Pop() any {
+	old := h.readers
+	n := len(old)
+	x := old[n-1]
+	h.readers = old[:n-1]
+	return x
+}
+
+// sortExternal sorts records with an external merge sort. Records are
+// read into memory until the memory limit is reached, then they are sorted
+// and written into a temporary file (run). At last, all runs are merged.
+// The temporary directory is always removed before returning.
+func sortExternal(files []string, config Config, opt *externalSortOptions, outfh *xopen.Writer) (err error) {
+	idRe, err := regexp.Compile(config.IDRegexp)
+	if err != nil {
+		return fmt.Errorf("fail to compile regexp: %s", config.IDRegexp)
+	}
+
+	if err = os.MkdirAll(opt.tmpDir, 0755); err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp(opt.tmpDir, "seqkit-sort-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if _err := os.RemoveAll(dir); _err != nil && err == nil {
+			err = _err
+		}
+	}()
+
+	items := make([]*externalSortItem, 0, 1024)
+	sortItems := func() {
+		sort.Slice(items, func(i, j int) bool { return opt.less(items[i], items[j]) })
+	}
+	runs := make([]string, 0, 8)
+
+	var mem int64
+	var idx uint64
+	var record *fastx.Record
+	var alphabet *seq.Alphabet
+	for _, file := range files {
+		fastxReader, err := fastx.NewReader(config.Alphabet, file, config.IDRegexp)
+		if err != nil {
+			return err
+		}
+		checkAlphabet := true
+		for {
+			record, err = fastxReader.Read()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				fastxReader.Close()
+				return err
+			}
+			if checkAlphabet {
+				if fastxReader.IsFastq {
+					if !config.LineWidthChanged {
+						config.LineWidth = 0
+					}
+					fastx.ForcelyOutputFastq = true
+				}
+				if alphabet == nil {
+					alphabet = record.Seq.Alphabet
+				}
+				checkAlphabet = false
+			}
+
+			item, err := opt.newItem(idx, record.Clone())
+			if err != nil {
+				fastxReader.Close()
+				return err
+			}
+			items = append(items, item)
+			idx++
+			mem += int64(externalSortItemSize + len(record.Name) + len(record.Seq.Seq) + len(record.Seq.Qual))
+
+			if mem >= opt.maxMem {
+				sortItems()
+				run := filepath.Join(dir, fmt.Sprintf("run_%06d.bin.lz4", len(runs)))
+				if err = writeSortRun(run, items); err != nil {
+					fastxReader.Close()
+					return err
+				}
+				runs = append(runs, run)
+
+				clear(items)
+				items = items[:0]
+				mem = 0
+			}
+		}
+		fastxReader.Close()
+	}
+
+	// all in memory
+	if len(runs) == 0 {
+		if !config.Quiet {
+			log.Infof("%d sequences read, sorting in memory", idx)
+		}
+		sortItems()
+		for _, item := range items {
+			item.record.FormatToWriter(outfh, config.LineWidth)
+		}
+		return nil
+	}
+
+	// the last run
+	if len(items) > 0 {
+		sortItems()
+		run := filepath.Join(dir, fmt.Sprintf("run_%06d.bin.lz4", len(runs)))
+		if err = writeSortRun(run, items); err != nil {
+			return err
+		}
+		runs = append(runs, run)
+		items = nil
+	}
+
+	// merge runs in multiple passes if there are too many, to avoid
+	// exceeding the limit of open files.
+	if !config.Quiet {
+		log.Infof("%d sequences read, merging %d sorted runs saved in temporary directory ...", idx, len(runs))
+	}
+	nRuns := len(runs)
+	for len(runs) > maxMergedSortRuns {
+		merged := make([]string, 0, (len(runs)+maxMergedSortRuns-1)/maxMergedSortRuns)
+		for i := 0; i < len(runs); i += maxMergedSortRuns {
+			group := runs[i:min(i+maxMergedSortRuns, len(runs))]
+			if len(group) == 1 {
+				merged = append(merged, group[0])
+				continue
+			}
+			run := filepath.Join(dir, fmt.Sprintf("run_%06d.bin.lz4", nRuns))
+			nRuns++
+			fh, err := xopen.Wopen(run)
+			if err != nil {
+				return err
+			}
+			err = mergeSortRuns(group, opt, idRe, alphabet, func(item *externalSortItem) {
+				writeSortRunItem(fh, item)
+			})
+			if err != nil {
+				fh.Close()
+				return err
+			}
+			if err = fh.Close(); err != nil {
+				return err
+			}
+			for _, file := range group { // release disk space as soon as possible
+				if err = os.Remove(file); err != nil {
+					return err
+				}
+			}
+			merged = append(merged, run)
+		}
+		runs = merged
+	}
+
+	return mergeSortRuns(runs, opt, idRe, alphabet, func(item *externalSortItem) {
+		item.record.FormatToWriter(outfh, config.LineWidth)
+	})
+}
+
+// maximum number of runs merged at the same time, i.e., open files
+const maxMergedSortRuns = 256
+
+// mergeSortRuns merges sorted runs, and calls fn for every item in order.
+func mergeSortRuns(runs []string, opt *externalSortOptions, idRe *regexp.Regexp,
+	alphabet *seq.Alphabet, fn func(item *externalSortItem)) error {
+
+	h := &sortRunHeap{readers: make([]*sortRunReader, 0, len(runs)), opt: opt}
+	defer func() { // close files of runs in case of errors
+		for _, r := range h.readers {
+			r.fh.Close()
+		}
+	}()
+	for _, run := range runs {
+		fh, err := xopen.Ropen(run)
+		if err != nil {
+			return err
+		}
+		r := &sortRunReader{fh: fh, opt: opt, idRe: idRe, alphabet: alphabet}
+		if err = r.next(); err != nil {
+			fh.Close()
+			if err == io.EOF {
+				continue
+			}
+			return err
+		}
+		h.readers = append(h.readers, r)
+	}
+	heap.Init(h)
+
+	var r *sortRunReader
+	var err error
+	for h.Len() > 0 {
+		r = h.readers[0]
+		fn(r.item)
+		if err = r.next(); err != nil {
+			if err != io.EOF {
+				return err
+			}
+			if err = r.fh.Close(); err != nil {
+				return err
+			}
+			heap.Pop(h)
+			continue
+		}
+		heap.Fix(h, 0)
+	}
+	return nil
+}
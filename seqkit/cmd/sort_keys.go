@@ -0,0 +1,247 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/shenwei356/bio/seqio/fastx"
+	"github.com/shenwei356/natsort"
+)
+
+// types of sorting keys
+const (
+	sortKeyStr = iota
+	sortKeyNum
+	sortKeyNatural
+)
+
+// sortKey is a sorting key, in the format of field[:type][:order].
+type sortKey struct {
+	Field string
+	Type  int
+	Desc  bool
+
+	builtin bool
+	re      *regexp.Regexp // for header fields
+}
+
+// built-in fields of sorting keys, and whether they are numeric.
+var sortKeyBuiltinFields = map[string]bool{
+	"id":     false,
+	"name":   false,
+	"seq":    false,
+	"length": true,
+	"bases":  true,
+	"gc":     true,
+	"qual":   true,
+}
+
+// parseSortKeys parses key specifications like "size:num:desc,id:natural".
+// Fields other than the built-in ones are extracted from sequence headers,
+// with a regular expression from fieldRegexps ("field:regexp") or the
+// default one matching "field=value".
+func parseSortKeys(spec string, fieldRegexps []string) ([]*sortKey, error) {
+	regexps := make(map[string]string, len(fieldRegexps))
+	for _, s := range fieldRegexps {
+		i := strings.Index(s, ":")
+		if i <= 0 || i == len(s)-1 {
+			return nil, fmt.Errorf(`invalid field regular expression: %s. it should be in the format of "field:regexp"`, s)
+		}
+		regexps[s[:i]] = s[i+1:]
+	}
+
+	keys := make([]*sortKey, 0, 4)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		items := strings.Split(item, ":")
+		if len(items) > 3 {
+			return nil, fmt.Errorf("invalid sorting key: %s", item)
+		}
+
+		key := &sortKey{Field: items[0]}
+		var numeric bool
+		if _, ok := regexps[key.Field]; !ok {
+			numeric, key.builtin = sortKeyBuiltinFields[key.Field]
+		}
+		if numeric {
+			key.Type = sortKeyNum
+		}
+
+		for _, s := range items[1:] {
+			switch strings.ToLower(s) {
+			case "str":
+				key.Type = sortKeyStr
+			case "num":
+				key.Type = sortKeyNum
+			case "natural":
+				key.Type = sortKeyNatural
+			case "asc":
+				key.Desc = false
+			case "desc":
+				key.Desc = true
+			default:
+				return nil, fmt.Errorf("invalid type or order in sorting key: %s. available: str, num, natural, asc, desc", item)
+			}
+		}
+		if numeric && key.Type != sortKeyNum {
+			return nil, fmt.Errorf("field %s only supports the type of num", key.Field)
+		}
+
+		if !key.builtin {
+			expr, ok := regexps[key.Field]
+			if !ok {
+				expr = `(?:^|[\s;|])` + regexp.QuoteMeta(key.Field) + `=([^\s;|]+)`
+			}
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compile regular expression of field %s: %s", key.Field, expr)
+			}
+			if re.NumSubexp() < 1 {
+				return nil, fmt.Errorf("regular expression of field %s should contain a capture group: %s", key.Field, expr)
+			}
+			key.re = re
+		}
+
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no sorting keys given")
+	}
+	return keys, nil
+}
+
+// sortKeyValue is the value of a sorting key of a record.
+type sortKeyValue struct {
+	s  string
+	f  float64
+	ok bool // false for missing values
+}
+
+// sortKeyValues computes values of sorting keys of a record.
+func sortKeyValues(keys []*sortKey, record *fastx.Record, ignoreCase bool, gapLetters string, qBase int) ([]sortKeyValue, error) {
+	values := make([]sortKeyValue, len(keys))
+	var s string
+	var err error
+	for i, key := range keys {
+		v := &values[i]
+		v.ok = true
+		if key.builtin {
+			switch key.Field {
+			case "length":
+				v.f = float64(len(record.Seq.Seq))
+				continue
+			case "bases":
+				v.f = float64(record.Seq.Bases(gapLetters))
+				continue
+			case "gc":
+				v.f = record.Seq.GC()
+				continue
+			case "qual":
+				if len(record.Seq.Qual) == 0 {
+					v.ok = false
+				} else {
+					v.f = record.Seq.AvgQual(qBase)
+				}
+				continue
+			case "id":
+				s = string(record.ID)
+			case "name":
+				s = string(record.Name)
+			case "seq":
+				s = string(record.Seq.Seq)
+			}
+		} else {
+			m := key.re.FindSubmatch(record.Name)
+			if m == nil {
+				v.ok = false
+				continue
+			}
+			s = string(m[1])
+		}
+
+		if key.Type == sortKeyNum {
+			v.f, err = strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse value of field %s as a number: %s, in: %s", key.Field, s, record.Name)
+			}
+			continue
+		}
+		if ignoreCase {
+			s = strings.ToLower(s)
+		}
+		v.s = s
+	}
+	return values, nil
+}
+
+// compareSortKeys compares values of sorting keys one by one.
+// Missing values are always placed at the end.
+func compareSortKeys(keys []*sortKey, a, b []sortKeyValue) int {
+	var c int
+	for i, key := range keys {
+		x, y := &a[i], &b[i]
+		if !x.ok || !y.ok {
+			if x.ok {
+				return -1
+			}
+			if y.ok {
+				return 1
+			}
+			continue
+		}
+
+		switch key.Type {
+		case sortKeyNum:
+			if x.f < y.f {
+				c = -1
+			} else if x.f > y.f {
+				c = 1
+			} else {
+				c = 0
+			}
+		case sortKeyNatural:
+			if x.s == y.s {
+				c = 0
+			} else if natsort.Compare(x.s, y.s, false) {
+				c = -1
+			} else {
+				c = 1
+			}
+		default:
+			c = strings.Compare(x.s, y.s)
+		}
+		if c == 0 {
+			continue
+		}
+		if key.Desc {
+			return -c
+		}
+		return c
+	}
+	return 0
+}
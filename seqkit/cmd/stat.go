@@ -22,10 +22,10 @@ package cmd
 
 import (
 	"fmt"
-	"io"
 	"math"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"sort"
 	"strconv"
@@ -38,6 +38,7 @@ import (
 	"github.com/shenwei356/bio/seq"
 	"github.com/shenwei356/bio/seqio/fastx"
 	"github.com/shenwei356/bio/util"
+	"github.com/shenwei356/natsort"
 	"github.com/shenwei356/stable"
 	"github.com/shenwei356/util/byteutil"
 	mathutil "github.com/shenwei356/util/math"
@@ -84,14 +85,57 @@ Columns:
   18. GC(%)     percentage of GC content
   19. sum_n     number of ambiguous letters (N, n, X, x)
 
+Assembly mode (-A/--assembly):
+  Statistics similar to the basic table of QUAST are computed for scaffolds
+  (input sequences) and contigs, which are obtained by splitting scaffolds
+  at runs of at least 10 N's (--min-n-run), and are outputted in two rows
+  for each file. Columns:
+
+  1.  file                   input file, "-" for STDIN
+  2.  level                  scaffold or contig
+  3.  num_seqs               number of sequences
+  4.  sum_len                number of bases
+  5.  min_len                minimal sequence length
+  6.  max_len                maximal sequence length
+  7.  num_seqs(>=L)          number of sequences >= L bp, L is given by -L/--len-thresholds
+  8.  sum_len(>=L)           number of bases in sequences >= L bp
+  9.  Nx                     Nx, x is given by -N/--N, default: 50, 90
+  10. auN                    area under the Nx curve, i.e., sum(length^2) / sum_len
+  11. Lx                     minimal number of sequences with a total length >= x% of sum_len
+  12. NGx, auNG, LGx         similar to Nx, auN, and Lx, but computed with the genome size (-g/--genome-size).
+                             NGx and LGx are 0 if sum_len is smaller than x% of the genome size.
+                             The genome size supports K/M/G suffixes with decimal units, e.g., 5M = 5,000,000.
+  13. GC(%)                  percentage of GC content, N's excluded
+  14. N_per_100kbp           number of N's per 100 kbp
+
+Grouping:
+  1. Records could be grouped by the first capture group of a regular expression
+     on the header (--group-by-header), e.g., barcodes in Nanopore reads:
+         seqkit stats --group-by-header 'barcode=(\S+)' reads.fq.gz
+     A new column "group" is added after "file", and records not matching the
+     regular expression are grouped as "NA". Groups are sorted in natural order.
+  2. Multiple files of the same sample could be aggregated into one row
+     by the first capture group of a regular expression on the file base name
+     (--group-by-file), which is shown in the column "file", e.g., Illumina lanes:
+         seqkit stats --group-by-file '^(.+)_L\d{3}_' *.fq.gz
+     Files not matching the regular expression are not aggregated.
+  3. Both could be used at the same time, and they also work in assembly mode.
+
 Attention:
   1. Sequence length metrics (sum_len, min_len, avg_len, max_len, Q1, Q2, Q3)
      count the number of gaps or spaces. You can remove them with "seqkit seq -g":
          seqkit seq -g input.fasta | seqkit stats
+  2. Lx in assembly mode is the number of sequences, while N50_num of the
+     default mode is the number of distinct lengths.
 
 Tips:
   1. For lots of small files (especially on SDD), use a big value of '-j' to
      parallelize counting.
+     For a few big files, each file bigger than 2X of -C/--chunk-size is split into
+     chunks which are processed in parallel with '-j' / number_of_files threads.
+     Only plain files and gzip files of multiple members (e.g., BGZF files, or
+     concatenated gzip files) are supported, and FASTQ records must be 4-line.
+     Other files (single-member gzip, xz, zstd, bzip2) and STDIN are processed sequentially.
   2. Extract one metric with csvtk (https://github.com/shenwei356/csvtk):
          seqkit stats -Ta input.fastq.gz | csvtk cut -t -f "Q30(%)" | csvtk del-header
 
@@ -115,9 +159,6 @@ Tips:
 			}
 		}
 		gapLettersBytes := []byte(gapLetters)
-		gcLettersBytes := []byte{'g', 'c', 'G', 'C'}
-		nLettersBytesNucl := []byte{'N', 'n'}
-		nLettersBytesProt := []byte{'X', 'x'}
 
 		skipFileCheck := getFlagBool(cmd, "skip-file-check")
 		all := getFlagBool(cmd, "all")
@@ -130,6 +171,22 @@ Tips:
 		_NX := getFlagStringSlice(cmd, "N")
 		hasNX := len(_NX) > 0
 
+		assembly := getFlagBool(cmd, "assembly")
+		genomeSizeS := getFlagString(cmd, "genome-size")
+		_thresholds := getFlagStringSlice(cmd, "len-thresholds")
+		minNRun := getFlagPositiveInt(cmd, "min-n-run")
+		if assembly {
+			if all {
+				checkError(fmt.Errorf("the flag -A/--assembly is incompatible with -a/--all"))
+			}
+			if !hasNX {
+				_NX = []string{"50", "90"}
+			}
+			hasNX = false // Nx are outputted in assembly statistics
+		} else if genomeSizeS != "" {
+			checkError(fmt.Errorf("the flag -g/--genome-size should be used along with -A/--assembly"))
+		}
+
 		NX := make([]float64, len(_NX))
 		var err error
 		for i, x := range _NX {
@@ -142,6 +199,67 @@ Tips:
 			}
 		}
 
+		var genomeSize uint64
+		var thresholds []uint64
+		if assembly {
+			if genomeSizeS != "" {
+				size, err := ParseGenomeSize(genomeSizeS)
+				if err != nil || size <= 0 {
+					checkError(fmt.Errorf("invalid value of flag -g/--genome-size: %s", genomeSizeS))
+				}
+				genomeSize = uint64(size)
+			}
+
+			thresholds = make([]uint64, len(_thresholds))
+			for i, t := range _thresholds {
+				thresholds[i], err = strconv.ParseUint(t, 10, 64)
+				if err != nil {
+					checkError(fmt.Errorf("the value of -L/--len-thresholds should be non-negative integers: %s", t))
+				}
+			}
+		}
+
+		groupHeader := getFlagString(cmd, "group-by-header")
+		groupFile := getFlagString(cmd, "group-by-file")
+		groupByHeader := groupHeader != ""
+		groupByFile := groupFile != ""
+		grouping := groupByHeader || groupByFile
+		var groupHeaderRe, groupFileRe *regexp.Regexp
+		if groupByHeader {
+			groupHeaderRe, err = regexp.Compile(groupHeader)
+			checkError(err)
+			if groupHeaderRe.NumSubexp() < 1 {
+				checkError(fmt.Errorf("regular expression for --group-by-header should contain a capture group: %s", groupHeader))
+			}
+		}
+		if groupByFile {
+			groupFileRe, err = regexp.Compile(groupFile)
+			checkError(err)
+			if groupFileRe.NumSubexp() < 1 {
+				checkError(fmt.Errorf("regular expression for --group-by-file should contain a capture group: %s", groupFile))
+			}
+		}
+
+		chunkSizeS := getFlagString(cmd, "chunk-size")
+		chunkSize, err := ParseByteSize(chunkSizeS)
+		if err != nil {
+			checkError(fmt.Errorf("invalid value of flag -C/--chunk-size: %s", chunkSizeS))
+		}
+
+		opt := &statOptions{
+			groupHeaderRe: groupHeaderRe,
+
+			all:          all,
+			gapLetters:   gapLettersBytes,
+			encodeOffset: fqEncoding.Offset(),
+			NX:           NX,
+			hasNX:        hasNX,
+			assembly:     assembly,
+			minNRun:      minNRun,
+			genomeSize:   genomeSize,
+			thresholds:   thresholds,
+		}
+
 		files := getFileListFromArgsAndFile(cmd, args, true, "infile-list", !(skipFileCheck || config.SkipFileCheck))
 		if !config.SkipFileCheck {
 			for _, file := range files {
@@ -149,6 +267,12 @@ Tips:
 			}
 		}
 
+		// threads for processing chunks of a big file
+		var chunkThreads int
+		if chunkSize > 0 {
+			chunkThreads = config.Threads / len(files)
+		}
+
 		style := &stable.TableStyle{
 			Name: "plain",
 
@@ -195,10 +319,50 @@ Tips:
 		checkError(err)
 		defer outfh.Close()
 
+		writeTabularRow := func(info statInfo) {
+			outfh.WriteString(info.file)
+			if groupByHeader {
+				outfh.WriteString("\t" + info.group)
+			}
+			fmt.Fprintf(outfh, "\t%s\t%s\t%d\t%d\t%d\t%.1f\t%d",
+				info.format,
+				info.t,
+				info.num,
+				info.lenSum,
+				info.lenMin,
+				info.lenAvg,
+				info.lenMax)
+			if all {
+				fmt.Fprintf(outfh, "\t%.0f\t%.0f\t%.0f\t%d\t%d\t%d\t%.0f\t%.0f\t%.2f\t%.2f\t%d",
+					info.Q1,
+					info.Q2,
+					info.Q3,
+					info.gapSum,
+					info.N50,
+					info.L50,
+					info.q20,
+					info.q30,
+					info.avgQual,
+					info.gc,
+					info.nSum,
+				)
+			}
+			if hasNX {
+				for _, x := range info.nx {
+					fmt.Fprintf(outfh, "\t%.0f", x)
+				}
+			}
+			outfh.WriteString("\n")
+			outfh.Flush()
+		}
+
 		// tabular output
-		if tabular {
-			colnames := []string{
-				"file",
+		if tabular && !assembly {
+			colnames := []string{"file"}
+			if groupByHeader {
+				colnames = append(colnames, "group")
+			}
+			colnames = append(colnames, []string{
 				"format",
 				"type",
 				"num_seqs",
@@ -206,7 +370,7 @@ Tips:
 				"min_len",
 				"avg_len",
 				"max_len",
-			}
+			}...)
 			if all {
 				colnames = append(colnames, []string{"Q1", "Q2", "Q3", "sum_gap", "N50", "N50_num", "Q20(%)", "Q30(%)", "AvgQual", "GC(%)", "sum_n"}...)
 			}
@@ -219,13 +383,15 @@ Tips:
 			outfh.WriteString(strings.Join(colnames, "\t") + "\n")
 		}
 
+		// results are outputted after all files being processed
+		delayed := !tabular || assembly || grouping
+
 		ch := make(chan statInfo, config.Threads)
 		statInfos := make([]statInfo, 0, 1024)
 
 		var quit bool
 
 		done := make(chan int)
-		var x float64
 		go func() {
 			var id uint64 = 1 // for keepping order
 			buf := make(map[uint64]statInfo)
@@ -245,40 +411,10 @@ Tips:
 				}
 
 				if id == info.id { // right the one
-					if !tabular {
+					if delayed {
 						statInfos = append(statInfos, info)
 					} else {
-						fmt.Fprintf(outfh, "%s\t%s\t%s\t%d\t%d\t%d\t%.1f\t%d",
-							info.file,
-							info.format,
-							info.t,
-							info.num,
-							info.lenSum,
-							info.lenMin,
-							info.lenAvg,
-							info.lenMax)
-						if all {
-							fmt.Fprintf(outfh, "\t%.0f\t%.0f\t%.0f\t%d\t%d\t%d\t%.0f\t%.0f\t%.2f\t%.2f\t%d",
-								info.Q1,
-								info.Q2,
-								info.Q3,
-								info.gapSum,
-								info.N50,
-								info.L50,
-								info.q20,
-								info.q30,
-								info.avgQual,
-								info.gc,
-								info.nSum,
-							)
-						}
-						if hasNX {
-							for _, x = range info.nx {
-								fmt.Fprintf(outfh, "\t%.0f", x)
-							}
-						}
-						outfh.WriteString("\n")
-						outfh.Flush()
+						writeTabularRow(info)
 					}
 					id++
 					continue
@@ -288,40 +424,10 @@ Tips:
 
 				// check bufferd results
 				if info, ok := buf[id]; ok {
-					if !tabular {
+					if delayed {
 						statInfos = append(statInfos, info)
 					} else {
-						fmt.Fprintf(outfh, "%s\t%s\t%s\t%d\t%d\t%d\t%.1f\t%d",
-							info.file,
-							info.format,
-							info.t,
-							info.num,
-							info.lenSum,
-							info.lenMin,
-							info.lenAvg,
-							info.lenMax)
-						if all {
-							fmt.Fprintf(outfh, "\t%.0f\t%.0f\t%.0f\t%d\t%d\t%d\t%.0f\t%.0f\t%.2f\t%.2f\t%d",
-								info.Q1,
-								info.Q2,
-								info.Q3,
-								info.gapSum,
-								info.N50,
-								info.L50,
-								info.q20,
-								info.q30,
-								info.avgQual,
-								info.gc,
-								info.nSum,
-							)
-						}
-						if hasNX {
-							for _, x = range info.nx {
-								fmt.Fprintf(outfh, "\t%.0f", x)
-							}
-						}
-						outfh.WriteString("\n")
-						outfh.Flush()
+						writeTabularRow(info)
 					}
 
 					delete(buf, info.id)
@@ -339,40 +445,10 @@ Tips:
 				sort.Sort(ids)
 				for _, id := range ids {
 					info := buf[id]
-					if !tabular {
+					if delayed {
 						statInfos = append(statInfos, info)
 					} else {
-						fmt.Fprintf(outfh, "%s\t%s\t%s\t%d\t%d\t%d\t%.1f\t%d",
-							info.file,
-							info.format,
-							info.t,
-							info.num,
-							info.lenSum,
-							info.lenMin,
-							info.lenAvg,
-							info.lenMax)
-						if all {
-							fmt.Fprintf(outfh, "\t%.0f\t%.0f\t%.0f\t%d\t%d\t%d\t%.0f\t%.0f\t%.2f\t%.2f\t%d",
-								info.Q1,
-								info.Q2,
-								info.Q3,
-								info.gapSum,
-								info.N50,
-								info.L50,
-								info.q20,
-								info.q30,
-								info.avgQual,
-								info.gc,
-								info.nSum,
-							)
-						}
-						if hasNX {
-							for _, x = range info.nx {
-								fmt.Fprintf(outfh, "\t%.0f", x)
-							}
-						}
-						outfh.WriteString("\n")
-						outfh.Flush()
+						writeTabularRow(info)
 					}
 				}
 			}
@@ -409,166 +485,89 @@ Tips:
 					}
 				}()
 
-				var gapSum uint64
-				var gcSum uint64
-				var nSum uint64
-
-				lensStats := util.NewLengthStats()
-
-				var errSum, avgQual float64
-				qual_map := seq.QUAL_MAP
-				var q20, q30 int64
-				var q byte
-				var qual int
-				var encodeOffset int = fqEncoding.Offset()
-				var seqFormat, t string
-				var record *fastx.Record
-				var fastxReader *fastx.Reader
-				var err error
-				checkSeqType := true
-				var isNucleotide bool
-
-				fastxReader, err = fastx.NewReader(alphabet, file, idRegexp)
-				if err != nil {
-					if replaceStdinLabel && isStdin(file) {
-						file = stdinLabel
-					}
-					ch <- statInfo{file: file, err: err, id: id}
-					return
+				fileLabel := file
+				if basename {
+					fileLabel = filepath.Base(fileLabel)
 				}
-
-				if quit {
-					return
+				if replaceStdinLabel && isStdin(file) {
+					fileLabel = stdinLabel
 				}
 
-				seqFormat = ""
-
-				for {
-					record, err = fastxReader.Read()
+				// a big file is split into chunks which are processed in parallel
+				var chunks *statChunks
+				var err error
+				if chunkThreads > 1 && !isStdin(file) {
+					chunks, err = splitStatFile(file, chunkSize)
 					if err != nil {
-						if err == io.EOF {
-							break
-						}
-
-						if replaceStdinLabel && isStdin(file) {
-							file = stdinLabel
-						}
-						ch <- statInfo{file: file, err: err, id: id}
+						ch <- statInfo{file: fileLabel, err: err, id: id}
 						return
 					}
+				}
 
-					// for early quit
-					if quit {
+				var counts *statFileCounts
+				var quitted bool
+				if chunks == nil {
+					var fastxReader *fastx.Reader
+					fastxReader, err = fastx.NewReader(alphabet, file, idRegexp)
+					if err != nil {
+						ch <- statInfo{file: fileLabel, err: err, id: id}
 						return
 					}
-
-					if checkSeqType {
-						checkSeqType = false
-
-						if fastxReader.IsFastq {
-							seqFormat = "FASTQ"
-						} else {
-							seqFormat = "FASTA"
-						}
-
-						isNucleotide = fastxReader.Alphabet() == seq.DNA ||
-							fastxReader.Alphabet() == seq.DNAredundant ||
-							fastxReader.Alphabet() == seq.RNA || fastxReader.Alphabet() == seq.RNAredundant
-					}
-
-					lensStats.Add(uint64(len(record.Seq.Seq)))
-
-					if all {
-						if fastxReader.IsFastq {
-							for _, q = range record.Seq.Qual {
-								qual = int(q) - encodeOffset
-								if qual >= 20 {
-									q20++
-									if qual >= 30 {
-										q30++
-									}
-								}
-
-								errSum += qual_map[qual]
-							}
-						}
-
-						gapSum += uint64(byteutil.CountBytes(record.Seq.Seq, gapLettersBytes))
-						if isNucleotide {
-							gcSum += uint64(byteutil.CountBytes(record.Seq.Seq, gcLettersBytes))
-							nSum += uint64(byteutil.CountBytes(record.Seq.Seq, nLettersBytesNucl))
-						} else {
-							nSum += uint64(byteutil.CountBytes(record.Seq.Seq, nLettersBytesProt))
-						}
-					}
+					counts, quitted, err = countStatRecords(fastxReader, opt, &quit)
+					fastxReader.Close()
+				} else {
+					counts, quitted, err = countStatChunks(chunks, alphabet, idRegexp, chunkThreads, opt, &quit)
+				}
+				if quitted {
+					return
+				}
+				if err != nil {
+					ch <- statInfo{file: fileLabel, err: err, id: id}
+					return
 				}
 
-				fastxReader.Close()
-
-				if fastxReader.Alphabet() == seq.DNAredundant {
+				var t string
+				if counts.alphabet == seq.DNAredundant {
 					t = "DNA"
-				} else if fastxReader.Alphabet() == seq.RNAredundant {
+				} else if counts.alphabet == seq.RNAredundant {
 					t = "RNA"
-				} else if seqFormat == "" && fastxReader.Alphabet() == seq.Unlimit {
+				} else if counts.seqFormat == "" && counts.alphabet == seq.Unlimit {
 					t = ""
 				} else {
-					t = fastxReader.Alphabet().String()
+					t = counts.alphabet.String()
 				}
 
-				var n50 uint64
-				var l50 int
-				var q1, q2, q3 float64
-				if all {
-					n50 = lensStats.N50()
-					l50 = lensStats.L50()
-					q1, q2, q3 = lensStats.Q1(), lensStats.Q2(), lensStats.Q3()
-
-					if errSum == 0 {
-						avgQual = 0
-					} else {
-						avgQual = -10 * math.Log10(errSum/float64(lensStats.Sum()))
-					}
-				}
-				var nx []float64
-				if hasNX {
-					nx = make([]float64, len(NX))
-					for i, x := range NX {
-						nx[i] = float64(lensStats.NX(x))
-					}
+				counter := counts.counter
+				if !grouping {
+					counter.format, counter.t = counts.seqFormat, t
+					info := counter.info(fileLabel, "")
+					info.id = id
+					ch <- info
+					return
 				}
 
-				if lensStats.Count() == 0 {
-					if basename {
-						file = filepath.Base(file)
-					}
-					if replaceStdinLabel && isStdin(file) {
-						file = stdinLabel
+				// grouped counters are merged and summarized after all files being processed
+				if groupByFile {
+					fileLabel = statFileGroup(groupFileRe, file, fileLabel)
+				}
+				var groups []*statGroup
+				if groupByHeader {
+					groups = make([]*statGroup, 0, len(counts.counters))
+					for group, counter := range counts.counters {
+						counter.format, counter.t = counts.seqFormat, t
+						groups = append(groups, &statGroup{file: fileLabel, group: group, counter: counter})
 					}
-					ch <- statInfo{file, seqFormat, t,
-						0, 0, 0, 0, 0,
-						0, 0, 0, 0,
-						0, 0, 0,
-						0, 0, 0, 0,
-						nx,
-						nil, id}
+					sort.Slice(groups, func(i, j int) bool {
+						if groups[i].group == statGroupNA || groups[j].group == statGroupNA {
+							return groups[j].group == statGroupNA && groups[i].group != statGroupNA
+						}
+						return natsort.Compare(groups[i].group, groups[j].group, false)
+					})
 				} else {
-					if basename {
-						file = filepath.Base(file)
-					}
-					if replaceStdinLabel && isStdin(file) {
-						file = stdinLabel
-					}
-					ch <- statInfo{file, seqFormat, t,
-						lensStats.Count(), lensStats.Sum(), gapSum, lensStats.Min(), nSum,
-						mathutil.Round(lensStats.Mean(), 1), lensStats.Max(), n50, l50,
-						q1, q2, q3,
-						mathutil.Round(float64(q20)/float64(lensStats.Sum())*100, 2),
-						mathutil.Round(float64(q30)/float64(lensStats.Sum())*100, 2),
-						mathutil.Round(avgQual, 2),
-						mathutil.Round(float64(gcSum)/float64(lensStats.Sum())*100, 2),
-						nx,
-						nil, id}
+					counter.format, counter.t = counts.seqFormat, t
+					groups = []*statGroup{{file: fileLabel, counter: counter}}
 				}
+				ch <- statInfo{file: fileLabel, groups: groups, id: id}
 			}(file, id)
 		}
 
@@ -588,13 +587,30 @@ Tips:
 			return
 		}
 
+		if grouping {
+			statInfos = mergeStatGroups(statInfos)
+		}
+
+		if assembly {
+			writeAssemblyStats(outfh, statInfos, tabular, style, groupByHeader, _NX, genomeSize, thresholds)
+			return
+		}
+
 		if tabular {
+			if grouping {
+				for _, info := range statInfos {
+					writeTabularRow(info)
+				}
+			}
 			return
 		}
 
 		// format output
-		columns := []stable.Column{
-			{Header: "file"},
+		columns := []stable.Column{{Header: "file"}}
+		if groupByHeader {
+			columns = append(columns, stable.Column{Header: "group"})
+		}
+		columns = append(columns, []stable.Column{
 			{Header: "format"},
 			{Header: "type"},
 			{Header: "num_seqs", Align: stable.AlignRight, HumanizeNumbers: true},
@@ -602,7 +618,7 @@ Tips:
 			{Header: "min_len", Align: stable.AlignRight, HumanizeNumbers: true},
 			{Header: "avg_len", Align: stable.AlignRight, HumanizeNumbers: true},
 			{Header: "max_len", Align: stable.AlignRight, HumanizeNumbers: true},
-		}
+		}...)
 
 		if all {
 			columns = append(columns, []stable.Column{
@@ -631,6 +647,9 @@ Tips:
 		for _, info := range statInfos {
 			row := make([]interface{}, 0, len(columns))
 			row = append(row, info.file)
+			if groupByHeader {
+				row = append(row, info.group)
+			}
 			row = append(row, info.format)
 			row = append(row, info.t)
 			row = append(row, humanize.Comma(int64(info.num)))
@@ -653,7 +672,7 @@ Tips:
 				row = append(row, info.nSum)
 			}
 			if hasNX {
-				for _, x = range info.nx {
+				for _, x := range info.nx {
 					row = append(row, x)
 				}
 			}
@@ -690,12 +709,194 @@ type statInfo struct {
 
 	gc float64
 
-	nx []float64
+	nx  []float64
+	asm []*assemblyStats // scaffold and contig statistics in assembly mode
+
+	group  string       // group of records, with --group-by-header
+	groups []*statGroup // counters to merge, with --group-by-header or --group-by-file
 
 	err error
 	id  uint64
 }
 
+// statOptions contains options for counting and summarizing sequences.
+type statOptions struct {
+	groupHeaderRe *regexp.Regexp // for grouping records by header
+
+	all          bool
+	gapLetters   []byte
+	encodeOffset int
+
+	NX    []float64
+	hasNX bool
+
+	assembly   bool
+	minNRun    int
+	genomeSize uint64
+	thresholds []uint64
+}
+
+var gcLettersBytes = []byte{'g', 'c', 'G', 'C'}
+var nLettersBytesNucl = []byte{'N', 'n'}
+var nLettersBytesProt = []byte{'X', 'x'}
+
+// statCounter accumulates statistics of sequences.
+// Counters of different files or groups could be merged.
+type statCounter struct {
+	opt *statOptions
+
+	format string
+	t      string
+
+	lens map[uint64]uint64 // length -> count
+
+	gapSum uint64
+	gcSum  uint64
+	nSum   uint64
+
+	errSum   float64
+	q20, q30 int64
+
+	asm *assemblyCounter
+}
+
+func newStatCounter(opt *statOptions) *statCounter {
+	c := &statCounter{opt: opt, lens: make(map[uint64]uint64, 256)}
+	if opt.assembly {
+		c.asm = newAssemblyCounter(opt.minNRun)
+	}
+	return c
+}
+
+func (c *statCounter) add(record *fastx.Record, isFastq bool, isNucleotide bool) {
+	c.lens[uint64(len(record.Seq.Seq))]++
+
+	if c.opt.assembly {
+		c.asm.Add(record.Seq.Seq)
+	}
+
+	if !c.opt.all {
+		return
+	}
+
+	if isFastq {
+		var qual int
+		for _, q := range record.Seq.Qual {
+			qual = int(q) - c.opt.encodeOffset
+			if qual >= 20 {
+				c.q20++
+				if qual >= 30 {
+					c.q30++
+				}
+			}
+
+			c.errSum += seq.QUAL_MAP[qual]
+		}
+	}
+
+	c.gapSum += uint64(byteutil.CountBytes(record.Seq.Seq, c.opt.gapLetters))
+	if isNucleotide {
+		c.gcSum += uint64(byteutil.CountBytes(record.Seq.Seq, gcLettersBytes))
+		c.nSum += uint64(byteutil.CountBytes(record.Seq.Seq, nLettersBytesNucl))
+	} else {
+		c.nSum += uint64(byteutil.CountBytes(record.Seq.Seq, nLettersBytesProt))
+	}
+}
+
+// merge merges another counter into this one.
+func (c *statCounter) merge(o *statCounter) {
+	if c.format == "" {
+		c.format, c.t = o.format, o.t
+	}
+	for l, n := range o.lens {
+		c.lens[l] += n
+	}
+	c.gapSum += o.gapSum
+	c.gcSum += o.gcSum
+	c.nSum += o.nSum
+	c.errSum += o.errSum
+	c.q20 += o.q20
+	c.q30 += o.q30
+	if c.asm != nil {
+		c.asm.merge(o.asm)
+	}
+}
+
+// info summarizes the statistics.
+func (c *statCounter) info(file, group string) statInfo {
+	opt := c.opt
+
+	lensStats := util.NewLengthStats()
+	var n uint64
+	for l, count := range c.lens {
+		for n = 0; n < count; n++ {
+			lensStats.Add(l)
+		}
+	}
+
+	var asm []*assemblyStats
+	if opt.assembly {
+		asm = c.asm.Stats(opt.NX, opt.genomeSize, opt.thresholds)
+	}
+
+	var nx []float64
+	if opt.hasNX {
+		nx = make([]float64, len(opt.NX))
+		for i, x := range opt.NX {
+			nx[i] = float64(lensStats.NX(x))
+		}
+	}
+
+	if lensStats.Count() == 0 {
+		return statInfo{file: file, format: c.format, t: c.t, nx: nx, asm: asm, group: group}
+	}
+
+	var n50 uint64
+	var l50 int
+	var q1, q2, q3, avgQual float64
+	if opt.all {
+		n50 = lensStats.N50()
+		l50 = lensStats.L50()
+		q1, q2, q3 = lensStats.Q1(), lensStats.Q2(), lensStats.Q3()
+
+		if c.errSum > 0 {
+			avgQual = -10 * math.Log10(c.errSum/float64(lensStats.Sum()))
+		}
+	}
+
+	return statInfo{
+		file:   file,
+		format: c.format,
+		t:      c.t,
+
+		num:    lensStats.Count(),
+		lenSum: lensStats.Sum(),
+		gapSum: c.gapSum,
+		lenMin: lensStats.Min(),
+		nSum:   c.nSum,
+
+		lenAvg: mathutil.Round(lensStats.Mean(), 1),
+		lenMax: lensStats.Max(),
+		N50:    n50,
+		L50:    l50,
+
+		Q1: q1,
+		Q2: q2,
+		Q3: q3,
+
+		q20:     mathutil.Round(float64(c.q20)/float64(lensStats.Sum())*100, 2),
+		q30:     mathutil.Round(float64(c.q30)/float64(lensStats.Sum())*100, 2),
+		avgQual: mathutil.Round(avgQual, 2),
+
+		gc: mathutil.Round(float64(c.gcSum)/float64(lensStats.Sum())*100, 2),
+
+		nx:  nx,
+		asm: asm,
+
+		group: group,
+	}
+}
+
 func init() {
 	RootCmd.AddCommand(statCmd)
 
@@ -709,6 +910,16 @@ func init() {
 	statCmd.Flags().StringSliceP("N", "N", []string{}, `append other N50-like stats as new columns. value range [0, 100], multiple values supported, e.g., -N 50,90 or -N 50 -N 90`)
 	statCmd.Flags().BoolP("skip-file-check", "S", false, `skip input file checking when given files or a file list.`)
 
+	statCmd.Flags().BoolP("assembly", "A", false, `assembly mode: QUAST-like statistics of scaffolds and contigs split at N runs`)
+	statCmd.Flags().StringP("genome-size", "g", "", `genome size (supports K/M/G suffix, K=1000) for computing NGx, LGx, and auNG, in assembly mode`)
+	statCmd.Flags().StringSliceP("len-thresholds", "L", []string{"1000", "5000", "10000", "25000", "50000"}, `length thresholds for counting sequences and bases, in assembly mode`)
+	statCmd.Flags().IntP("min-n-run", "", 10, `minimum length of N runs for splitting scaffolds into contigs, in assembly mode`)
+
+	statCmd.Flags().StringP("group-by-header", "", "", `group records by the first capture group of a regular expression on the header, e.g., 'barcode=(\S+)'`)
+	statCmd.Flags().StringP("group-by-file", "", "", `group files by the first capture group of a regular expression on the file base name, e.g., '^(.+)_L\d{3}_'`)
+
+	statCmd.Flags().StringP("chunk-size", "C", "64M", `split a big plain or multi-member gzip (including BGZF) file into chunks of this size (supports K/M/G suffix) to process in parallel, when the number of threads is bigger than the number of files. 0 for disabling it`)
+
 }
 
 func median(sorted []int64) int64 {
@@ -0,0 +1,332 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/dustin/go-humanize"
+	"github.com/shenwei356/stable"
+	"github.com/shenwei356/xopen"
+)
+
+// assemblyCounter collects lengths of scaffolds (input sequences) and
+// contigs obtained by splitting scaffolds at runs of N's.
+type assemblyCounter struct {
+	minNRun int
+
+	scaffolds []uint64
+	contigs   []uint64
+
+	gc    uint64 // GC bases, the same for scaffolds and contigs
+	nScaf uint64 // N's in scaffolds
+	nCtg  uint64 // N's in contigs, i.e., in runs shorter than minNRun
+}
+
+func newAssemblyCounter(minNRun int) *assemblyCounter {
+	return &assemblyCounter{
+		minNRun:   minNRun,
+		scaffolds: make([]uint64, 0, 1024),
+		contigs:   make([]uint64, 0, 1024),
+	}
+}
+
+// Add adds a scaffold.
+func (c *assemblyCounter) Add(s []byte) {
+	c.scaffolds = append(c.scaffolds, uint64(len(s)))
+
+	var start, runStart, run int // start of the current contig, start and length of the current N run
+	for i, b := range s {
+		switch b {
+		case 'N', 'n':
+			if run == 0 {
+				runStart = i
+			}
+			run++
+			c.nScaf++
+			continue
+		case 'G', 'g', 'C', 'c':
+			c.gc++
+		}
+		if run > 0 {
+			if run >= c.minNRun {
+				if runStart > start {
+					c.contigs = append(c.contigs, uint64(runStart-start))
+				}
+				start = i
+			} else {
+				c.nCtg += uint64(run)
+			}
+			run = 0
+		}
+	}
+	if run > 0 {
+		if run >= c.minNRun {
+			if runStart > start {
+				c.contigs = append(c.contigs, uint64(runStart-start))
+			}
+			return
+		}
+		c.nCtg += uint64(run)
+	}
+	if len(s) > start {
+		c.contigs = append(c.contigs, uint64(len(s)-start))
+	}
+}
+
+// merge merges another counter into this one.
+func (c *assemblyCounter) merge(o *assemblyCounter) {
+	c.scaffolds = append(c.scaffolds, o.scaffolds...)
+	c.contigs = append(c.contigs, o.contigs...)
+	c.gc += o.gc
+	c.nScaf += o.nScaf
+	c.nCtg += o.nCtg
+}
+
+// Stats returns statistics of scaffolds and contigs.
+func (c *assemblyCounter) Stats(nx []float64, genomeSize uint64, thresholds []uint64) []*assemblyStats {
+	return []*assemblyStats{
+		newAssemblyStats("scaffold", c.scaffolds, c.gc, c.nScaf, nx, genomeSize, thresholds),
+		newAssemblyStats("contig", c.contigs, c.gc, c.nCtg, nx, genomeSize, thresholds),
+	}
+}
+
+// assemblyStats holds QUAST-like statistics of scaffolds or contigs.
+type assemblyStats struct {
+	level string
+
+	num    uint64
+	sum    uint64
+	min    uint64
+	max    uint64
+	numGEs []uint64 // number of sequences >= thresholds
+	sumGEs []uint64 // total length of sequences >= thresholds
+
+	nx  []uint64
+	lx  []uint64
+	auN float64
+
+	ngx  []uint64 // 0 if the total length is smaller than x% of the genome size
+	lgx  []uint64
+	auNG float64
+
+	gc         float64
+	nPer100Kbp float64
+}
+
+func newAssemblyStats(level string, lens []uint64, gc, n uint64,
+	nx []float64, genomeSize uint64, thresholds []uint64) *assemblyStats {
+
+	s := &assemblyStats{
+		level:  level,
+		num:    uint64(len(lens)),
+		numGEs: make([]uint64, len(thresholds)),
+		sumGEs: make([]uint64, len(thresholds)),
+		nx:     make([]uint64, len(nx)),
+		lx:     make([]uint64, len(nx)),
+	}
+	if genomeSize > 0 {
+		s.ngx = make([]uint64, len(nx))
+		s.lgx = make([]uint64, len(nx))
+	}
+	if len(lens) == 0 {
+		return s
+	}
+
+	sort.Slice(lens, func(i, j int) bool { return lens[i] > lens[j] })
+	s.max, s.min = lens[0], lens[len(lens)-1]
+
+	var sumSquare float64
+	for _, l := range lens {
+		s.sum += l
+		sumSquare += float64(l) * float64(l)
+		for i, t := range thresholds {
+			if l >= t {
+				s.numGEs[i]++
+				s.sumGEs[i] += l
+			}
+		}
+	}
+	s.auN = sumSquare / float64(s.sum)
+	if genomeSize > 0 {
+		s.auNG = sumSquare / float64(genomeSize)
+	}
+	if s.sum > n { // N's are excluded, the same as QUAST
+		s.gc = float64(gc) / float64(s.sum-n) * 100
+	}
+	s.nPer100Kbp = float64(n) / float64(s.sum) * 100000
+
+	// Nx and NGx
+	var cum uint64
+	var j int
+	boundaries := make([]float64, len(nx))
+	for i, x := range nx {
+		boundaries[i] = float64(s.sum) * x / 100
+	}
+	var boundariesG []float64
+	if genomeSize > 0 {
+		boundariesG = make([]float64, len(nx))
+		for i, x := range nx {
+			boundariesG[i] = float64(genomeSize) * x / 100
+		}
+	}
+	for i, l := range lens {
+		cum += l
+		for j = range nx {
+			if s.lx[j] == 0 && float64(cum) >= boundaries[j] {
+				s.nx[j], s.lx[j] = l, uint64(i+1)
+			}
+			if genomeSize > 0 && s.lgx[j] == 0 && float64(cum) >= boundariesG[j] {
+				s.ngx[j], s.lgx[j] = l, uint64(i+1)
+			}
+		}
+	}
+
+	return s
+}
+
+// assemblyStatsColumns returns column names of assembly statistics.
+func assemblyStatsColumns(hasGroup bool, _NX []string, genomeSize uint64, thresholds []uint64) []string {
+	colnames := []string{"file"}
+	if hasGroup {
+		colnames = append(colnames, "group")
+	}
+	colnames = append(colnames, "level", "num_seqs", "sum_len", "min_len", "max_len")
+	for _, t := range thresholds {
+		colnames = append(colnames, fmt.Sprintf("num_seqs(>=%d)", t))
+	}
+	for _, t := range thresholds {
+		colnames = append(colnames, fmt.Sprintf("sum_len(>=%d)", t))
+	}
+	for _, x := range _NX {
+		colnames = append(colnames, "N"+x)
+	}
+	colnames = append(colnames, "auN")
+	for _, x := range _NX {
+		colnames = append(colnames, "L"+x)
+	}
+	if genomeSize > 0 {
+		for _, x := range _NX {
+			colnames = append(colnames, "NG"+x)
+		}
+		colnames = append(colnames, "auNG")
+		for _, x := range _NX {
+			colnames = append(colnames, "LG"+x)
+		}
+	}
+	colnames = append(colnames, "GC(%)", "N_per_100kbp")
+	return colnames
+}
+
+// row returns values of a row, integers are formatted with commas when humanizeNumbers is true.
+func (s *assemblyStats) row(file string, group string, hasGroup bool, genomeSize uint64, humanizeNumbers bool) []string {
+	u := func(v uint64) string {
+		if humanizeNumbers {
+			return humanize.Comma(int64(v))
+		}
+		return strconv.FormatUint(v, 10)
+	}
+	f := func(v float64, prec int) string {
+		return strconv.FormatFloat(v, 'f', prec, 64)
+	}
+
+	row := []string{file}
+	if hasGroup {
+		row = append(row, group)
+	}
+	row = append(row, s.level, u(s.num), u(s.sum), u(s.min), u(s.max))
+	for _, v := range s.numGEs {
+		row = append(row, u(v))
+	}
+	for _, v := range s.sumGEs {
+		row = append(row, u(v))
+	}
+	for _, v := range s.nx {
+		row = append(row, u(v))
+	}
+	row = append(row, f(s.auN, 1))
+	for _, v := range s.lx {
+		row = append(row, u(v))
+	}
+	if genomeSize > 0 {
+		for _, v := range s.ngx {
+			row = append(row, u(v))
+		}
+		row = append(row, f(s.auNG, 1))
+		for _, v := range s.lgx {
+			row = append(row, u(v))
+		}
+	}
+	row = append(row, f(s.gc, 2), f(s.nPer100Kbp, 2))
+	return row
+}
+
+// writeAssemblyStats outputs assembly statistics of all files.
+func writeAssemblyStats(outfh *xopen.Writer, statInfos []statInfo, tabular bool, style *stable.TableStyle,
+	hasGroup bool, _NX []string, genomeSize uint64, thresholds []uint64) {
+
+	colnames := assemblyStatsColumns(hasGroup, _NX, genomeSize, thresholds)
+
+	if tabular {
+		for i, c := range colnames {
+			if i > 0 {
+				outfh.WriteString("\t")
+			}
+			outfh.WriteString(c)
+		}
+		outfh.WriteString("\n")
+		for _, info := range statInfos {
+			for _, s := range info.asm {
+				for i, v := range s.row(info.file, info.group, hasGroup, genomeSize, false) {
+					if i > 0 {
+						outfh.WriteString("\t")
+					}
+					outfh.WriteString(v)
+				}
+				outfh.WriteString("\n")
+			}
+		}
+		return
+	}
+
+	columns := make([]stable.Column, len(colnames))
+	for i, c := range colnames {
+		columns[i] = stable.Column{Header: c}
+		if i > 1 && !(hasGroup && i == 2) {
+			columns[i].Align = stable.AlignRight
+		}
+	}
+	tbl := stable.New()
+	tbl.HeaderWithFormat(columns)
+	for _, info := range statInfos {
+		for _, s := range info.asm {
+			values := s.row(info.file, info.group, hasGroup, genomeSize, true)
+			row := make([]interface{}, len(values))
+			for i, v := range values {
+				row[i] = v
+			}
+			tbl.AddRow(row)
+		}
+	}
+	outfh.Write(tbl.Render(style))
+}
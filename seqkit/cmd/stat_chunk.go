@@ -0,0 +1,511 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/shenwei356/bio/seq"
+	"github.com/shenwei356/bio/seqio/fastx"
+)
+
+// statChunks are regions of a plain or multi-member gzip (including BGZF) file,
+// which could be parsed independently.
+//
+// A chunk starts at an offset of the plain file or a start of a gzip member,
+// and the partial record at the beginning of a chunk belongs to the previous chunk.
+// So the data of chunk i are:
+//
+//	data from offsets[i] to offsets[i+1], with the first heads[i] bytes skipped,
+//	plus the first heads[i+1] bytes from offsets[i+1].
+type statChunks struct {
+	file    string
+	gz      bool
+	isFastq bool
+
+	offsets []int64 // start offsets of chunks, with the file size appended
+	heads   []int64 // lengths of the partial records at the beginning of chunks
+}
+
+// gzip member scanning window for non-BGZF files
+const statGzipScanWindow = 1 << 20
+
+// splitStatFile splits a plain or multi-member gzip file into chunks of
+// about chunkSize bytes. Nil is returned if the file could not be split,
+// e.g., a single-member gzip file, other compression formats,
+// or FASTQ files with sequences in multiple lines.
+func splitStatFile(file string, chunkSize int64) (*statChunks, error) {
+	fh, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	info, err := fh.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if !info.Mode().IsRegular() || size < chunkSize*2 {
+		return nil, nil
+	}
+
+	header := make([]byte, 18)
+	n, _ := fh.ReadAt(header, 0)
+	c := &statChunks{file: file, gz: n >= 2 && header[0] == 0x1f && header[1] == 0x8b}
+	bgzf := c.gz && isBGZFHeader(header[:n])
+
+	// format
+	r, done, err := c.open(fh, 0, size)
+	if err != nil {
+		return nil, nil
+	}
+	ok := c.checkFormat(r)
+	done()
+	if !ok {
+		return nil, nil
+	}
+
+	// chunk offsets
+	c.offsets = []int64{0}
+	var offset int64
+	for target := chunkSize; target < size; target += chunkSize {
+		if !c.gz {
+			c.offsets = append(c.offsets, target)
+			continue
+		}
+		if target <= c.offsets[len(c.offsets)-1] {
+			continue
+		}
+		if bgzf { // the size of a BGZF block is <= 64 KiB
+			offset, err = nextGzipMember(fh, target, min(size, target+65536+1), size, true)
+		} else {
+			offset, err = nextGzipMember(fh, target, min(size, target+statGzipScanWindow), size, false)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if offset > 0 {
+			c.offsets = append(c.offsets, offset)
+		}
+	}
+	if len(c.offsets) == 1 {
+		return nil, nil
+	}
+	c.offsets = append(c.offsets, size)
+
+	// partial records
+	c.heads = make([]int64, len(c.offsets))
+	for i := 1; i < len(c.offsets)-1; i++ {
+		r, done, err = c.open(fh, c.offsets[i], size)
+		if err != nil {
+			return nil, err
+		}
+		c.heads[i], err = c.partialRecordLen(r)
+		done()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// open returns a reader of the (decompressed) data from the offset start to end.
+func (c *statChunks) open(fh *os.File, start, end int64) (io.Reader, func(), error) {
+	section := io.NewSectionReader(fh, start, end-start)
+	if !c.gz {
+		return bufio.NewReaderSize(section, 65536), func() {}, nil
+	}
+	gr, err := gzip.NewReader(bufio.NewReaderSize(section, 65536))
+	if err != nil {
+		return nil, nil, err
+	}
+	return gr, func() { gr.Close() }, nil
+}
+
+// checkFormat checks the first record, FASTQ records should be in four lines.
+func (c *statChunks) checkFormat(r io.Reader) bool {
+	br := bufio.NewReader(r)
+	first, err := br.Peek(1)
+	if err != nil {
+		return false
+	}
+	switch first[0] {
+	case '>':
+		return true
+	case '@':
+		c.isFastq = true
+	default:
+		return false
+	}
+
+	var line []byte
+	lines := make([][]byte, 0, 4)
+	for i := 0; i < 4; i++ {
+		line, err = br.ReadBytes('\n')
+		if err != nil && !(err == io.EOF && i == 3) {
+			return false
+		}
+		lines = append(lines, bytes.TrimRight(line, "\r\n"))
+	}
+	return len(lines[2]) > 0 && lines[2][0] == '+' && len(lines[1]) == len(lines[3])
+}
+
+// partialRecordLen returns the number of bytes before the first record start,
+// which is searched after the first line break, because the data might start
+// in the middle of a line. Therefore it is consistent for the previous chunk
+// and the current one.
+func (c *statChunks) partialRecordLen(r io.Reader) (int64, error) {
+	br := bufio.NewReaderSize(r, 65536)
+
+	var n, start int64
+	var first byte
+	var line []byte
+	var err error
+	var starts [3]int64  // starts of the last three lines
+	var firsts [3]byte   // first characters of the last three lines
+	for i := -1; ; i++ { // the first line is ignored
+		start = n
+		first = 0
+		for {
+			line, err = br.ReadSlice('\n')
+			if first == 0 && len(line) > 0 {
+				first = line[0]
+			}
+			n += int64(len(line))
+			if err != bufio.ErrBufferFull {
+				break
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return n, nil
+			}
+			return 0, err
+		}
+
+		if i < 0 {
+			continue
+		}
+		if !c.isFastq {
+			if first == '>' {
+				return start, nil
+			}
+			continue
+		}
+
+		// FASTQ: a header line followed by a sequence line and a "+" line
+		starts[0], starts[1], starts[2] = starts[1], starts[2], start
+		firsts[0], firsts[1], firsts[2] = firsts[1], firsts[2], first
+		if i >= 2 && firsts[0] == '@' && firsts[2] == '+' {
+			return starts[0], nil
+		}
+	}
+}
+
+// reader returns a reader of the data of chunk i.
+func (c *statChunks) reader(fh *os.File, i int) (io.Reader, func(), error) {
+	size := c.offsets[len(c.offsets)-1]
+	r, done, err := c.open(fh, c.offsets[i], c.offsets[i+1])
+	if err != nil {
+		return nil, nil, err
+	}
+	if i+1 < len(c.offsets)-1 && c.heads[i+1] > 0 { // the last record of this chunk
+		r2, done2, err := c.open(fh, c.offsets[i+1], size)
+		if err != nil {
+			done()
+			return nil, nil, err
+		}
+		r = io.MultiReader(r, io.LimitReader(r2, c.heads[i+1]))
+		done1 := done
+		done = func() {
+			done1()
+			done2()
+		}
+	}
+	if c.heads[i] > 0 {
+		if _, err = io.CopyN(io.Discard, r, c.heads[i]); err != nil && err != io.EOF {
+			done()
+			return nil, nil, err
+		}
+	}
+	return r, done, nil
+}
+
+// nextGzipMember returns the offset of the first gzip member in [start, end),
+// 0 is returned if not found.
+func nextGzipMember(fh *os.File, start, end, size int64, bgzf bool) (int64, error) {
+	magic := []byte{0x1f, 0x8b, 0x08}
+	buf := make([]byte, 65536)
+	var n, i, j int
+	var err error
+	for pos := start; pos < end; pos += int64(n - 2) { // in case the magic number is split
+		n, err = fh.ReadAt(buf[:min(int64(len(buf)), size-pos)], pos)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		for i = 0; ; i += j + 1 {
+			j = bytes.Index(buf[i:n], magic)
+			if j < 0 || pos+int64(i+j) >= end {
+				break
+			}
+			if isGzipMember(fh, pos+int64(i+j), size, bgzf) {
+				return pos + int64(i+j), nil
+			}
+		}
+		if n < 3 {
+			break
+		}
+	}
+	return 0, nil
+}
+
+// isGzipMember checks if a gzip member starts at the offset.
+// For BGZF files, the candidate is verified with the block size in the header.
+// For other multi-member gzip files, the candidate is verified by decompressing
+// the member or the first 1 MiB.
+func isGzipMember(fh *os.File, offset int64, size int64, bgzf bool) bool {
+	header := make([]byte, 18)
+	n, _ := fh.ReadAt(header, offset)
+	if n < 10 || header[3]&0xe0 != 0 { // reserved flag bits
+		return false
+	}
+
+	if bgzf {
+		if !isBGZFHeader(header[:n]) {
+			return false
+		}
+		next := offset + int64(binary.LittleEndian.Uint16(header[16:18])) + 1
+		if next >= size {
+			return next == size
+		}
+		n, _ = fh.ReadAt(header[:3], next)
+		return n == 3 && header[0] == 0x1f && header[1] == 0x8b && header[2] == 0x08
+	}
+
+	gr, err := gzip.NewReader(bufio.NewReader(io.NewSectionReader(fh, offset, size-offset)))
+	if err != nil {
+		return false
+	}
+	defer gr.Close()
+	gr.Multistream(false)
+	_, err = io.CopyN(io.Discard, gr, statGzipScanWindow)
+	return err == nil || err == io.EOF
+}
+
+// isBGZFHeader checks if the gzip header contains the BGZF extra field:
+// FEXTRA, XLEN=6, SI1='B', SI2='C', SLEN=2, BSIZE.
+func isBGZFHeader(header []byte) bool {
+	return len(header) >= 18 && header[3]&0x04 != 0 &&
+		binary.LittleEndian.Uint16(header[10:12]) == 6 &&
+		header[12] == 'B' && header[13] == 'C' &&
+		binary.LittleEndian.Uint16(header[14:16]) == 2
+}
+
+// statFileCounts holds counters of a file or a chunk.
+type statFileCounts struct {
+	counter  *statCounter
+	counters map[string]*statCounter // counters of groups, with --group-by-header
+
+	seqFormat string
+	alphabet  *seq.Alphabet
+}
+
+// merge merges counters of another chunk.
+func (c *statFileCounts) merge(o *statFileCounts) {
+	if c.seqFormat == "" {
+		c.seqFormat = o.seqFormat
+	}
+	c.counter.merge(o.counter)
+	for group, counter := range o.counters {
+		if counter0, ok := c.counters[group]; ok {
+			counter0.merge(counter)
+		} else {
+			c.counters[group] = counter
+		}
+	}
+}
+
+// countStatRecords counts all records of a reader. The reader is not closed.
+func countStatRecords(fastxReader *fastx.Reader, opt *statOptions, quit *bool) (*statFileCounts, bool, error) {
+	counts := &statFileCounts{counter: newStatCounter(opt)}
+	if opt.groupHeaderRe != nil {
+		counts.counters = make(map[string]*statCounter, 8)
+	}
+
+	counter := counts.counter
+	var group string
+	var m [][]byte
+	var ok bool
+	var record *fastx.Record
+	var err error
+	checkSeqType := true
+	var isNucleotide bool
+	for {
+		record, err = fastxReader.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, false, err
+		}
+
+		// for early quit
+		if *quit {
+			return nil, true, nil
+		}
+
+		if checkSeqType {
+			checkSeqType = false
+
+			if fastxReader.IsFastq {
+				counts.seqFormat = "FASTQ"
+			} else {
+				counts.seqFormat = "FASTA"
+			}
+
+			isNucleotide = fastxReader.Alphabet() == seq.DNA ||
+				fastxReader.Alphabet() == seq.DNAredundant ||
+				fastxReader.Alphabet() == seq.RNA || fastxReader.Alphabet() == seq.RNAredundant
+		}
+
+		if opt.groupHeaderRe != nil {
+			if m = opt.groupHeaderRe.FindSubmatch(record.Name); m == nil {
+				group = statGroupNA
+			} else {
+				group = string(m[1])
+			}
+			if counter, ok = counts.counters[group]; !ok {
+				counter = newStatCounter(opt)
+				counts.counters[group] = counter
+			}
+		}
+
+		counter.add(record, fastxReader.IsFastq, isNucleotide)
+	}
+
+	counts.alphabet = fastxReader.Alphabet()
+	return counts, false, nil
+}
+
+// countStatChunks counts records of chunks of a file in parallel.
+func countStatChunks(chunks *statChunks, alphabet *seq.Alphabet, idRegexp string, threads int,
+	opt *statOptions, quit *bool) (*statFileCounts, bool, error) {
+
+	// guess the alphabet with the first record, which is used for all chunks
+	if alphabet == nil {
+		fastxReader, err := fastx.NewReader(alphabet, chunks.file, idRegexp)
+		if err != nil {
+			return nil, false, err
+		}
+		_, err = fastxReader.Read()
+		if err != nil && err != io.EOF {
+			fastxReader.Close()
+			return nil, false, err
+		}
+		alphabet = fastxReader.Alphabet()
+		fastxReader.Close()
+	}
+
+	n := len(chunks.offsets) - 1
+	results := make([]*statFileCounts, n)
+	errs := make([]error, n)
+	quits := make([]bool, n) // written by the goroutines separately, checked after all done
+	var wg sync.WaitGroup
+	tokens := make(chan int, threads)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		tokens <- 1
+		go func(i int) {
+			defer func() {
+				wg.Done()
+				<-tokens
+			}()
+
+			fh, err := os.Open(chunks.file)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer fh.Close()
+
+			r, done, err := chunks.reader(fh, i)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer done()
+
+			br := bufio.NewReaderSize(r, 65536)
+			if _, err = br.Peek(1); err != nil { // empty chunk
+				if err != io.EOF {
+					errs[i] = err
+				}
+				return
+			}
+
+			fastxReader, err := fastx.NewReaderFromIO(alphabet, br, idRegexp)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			results[i], quits[i], errs[i] = countStatRecords(fastxReader, opt, quit)
+			fastxReader.Close()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, q := range quits {
+		if q {
+			return nil, true, nil
+		}
+	}
+
+	var counts *statFileCounts
+	for i, result := range results {
+		if errs[i] != nil {
+			return nil, false, errs[i]
+		}
+		if result == nil {
+			continue
+		}
+		if counts == nil {
+			counts = result
+			continue
+		}
+		counts.merge(result)
+	}
+	if counts == nil {
+		counts = &statFileCounts{counter: newStatCounter(opt)}
+		if opt.groupHeaderRe != nil {
+			counts.counters = make(map[string]*statCounter, 8)
+		}
+	}
+	counts.alphabet = alphabet
+	return counts, false, nil
+}
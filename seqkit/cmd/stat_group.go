@@ -0,0 +1,76 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"path/filepath"
+	"regexp"
+)
+
+// statGroupNA is the group of records whose headers do not match the regular expression.
+const statGroupNA = "NA"
+
+// statGroup is a counter of a group of records in a file or a group of files.
+type statGroup struct {
+	file    string // file or file group
+	group   string // group of records
+	counter *statCounter
+}
+
+// statFileGroup returns the group of a file, i.e., the first capture group of
+// the regular expression on the file base name. The label of the file is
+// returned if the file name does not match.
+func statFileGroup(re *regexp.Regexp, file string, label string) string {
+	m := re.FindStringSubmatch(filepath.Base(file))
+	if m == nil {
+		return label
+	}
+	return m[1]
+}
+
+// mergeStatGroups merges counters with the same file group and record group,
+// and summarizes them in the order of their first appearance.
+func mergeStatGroups(infos []statInfo) []statInfo {
+	type key [2]string
+	merged := make(map[key]*statGroup, len(infos))
+	keys := make([]key, 0, len(infos))
+	var k key
+	var g0 *statGroup
+	var ok bool
+	for _, info := range infos {
+		for _, g := range info.groups {
+			k = key{g.file, g.group}
+			if g0, ok = merged[k]; !ok {
+				merged[k] = g
+				keys = append(keys, k)
+				continue
+			}
+			g0.counter.merge(g.counter)
+		}
+	}
+
+	infos2 := make([]statInfo, len(keys))
+	for i, k := range keys {
+		g0 = merged[k]
+		infos2[i] = g0.counter.info(g0.file, g0.group)
+	}
+	return infos2
+}
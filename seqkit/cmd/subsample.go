@@ -0,0 +1,339 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/shenwei356/bio/seq"
+	"github.com/shenwei356/bio/seqio/fastx"
+	"github.com/shenwei356/xopen"
+	"github.com/spf13/cobra"
+)
+
+// subsampleCmd represents the subsample command
+var subsampleCmd = &cobra.Command{
+	GroupID: "set",
+
+	Use:   "subsample",
+	Short: "subsample reads to a target number of bases or coverage",
+	Long: `subsample reads to a target number of bases or coverage.
+
+The target could be given as a number of bases (-b/--bases), or a coverage
+(-c/--coverage) with a genome size (-g/--genome-size), i.e.,
+bases = coverage * genome size. Both support K/M/G suffixes (K=1024).
+
+Reads are selected until the total bases reach the target. The last
+selected read may exceed the target a little. All reads are outputted
+if the total bases are less than the target.
+
+Selection modes:
+  1. Random (default). Reads are chosen randomly, and the result is
+     deterministic with the same random seed (-s/--rand-seed).
+  2. Best reads (-B/--best). Reads are chosen in descending order of the score:
+         score = length ^ length-weight * mean_quality ^ qual-weight
+     where mean_quality is the Phred score of the mean error probability,
+     and it is ignored for FASTA records. E.g., use "--qual-weight 0"
+     to choose the longest reads.
+
+Two-pass mode:
+  In the first pass, lengths and scores of all reads are indexed in
+  memory (about 24 bytes per read). Then reads are chosen with the index,
+  and are outputted in the second pass in their input order. So it works
+  on large gzipped FASTQ files. Data from stdin are saved in a temporary
+  file in --tmp-dir.
+
+Attention:
+  1. Multiple input files are treated as a single dataset.
+  2. It is designed for single-end reads, e.g., long reads. Mates of paired-end
+     reads would be chosen independently.
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := getConfigs(cmd)
+		outFile := config.OutFile
+		quiet := config.Quiet
+		seq.AlphabetGuessSeqLengthThreshold = config.AlphabetGuessSeqLength
+		seq.ValidateSeq = false
+		runtime.GOMAXPROCS(config.Threads)
+
+		files := getFileListFromArgsAndFile(cmd, args, true, "infile-list", !config.SkipFileCheck)
+		if !config.SkipFileCheck {
+			for _, file := range files {
+				checkIfFilesAreTheSame(file, outFile, "input", "output")
+			}
+		}
+
+		basesS := getFlagString(cmd, "bases")
+		coverage := getFlagFloat64(cmd, "coverage")
+		genomeSizeS := getFlagString(cmd, "genome-size")
+		best := getFlagBool(cmd, "best")
+		lenWeight := getFlagFloat64(cmd, "length-weight")
+		qualWeight := getFlagFloat64(cmd, "qual-weight")
+		minLen := getFlagNonNegativeInt(cmd, "min-len")
+		minQual := getFlagFloat64(cmd, "min-qual")
+		qBase := getFlagPositiveInt(cmd, "qual-ascii-base")
+		seed := getFlagInt64(cmd, "rand-seed")
+		nonDeterministic := getFlagBool(cmd, "non-deterministic")
+		tmpDir := getFlagString(cmd, "tmp-dir")
+
+		if coverage < 0 || lenWeight < 0 || qualWeight < 0 || minQual < 0 {
+			checkError(fmt.Errorf("values of flags -c/--coverage, --length-weight, --qual-weight, and -q/--min-qual should not be negative"))
+		}
+		if nonDeterministic && cmd.Flags().Lookup("rand-seed").Changed {
+			checkError(fmt.Errorf("the flags -s/--rand-seed and -r/--non-deterministic are incompatible"))
+		}
+		if best && (nonDeterministic || cmd.Flags().Lookup("rand-seed").Changed) {
+			log.Warningf("flags -s/--rand-seed and -r/--non-deterministic are ignored with -B/--best")
+		}
+		if !best && (cmd.Flags().Lookup("length-weight").Changed || cmd.Flags().Lookup("qual-weight").Changed) {
+			checkError(fmt.Errorf("flags --length-weight and --qual-weight should be used with -B/--best"))
+		}
+
+		var target int64
+		var genomeSize int64
+		var err error
+		if basesS != "" {
+			if coverage > 0 || genomeSizeS != "" {
+				checkError(fmt.Errorf("flag -b/--bases is incompatible with -c/--coverage and -g/--genome-size"))
+			}
+			target, err = ParseByteSize(basesS)
+			if err != nil {
+				checkError(fmt.Errorf("invalid value of flag -b/--bases: %s", basesS))
+			}
+		} else {
+			if coverage == 0 || genomeSizeS == "" {
+				checkError(fmt.Errorf("flag -b/--bases, or both -c/--coverage and -g/--genome-size needed"))
+			}
+			genomeSize, err = ParseByteSize(genomeSizeS)
+			if err != nil {
+				checkError(fmt.Errorf("invalid value of flag -g/--genome-size: %s", genomeSizeS))
+			}
+			target = int64(math.Ceil(coverage * float64(genomeSize)))
+		}
+		if target <= 0 {
+			checkError(fmt.Errorf("the target number of bases should be positive"))
+		}
+
+		outfh, err := xopen.Wopen(outFile)
+		checkError(err)
+		defer outfh.Close()
+
+		// stdin
+		var dir string // temporary directory for saving data from stdin
+		// checkErr removes the temporary directory before exiting,
+		// as deferred functions are not called by os.Exit.
+		checkErr := func(err error) {
+			if err != nil && dir != "" {
+				os.RemoveAll(dir)
+			}
+			checkError(err)
+		}
+		for _, file := range files {
+			if isStdin(file) {
+				checkError(os.MkdirAll(tmpDir, 0755))
+				dir, err = os.MkdirTemp(tmpDir, "seqkit-subsample-*")
+				checkError(err)
+				defer func() {
+					checkError(os.RemoveAll(dir))
+				}()
+				files, err = copyStdinForRereading(files, dir, quiet)
+				checkErr(err)
+				break
+			}
+		}
+
+		// -----------------------------------------------------------
+		// first pass: index of lengths and scores
+
+		if !quiet {
+			log.Infof("first pass: indexing lengths and scores of reads ...")
+		}
+
+		index := make([]subsampleRead, 0, 1<<16)
+		var record *fastx.Record
+		var idx uint64
+		var total int64
+		var length int
+		var qual float64
+		var r subsampleRead
+		for _, file := range files {
+			fastxReader, err := fastx.NewReader(config.Alphabet, file, config.IDRegexp)
+			checkErr(err)
+			for ; ; idx++ {
+				record, err = fastxReader.Read()
+				if err != nil {
+					if err == io.EOF {
+						break
+					}
+					checkErr(err)
+					break
+				}
+
+				length = len(record.Seq.Seq)
+				if length < minLen {
+					continue
+				}
+				qual = 0
+				if len(record.Seq.Qual) > 0 && (minQual > 0 || best && qualWeight > 0) {
+					qual = record.Seq.AvgQual(qBase)
+					if qual < minQual {
+						continue
+					}
+				}
+
+				r = subsampleRead{idx: idx, length: uint32(length)}
+				if best {
+					r.score = math.Pow(float64(length), lenWeight)
+					if qual > 0 {
+						r.score *= math.Pow(qual, qualWeight)
+					}
+				}
+				index = append(index, r)
+				total += int64(length)
+			}
+			fastxReader.Close()
+		}
+
+		if !quiet {
+			if genomeSize > 0 {
+				log.Infof("%d reads with %d bases (%.2fX) passed the filters, target: %d bases (%.2fX)",
+					len(index), total, float64(total)/float64(genomeSize), target, coverage)
+			} else {
+				log.Infof("%d reads with %d bases passed the filters, target: %d bases", len(index), total, target)
+			}
+		}
+
+		// -----------------------------------------------------------
+		// choose reads
+
+		if best {
+			sort.Slice(index, func(i, j int) bool {
+				if index[i].score != index[j].score {
+					return index[i].score > index[j].score
+				}
+				return index[i].idx < index[j].idx
+			})
+		} else {
+			var _rand *rand.Rand
+			if nonDeterministic {
+				_rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+			} else {
+				_rand = rand.New(rand.NewSource(seed))
+			}
+			_rand.Shuffle(len(index), func(i, j int) { index[i], index[j] = index[j], index[i] })
+		}
+
+		var chosen bitSet
+		var sum int64
+		var n int
+		for _, r = range index {
+			if sum >= target {
+				break
+			}
+			chosen.Set(r.idx)
+			sum += int64(r.length)
+			n++
+		}
+		index = nil
+
+		if sum < target {
+			log.Warningf("the total bases (%d) of reads passed the filters are less than the target (%d)", sum, target)
+		}
+
+		// -----------------------------------------------------------
+		// second pass: output
+
+		if !quiet {
+			log.Infof("second pass: outputting %d chosen reads ...", n)
+		}
+
+		idx = 0
+		for _, file := range files {
+			fastxReader, err := fastx.NewReader(config.Alphabet, file, config.IDRegexp)
+			checkErr(err)
+			checkAlphabet := true
+			for ; ; idx++ {
+				record, err = fastxReader.Read()
+				if err != nil {
+					if err == io.EOF {
+						break
+					}
+					checkErr(err)
+					break
+				}
+				if checkAlphabet {
+					if fastxReader.IsFastq {
+						if !config.LineWidthChanged {
+							config.LineWidth = 0
+						}
+						fastx.ForcelyOutputFastq = true
+					}
+					checkAlphabet = false
+				}
+
+				if chosen.Has(idx) {
+					record.FormatToWriter(outfh, config.LineWidth)
+				}
+			}
+			fastxReader.Close()
+		}
+
+		if !quiet {
+			if genomeSize > 0 {
+				log.Infof("%d reads with %d bases (%.2fX) outputted", n, sum, float64(sum)/float64(genomeSize))
+			} else {
+				log.Infof("%d reads with %d bases outputted", n, sum)
+			}
+		}
+	},
+}
+
+// subsampleRead is the index of a read in subsample.
+type subsampleRead struct {
+	idx    uint64
+	length uint32
+	score  float64
+}
+
+func init() {
+	RootCmd.AddCommand(subsampleCmd)
+
+	subsampleCmd.Flags().StringP("bases", "b", "", "target number of bases (supports K/M/G suffix)")
+	subsampleCmd.Flags().Float64P("coverage", "c", 0, "target coverage, used along with -g/--genome-size")
+	subsampleCmd.Flags().StringP("genome-size", "g", "", "genome size (supports K/M/G suffix), used along with -c/--coverage")
+	subsampleCmd.Flags().BoolP("best", "B", false, "choose the best reads in descending order of score, instead of randomly")
+	subsampleCmd.Flags().Float64P("length-weight", "", 1, "weight of read length in the score, for -B/--best")
+	subsampleCmd.Flags().Float64P("qual-weight", "", 1, "weight of mean quality in the score, for -B/--best")
+	subsampleCmd.Flags().IntP("min-len", "m", 0, "minimum read length")
+	subsampleCmd.Flags().Float64P("min-qual", "q", 0, "minimum mean quality")
+	subsampleCmd.Flags().IntP("qual-ascii-base", "", 33, "ASCII BASE, 33 for Phred+33")
+	subsampleCmd.Flags().Int64P("rand-seed", "s", 11, "random seed")
+	subsampleCmd.Flags().BoolP("non-deterministic", "r", false, "use a time-based seed to generate non-deterministic (truly random) results")
+	subsampleCmd.Flags().StringP("tmp-dir", "", "./", "directory for saving data from stdin")
+}
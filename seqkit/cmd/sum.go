@@ -61,6 +61,43 @@ Attention:
      - The message digest would change with different values of k-mer size.
   4. Multiple files are processed in parallel (-j/--threads).
 
+Per-sequence manifest (-m/--manifest):
+  The message digest of a file tells whether the file changed, but not which
+  sequences. A per-sequence manifest, with the columns of file, id, length and
+  digest (MD5 of the processed sequence, see Method), could be saved for
+  auditing updates of reference sequences or data transfers.
+
+Checking (--check):
+  Input files are verified against a previous output of "seqkit sum",
+  i.e., a per-sequence manifest or file digests. Files are matched by names
+  (also affected by -b/--basename), or compared directly if both the input
+  and the previous output contain only one file. Please use the same flags
+  (e.g., -g, --rna2dna, -c) when creating and checking the digests.
+
+  1. For file digests, the status of each file (OK or FAILED) is outputted.
+  2. For a manifest, records with different IDs or digests are outputted with
+     the columns of file, status, id, length, new_id and new_length.
+     Records with the same ID and digest are unchanged, then the status is:
+       changed   the same ID, different sequences
+       renamed   the same sequence, different IDs
+       added     new records
+       removed   records not existing anymore
+  3. The exit status is non-zero if any file fails the check.
+
+GA4GH digests:
+  1. --seqcol outputs the level-0 digest of the sequence collection
+     (https://ga4gh.github.io/refget/seqcols/) of each file instead, for
+     detecting equivalent assemblies and matching public registries.
+     Names are sequence IDs, and sequences are upper-cased refget identifiers.
+     Since names are inherent attributes, the same sequences with different
+     names have different level-0 digests, but the same level-1 digest of
+     sequences. With -a/--all, the number of sequences, total length, and
+     level-1 digests of names, lengths, sequences, and sorted_name_length_pairs
+     are also outputted.
+  2. --refget uses refget identifiers (sha512t24u of upper-cased sequences)
+     as per-sequence digests in the manifest (-m/--manifest).
+  3. Neither supports circular genomes (-c/--circular).
+
 Method:
   1. Converting the sequences to low cases, optionally removing gaps (-g).
   2. Computing the hash (xxhash) for all sequences or k-mers of a circular
@@ -108,6 +145,38 @@ Examples:
 		all := getFlagBool(cmd, "all")
 		rna2dna := getFlagBool(cmd, "rna2dna")
 		singleStrand := getFlagBool(cmd, "single-strand")
+		manifest := getFlagBool(cmd, "manifest")
+		checkFile := getFlagString(cmd, "check")
+		check := checkFile != ""
+		seqcol := getFlagBool(cmd, "seqcol")
+		refget := getFlagBool(cmd, "refget")
+
+		if manifest && circular {
+			checkError(fmt.Errorf("the flag -m/--manifest is not supported for circular genomes (-c/--circular)"))
+		}
+		if manifest && check {
+			checkError(fmt.Errorf("the flags -m/--manifest and --check are incompatible"))
+		}
+		if (seqcol || refget) && circular {
+			checkError(fmt.Errorf("the flags --seqcol and --refget are not supported for circular genomes (-c/--circular)"))
+		}
+		if seqcol && manifest {
+			checkError(fmt.Errorf("the flag --seqcol is for file digests, please use --refget for a manifest with refget digests"))
+		}
+		if refget && !(manifest || check) {
+			checkError(fmt.Errorf("the flag --refget only works with -m/--manifest or --check"))
+		}
+		var ref *sumReference
+		var err error
+		if check {
+			ref, err = readSumReference(checkFile)
+			checkError(err)
+			if ref.manifest && circular {
+				checkError(fmt.Errorf("a manifest can not be used for checking circular genomes (-c/--circular)"))
+			}
+		}
+		// per-sequence digests are needed
+		needRecords := manifest || (check && ref.manifest)
 
 		files := getFileListFromArgsAndFile(cmd, args, true, "infile-list", !config.SkipFileCheck)
 		if !config.SkipFileCheck {
@@ -153,6 +222,16 @@ Examples:
 		checkError(err)
 		defer outfh.Close()
 
+		if manifest {
+			fmt.Fprintln(outfh, sumManifestHeader)
+		} else if check {
+			if ref.manifest {
+				fmt.Fprintln(outfh, "file\tstatus\tid\tlength\tnew_id\tnew_length")
+			} else {
+				fmt.Fprintln(outfh, "file\tstatus")
+			}
+		}
+
 		tokens := make(chan int, config.Threads)
 		done := make(chan int)
 		threadsFloat := float64(config.Threads) // just avoid repeated type conversion
@@ -165,6 +244,36 @@ Examples:
 		}
 		ch := make(chan *Aresult, config.Threads)
 
+		var nFailed int // number of files failing the check
+		write := func(r *Aresult) {
+			if !r.ok {
+				if check {
+					nFailed++
+				}
+				return
+			}
+
+			switch {
+			case check:
+				if !ref.check(outfh, r.result, len(files), config.Quiet) {
+					nFailed++
+				}
+			case manifest:
+				for _, rec := range r.result.Records {
+					fmt.Fprintf(outfh, "%s\t%s\t%d\t%s\n", r.result.File, rec.ID, rec.Length, rec.Digest)
+				}
+			case all && seqcol:
+				d := r.result.SeqCol
+				fmt.Fprintf(outfh, "%s\t%s\t%d\t%d\t%s\t%s\t%s\t%s\n", r.result.Digest, r.result.File, r.result.SeqNum, r.result.SeqLen,
+					d.Names, d.Lengths, d.Sequences, d.SortedNameLengthPairs)
+			case all:
+				fmt.Fprintf(outfh, "%s\t%s\t%d\t%d\n", r.result.Digest, r.result.File, r.result.SeqNum, r.result.SeqLen)
+			default:
+				fmt.Fprintf(outfh, "%s\t%s\n", r.result.Digest, r.result.File)
+			}
+			outfh.Flush()
+		}
+
 		go func() {
 			m := make(map[uint64]*Aresult, config.Threads)
 			var id, _id uint64
@@ -176,15 +285,7 @@ Examples:
 				_id = r.id
 
 				if _id == id { // right there
-					if r.ok {
-						if all {
-							fmt.Fprintf(outfh, "%s\t%s\t%d\t%d\n", r.result.Digest, r.result.File, r.result.SeqNum, r.result.SeqLen)
-						} else {
-							fmt.Fprintf(outfh, "%s\t%s\n", r.result.Digest, r.result.File)
-						}
-
-						outfh.Flush()
-					}
+					write(r)
 					id++
 					continue
 				}
@@ -192,14 +293,7 @@ Examples:
 				m[_id] = r // save for later check
 
 				if _r, ok = m[id]; ok { // check buffered
-					if _r.ok {
-						if all {
-							fmt.Fprintf(outfh, "%s\t%s\t%d\t%d\n", _r.result.Digest, _r.result.File, _r.result.SeqNum, _r.result.SeqLen)
-						} else {
-							fmt.Fprintf(outfh, "%s\t%s\n", _r.result.Digest, _r.result.File)
-						}
-						outfh.Flush()
-					}
+					write(_r)
 					delete(m, id)
 					id++
 				}
@@ -214,16 +308,7 @@ Examples:
 				}
 				sortutil.Uint64s(ids)
 				for _, _id = range ids {
-					_r = m[_id]
-
-					if _r.ok {
-						if all {
-							fmt.Fprintf(outfh, "%s\t%s\t%d\t%d\n", _r.result.Digest, _r.result.File, _r.result.SeqNum, _r.result.SeqLen)
-						} else {
-							fmt.Fprintf(outfh, "%s\t%s\n", _r.result.Digest, _r.result.File)
-						}
-						outfh.Flush()
-					}
+					write(m[_id])
 				}
 			}
 			done <- 1
@@ -250,6 +335,13 @@ Examples:
 				var lens int // lengths of all seqs
 				var h uint64
 				hashes := make([]uint64, 0, 1024)
+				var records []SumRecord
+				var digestSeq [md5.Size]byte
+				var collection *SeqCollection
+				if seqcol {
+					collection = NewSeqCollection()
+				}
+				var upper []byte // upper-cased sequence for GA4GH digests
 
 				var record *fastx.Record
 				var fastxReader *fastx.Reader
@@ -496,6 +588,24 @@ Examples:
 
 						hashes = append(hashes, h)
 
+						if seqcol || refget {
+							upper = bytes.ToUpper(_seq.Seq)
+						}
+						if seqcol {
+							collection.Add(string(record.ID), upper)
+						}
+
+						if needRecords {
+							rec := SumRecord{ID: string(record.ID), Length: len(_seq.Seq)}
+							if refget {
+								rec.Digest = "SQ." + sha512t24u(upper)
+							} else {
+								digestSeq = md5.Sum(_seq.Seq)
+								rec.Digest = hex.EncodeToString(digestSeq[:])
+							}
+							records = append(records, rec)
+						}
+
 						n++
 						lens += len(_seq.Seq)
 					}
@@ -542,6 +652,12 @@ Examples:
 					k,
 					hex.EncodeToString(digest[:]))
 
+				var digests *SeqCollectionDigests
+				if seqcol {
+					digests = collection.Digests()
+					sum = digests.Level0
+				}
+
 				ch <- &Aresult{
 					id: id,
 					ok: true,
@@ -550,6 +666,9 @@ Examples:
 						SeqNum: n,
 						SeqLen: lens,
 						Digest: sum,
+
+						Records: records,
+						SeqCol:  digests,
 					},
 				}
 			}(file, id)
@@ -565,6 +684,11 @@ Examples:
 
 		close(ch)
 		<-done
+
+		if nFailed > 0 {
+			outfh.Close()
+			checkError(fmt.Errorf("%d of %d files failed the check", nFailed, len(files)))
+		}
 	},
 }
 
@@ -579,6 +703,10 @@ func init() {
 	sumCmd.Flags().BoolP("all", "a", false, "show all information, including the sequences length and the number of sequences")
 	sumCmd.Flags().BoolP("rna2dna", "", false, "convert RNA to DNA")
 	sumCmd.Flags().BoolP("single-strand", "s", false, "only consider the positive strand of a circular genome, e.g., ssRNA virus genomes")
+	sumCmd.Flags().BoolP("manifest", "m", false, "output a per-sequence manifest with the columns of file, id, length and digest")
+	sumCmd.Flags().BoolP("seqcol", "", false, "output GA4GH sequence collection (seqcol) digests instead")
+	sumCmd.Flags().BoolP("refget", "", false, "use GA4GH refget digests (sha512t24u) of sequences in the manifest")
+	sumCmd.Flags().StringP("check", "", "", "verify input files against a previous output (a per-sequence manifest or file digests) of this command")
 }
 
 type SumResult struct {
@@ -586,6 +714,9 @@ type SumResult struct {
 	SeqNum int
 	SeqLen int
 	Digest string
+
+	Records []SumRecord           // per-sequence digests, only for -m/--manifest or --check
+	SeqCol  *SeqCollectionDigests // digests of the sequence collection, only for --seqcol
 }
 
 const sumVersion = "0.1"
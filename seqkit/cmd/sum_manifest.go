@@ -0,0 +1,227 @@
+// Copyright © 2016-2026 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/shenwei356/xopen"
+)
+
+// sumManifestHeader is the header line of a per-sequence manifest.
+const sumManifestHeader = "file\tid\tlength\tdigest"
+
+// SumRecord is an entry of a per-sequence manifest.
+type SumRecord struct {
+	ID     string
+	Length int
+	Digest string
+}
+
+// sumReference is the content of a previous output of "seqkit sum",
+// i.e., a per-sequence manifest, or file digests.
+type sumReference struct {
+	manifest bool
+	files    []string               // in the order of appearance
+	records  map[string][]SumRecord // file -> records, for a manifest
+	digests  map[string]string      // file -> digest, for file digests
+}
+
+// readSumReference reads a per-sequence manifest (with a header line)
+// or file digests (the first two columns are digest and file) outputted by "seqkit sum".
+func readSumReference(file string) (*sumReference, error) {
+	fh, err := xopen.Ropen(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	ref := &sumReference{}
+	scanner := bufio.NewScanner(fh)
+	scanner.Buffer(make([]byte, 0, 65536), 1<<30)
+	var line, f string
+	var items []string
+	var n int
+	var first = true
+	var ok bool
+	for scanner.Scan() {
+		n++
+		line = strings.TrimRight(scanner.Text(), "\r\n")
+		if line == "" {
+			continue
+		}
+		if first {
+			first = false
+			if line == sumManifestHeader {
+				ref.manifest = true
+				ref.records = make(map[string][]SumRecord, 8)
+				continue
+			}
+			ref.digests = make(map[string]string, 8)
+		}
+
+		items = strings.Split(line, "\t")
+		if ref.manifest {
+			if len(items) != 4 {
+				return nil, fmt.Errorf("%s: line %d: four columns expected in a manifest", file, n)
+			}
+			f = items[0]
+			var l int
+			l, err = strconv.Atoi(items[2])
+			if err != nil {
+				return nil, fmt.Errorf("%s: line %d: invalid sequence length: %s", file, n, items[2])
+			}
+			if _, ok = ref.records[f]; !ok {
+				ref.files = append(ref.files, f)
+			}
+			ref.records[f] = append(ref.records[f], SumRecord{ID: items[1], Length: l, Digest: items[3]})
+			continue
+		}
+
+		if len(items) < 2 || items[0] == "" {
+			return nil, fmt.Errorf("%s: line %d: at least two columns (digest and file) expected for file digests", file, n)
+		}
+		f = items[1]
+		if _, ok = ref.digests[f]; ok {
+			return nil, fmt.Errorf("%s: line %d: duplicated file: %s", file, n, f)
+		}
+		ref.files = append(ref.files, f)
+		ref.digests[f] = items[0]
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ref.files) == 0 {
+		return nil, fmt.Errorf("no data found in file: %s", file)
+	}
+	return ref, nil
+}
+
+// lookup returns the reference file name for an input file.
+// If both have only one file, they are compared regardless of the names.
+func (ref *sumReference) lookup(file string, nFiles int) (string, bool) {
+	var ok bool
+	if ref.manifest {
+		_, ok = ref.records[file]
+	} else {
+		_, ok = ref.digests[file]
+	}
+	if ok {
+		return file, true
+	}
+	if nFiles == 1 && len(ref.files) == 1 {
+		return ref.files[0], true
+	}
+	return "", false
+}
+
+// sumDiff is a difference between two sets of sequence records.
+type sumDiff struct {
+	status string // added, removed, renamed, or changed
+	old    *SumRecord
+	new    *SumRecord
+}
+
+// compareSumRecords compares new records to old ones, and returns
+// differences and the number of unchanged records.
+//
+// Records with the same ID and digest are unchanged. Then the remaining
+// ones with the same ID are "changed", and those with the same digest
+// are "renamed". Others are "removed" or "added".
+func compareSumRecords(olds, news []SumRecord) ([]sumDiff, int) {
+	usedOld := make([]bool, len(olds))
+	usedNew := make([]bool, len(news))
+	var nSame int
+
+	diffs := make([]sumDiff, 0, 8)
+	match := func(key func(r *SumRecord) string, status string) {
+		matchByKey(usedOld, usedNew,
+			func(i int) string { return key(&olds[i]) },
+			func(j int) string { return key(&news[j]) },
+			func(i, j int) {
+				if status == "" {
+					nSame++
+					return
+				}
+				diffs = append(diffs, sumDiff{status: status, old: &olds[i], new: &news[j]})
+			})
+	}
+
+	match(func(r *SumRecord) string { return r.ID + "\t" + r.Digest }, "")
+	match(func(r *SumRecord) string { return r.ID }, "changed")
+	match(func(r *SumRecord) string { return r.Digest }, "renamed")
+
+	for j := range news {
+		if !usedNew[j] {
+			diffs = append(diffs, sumDiff{status: "added", new: &news[j]})
+		}
+	}
+	for i := range olds {
+		if !usedOld[i] {
+			diffs = append(diffs, sumDiff{status: "removed", old: &olds[i]})
+		}
+	}
+	return diffs, nSame
+}
+
+// check compares the result of an input file to the reference, outputs
+// the status or differences, and returns true if they are identical.
+func (ref *sumReference) check(outfh *xopen.Writer, r *SumResult, nFiles int, quiet bool) bool {
+	file, ok := ref.lookup(r.File, nFiles)
+	if !ok {
+		log.Warningf("%s: not found in the file digests or manifest", r.File)
+		return false
+	}
+
+	if !ref.manifest {
+		if r.Digest == ref.digests[file] {
+			fmt.Fprintf(outfh, "%s\tOK\n", r.File)
+			return true
+		}
+		fmt.Fprintf(outfh, "%s\tFAILED\n", r.File)
+		return false
+	}
+
+	diffs, nSame := compareSumRecords(ref.records[file], r.Records)
+	counts := make(map[string]int, 4)
+	for _, d := range diffs {
+		counts[d.status]++
+		fmt.Fprintf(outfh, "%s\t%s", r.File, d.status)
+		if d.old != nil {
+			fmt.Fprintf(outfh, "\t%s\t%d", d.old.ID, d.old.Length)
+		} else {
+			fmt.Fprintf(outfh, "\t\t")
+		}
+		if d.new != nil {
+			fmt.Fprintf(outfh, "\t%s\t%d\n", d.new.ID, d.new.Length)
+		} else {
+			fmt.Fprintf(outfh, "\t\t\n")
+		}
+	}
+	if !quiet {
+		log.Infof("%s: %d unchanged, %d changed, %d renamed, %d added, %d removed",
+			r.File, nSame, counts["changed"], counts["renamed"], counts["added"], counts["removed"])
+	}
+	return len(diffs) == 0
+}
@@ -26,7 +26,6 @@ import (
 	"os"
 	"runtime"
 	"sort"
-	"strconv"
 
 	"github.com/shenwei356/bio/seq"
 	"github.com/shenwei356/bio/seqio/fastx"
@@ -103,22 +102,7 @@ Translate Tables/Genetic Codes:
 		if _, ok := seq.CodonTables[translTable]; !ok {
 			checkError(fmt.Errorf("invalid translate table: %d", translTable))
 		}
-		_frames := getFlagStringSlice(cmd, "frame")
-		frames := make([]int, 0, len(_frames))
-		for _, _frame := range _frames {
-			frame, err := strconv.Atoi(_frame)
-			if err != nil {
-				checkError(fmt.Errorf("invalid frame(s): %s. available: 1, 2, 3, -1, -2, -3, and 6 for all. multiple frames should be separated by comma", _frame))
-			}
-			if !(frame == 1 || frame == 2 || frame == 3 || frame == -1 || frame == -2 || frame == -3 || frame == 6) {
-				checkError(fmt.Errorf("invalid frame: %d. available: 1, 2, 3, -1, -2, -3, and 6 for all", frame))
-			}
-			if frame == 6 {
-				frames = []int{1, 2, 3, -1, -2, -3}
-				break
-			}
-			frames = append(frames, frame)
-		}
+		frames := parseFrames(getFlagStringSlice(cmd, "frame"))
 		trim := getFlagBool(cmd, "trim")
 		clean := getFlagBool(cmd, "clean")
 		allowUnknownCodon := getFlagBool(cmd, "allow-unknown-codon")
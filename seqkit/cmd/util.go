@@ -528,3 +528,34 @@ func wrapByteSlice(s []byte, width int, buffer *bytes.Buffer) ([]byte, *bytes.Bu
 	}
 	return buffer.Bytes(), buffer
 }
+
+// matchByKey pairs unused items of two lists with the same key, and calls
+// fn for every pair, where both items are marked as used.
+// The order of items is kept for duplicated keys, i.e., the n-th item of
+// a key in the first list is paired with the n-th one in the second list.
+func matchByKey(usedA, usedB []bool, keyA, keyB func(int) string, fn func(i, j int)) {
+	m := make(map[string][]int, len(usedA))
+	var k string
+	for i, used := range usedA {
+		if used {
+			continue
+		}
+		k = keyA(i)
+		m[k] = append(m[k], i)
+	}
+	var idx []int
+	var ok bool
+	var i int
+	for j, used := range usedB {
+		if used {
+			continue
+		}
+		k = keyB(j)
+		if idx, ok = m[k]; !ok || len(idx) == 0 {
+			continue
+		}
+		i, m[k] = idx[0], idx[1:]
+		usedA[i], usedB[j] = true, true
+		fn(i, j)
+	}
+}
@@ -31,6 +31,69 @@ file=tests/blank1.fx
 run stats $app stats -T $file
 assert_equal 0 $(sed 1d $STDOUT_FILE | cut -f 4)
 
+fun(){
+    echo -e ">scaf1\nACGTACGTAANNNNNNNNNNNNACGTACGTNNNACGT\n>scaf2\nACGTACGTACGTAC\n>ctg3\nACGTAC" \
+        | $app stats -A -T -L 10 -g 60 | cut -f 2-4,9,12,14,17
+}
+run "stats -A" fun
+assert_equal "$(sed -n 2p $STDOUT_FILE)" "$(echo -e 'scaffold\t3\t57\t37\t1\t37\t1')"
+assert_equal "$(sed -n 3p $STDOUT_FILE)" "$(echo -e 'contig\t4\t45\t14\t2\t10\t3')"
+
+# GC content without N's, and genome size with decimal units
+fun(){
+    (echo ">scaf1"; printf 'ACGT%.0s' {1..500}; printf 'N%.0s' {1..100}; printf 'AC%.0s' {1..500}; echo; \
+        echo ">scaf2"; printf 'AT%.0s' {1..750}; echo) \
+        | $app stats -A -T -g 5K | cut -f 2,22,23,26,27 | sed 1d
+}
+run "stats -A with N gaps and -g 5K" fun
+assert_equal "$(sed -n 1p $STDOUT_FILE)" "$(echo -e 'scaffold\t3100\t1500\t2\t33.33')"
+
+fun(){
+    echo -e ">r1 barcode=bc01\nACGTACGT\n>r2 barcode=bc02\nACGTACGTACGT\n>r3 barcode=bc01\nACGT\n>r4\nACGTAC" \
+        | $app stats -T --group-by-header 'barcode=(\S+)' | cut -f 2,5,6
+}
+run "stats --group-by-header" fun
+assert_equal "$(sed 1d $STDOUT_FILE | tr '\t\n' ',;')" "bc01,2,12;bc02,1,12;NA,1,6;"
+
+fun(){
+    $app stats -T --group-by-file '^(.+)_\d\.fq' tests/reads_1.fq.gz tests/reads_2.fq.gz | sed 1d | cut -f 1,4
+    $app stats -T tests/reads_1.fq.gz tests/reads_2.fq.gz | sed 1d | awk '{n+=$4} END {print n}'
+}
+run "stats --group-by-file" fun
+assert_equal "$(head -n 1 $STDOUT_FILE)" "$(echo -e "reads\t$(sed -n 2p $STDOUT_FILE)")"
+
+fun(){
+    zcat tests/hairpin.fa.gz | $app split2 -s 5000 -O stats.tmp -e .gz --quiet
+    cat stats.tmp/*.gz > stats.tmp.fa.gz
+    $app stats -a -T -j 1 stats.tmp.fa.gz
+    $app stats -a -T -j 4 -C 100K stats.tmp.fa.gz
+    rm -rf stats.tmp stats.tmp.fa.gz
+}
+run "stats -C" fun
+assert_equal "$(sed -n 2p $STDOUT_FILE)" "$(sed -n 4p $STDOUT_FILE)"
+
+# tests/reads_1.bgzf.fq.gz was compressed with bgzip
+fun(){
+    $app stats -a -T -j 1 tests/reads_1.bgzf.fq.gz
+    $app stats -a -T -j 4 -C 20K tests/reads_1.bgzf.fq.gz
+}
+run "stats -C with a BGZF file" fun
+assert_equal "$(sed -n 2p $STDOUT_FILE)" "$(sed -n 4p $STDOUT_FILE)"
+
+# ------------------------------------------------------------
+#                        qc
+# ------------------------------------------------------------
+file=tests/reads_1.fq.gz
+fun(){
+    $app qc -O qc.tmp --force $file 2>/dev/null | sed 1d | cut -f 3,4
+    $app stats -T $file | sed 1d | cut -f 4,5
+    sed 1d qc.tmp/reads_1.length_distribution.tsv | wc -l
+    rm -rf qc.tmp
+}
+run qc fun
+assert_equal "$(head -n 1 $STDOUT_FILE)" "$(sed -n 2p $STDOUT_FILE)"
+assert_equal $(sed -n 3p $STDOUT_FILE) 4
+
 # ------------------------------------------------------------
 #                        seq
 # ------------------------------------------------------------
@@ -248,6 +311,33 @@ float_gt $RQ 0.99
 assert_equal $? 1
 rm seqkit.tsv corr_len.tsv corr_qual.tsv
 
+# protein properties
+fun() {
+    echo -e ">ubq\nMQIFVKTLTGKTITLEVEPSDTIENVKAKIQDKEGIPPDQQRLIFAGKQLEDGRTLSDYNIQKESTLHLVLRLRGG" \
+        | $app fx2tab -n --mol-weight --iso-point
+}
+run fx2tab_protein fun
+assert_equal $(cut -f 2,3 $STDOUT_FILE | tr '\t' ,) "8564.84,6.56"
+
+fun() {
+    echo -e ">seq\nAUFWO" | $app fx2tab -n -t protein --gravy --aromaticity
+}
+run "fx2tab_protein with U and O" fun
+assert_equal $(cut -f 2,3 $STDOUT_FILE | tr '\t' ,) "1.2333,0.6667"
+
+fun() {
+    echo -e ">seq\nACGTACGT" | $app fx2tab -n -p
+}
+run fx2tab_protein_dna fun
+assert_in_stderr "protein properties only apply to protein sequences"
+
+# GA4GH refget digests
+fun() {
+    echo -e ">seq\nacgt" | $app fx2tab -n -R
+}
+run fx2tab_refget fun
+assert_equal "$(cut -f 2,3 $STDOUT_FILE | tr '\t' ,)" "SQ.aKF498dAxcJAqme6QYQ7EZ07-fiw8Kw2,f1f8f4bf413b16ad135722aa4591043e"
+
 # ------------------------------------------------------------
 #                       grep
 # ------------------------------------------------------------
@@ -309,6 +399,167 @@ assert_equal $(cat $STDOUT_FILE | seqkit seq -s) ""
 #                       locate
 # ------------------------------------------------------------
 
+$app seq tests/hairpin.fa.gz > tmp-fmi.fa
+$app fmindex tmp-fmi.fa
+p="-p UGAGGUAGUAGGUUGUAUAGUU -p ACGUACGU"
+fun() {
+    $app locate --index -m 1 $p tmp-fmi.fa | sort
+}
+run "locate --index" fun
+assert_equal $(cat $STDOUT_FILE | md5sum | cut -d" " -f 1) $($app locate -m 1 $p tmp-fmi.fa | sort | md5sum | cut -d" " -f 1)
+
+fun() {
+    $app grep -s --index -m 1 $p tmp-fmi.fa
+}
+run "grep -s --index" fun
+assert_equal $(cat $STDOUT_FILE | md5sum | cut -d" " -f 1) $($app grep -s -m 1 $p tmp-fmi.fa | md5sum | cut -d" " -f 1)
+rm tmp-fmi.fa tmp-fmi.fa.fmi
+
+fun() {
+    echo -e ">read\nGGGACGTTTTTACGCCCGGG" | $app locate -p ACRTTTTACS -e 1 -d | sed 1d | cut -f 4-
+}
+run "locate -e" fun
+assert_equal "$(cat $STDOUT_FILE | tr '\t' ,)" "+,4,14,ACGTTTTTACG,1,3=1D7="
+
+fun() {
+    echo -e ">read\nGGGACGTTTTCCGGG" | $app locate -p ACGTTTCC -e 1 -P | sed 1d | cut -f 5-
+}
+run "locate -e with a homopolymer indel" fun
+assert_equal "$(cat $STDOUT_FILE | tr '\t' ,)" "4,12,ACGTTTTCC,1,3=1D5="
+
+fun() {
+    echo -e ">read\nGGGACGTTTTTACGCCCGGG" | $app grep -e 1 -p CGTAAAAACGT
+}
+run "grep -e" fun
+assert_equal $(cat $STDOUT_FILE | $app seq -n) "read"
+
+# many motifs are searched with an Aho-Corasick automaton
+motifs="ACGUA,CGUAC,GGUCA,UUCGA,AAGCU,CUGGA,RYUUG,GNNCA,UGGNN"
+$app head -n 200 tests/hairpin.fa.gz > tmp-ac.fa
+fun() {
+    $app locate -d -c -p $motifs tmp-ac.fa | sed 1d | sort
+}
+run "locate with many motifs" fun
+assert_equal $(cat $STDOUT_FILE | md5sum | cut -d" " -f 1) $(for p in ${motifs//,/ }; do $app locate -d -c -p $p tmp-ac.fa | sed 1d; done | sort | md5sum | cut -d" " -f 1)
+
+motifs="ACGUA,CGUAC,GGUCA,UUCGA,AAGCU,CUGGA,AUGCA,GCAUG"
+fun() {
+    $app grep -s -C -p $motifs tmp-ac.fa
+}
+run "grep -s -C with many motifs" fun
+assert_equal $(cat $STDOUT_FILE) $($app grep -s -C -r -p "${motifs//,/|}" tmp-ac.fa)
+rm tmp-ac.fa
+
+# ------------------------------------------------------------
+#                       orf
+# ------------------------------------------------------------
+testseq() {
+    echo -e ">seq\nCCTAAGGATGTTTC"
+}
+fun() {
+    testseq | $app orf -m 6 -c -f 1,2,3
+}
+run orf_circular fun
+assert_equal $(cat $STDOUT_FILE | $app seq -n -i) "seq_frame=2_begin=8_end=19"
+assert_equal $(cat $STDOUT_FILE | $app seq -s) "ATGTTTCCCTAA"
+
+fun() {
+    testseq | $app seq -r -p -t dna | $app orf -m 6 -c -a
+}
+run orf_circular_negative fun
+assert_equal $(cat $STDOUT_FILE | $app seq -n -i) "seq_frame=-2_begin=10_end=21"
+assert_equal $(cat $STDOUT_FILE | $app seq -s) "MFP"
+
+# ------------------------------------------------------------
+#                       codon
+# ------------------------------------------------------------
+file=tests/mouse-p53-cds.fna
+
+run codon $app codon $file
+assert_equal $(sed 1d $STDOUT_FILE | wc -l) 64
+assert_equal $(sed 1d $STDOUT_FILE | awk '{s+=$4} END {print s}') $(($($app fx2tab -n -l $file | cut -f 2) / 3))
+
+fun() {
+    echo -e ">seq\nATGTAAGGGC" | $app codon -I
+}
+run codon_indices fun
+assert_equal $(sed 1d $STDOUT_FILE | cut -f 6,7 | tr '\t' ,) "1,1"
+
+# ------------------------------------------------------------
+#                       digest
+# ------------------------------------------------------------
+fun() {
+    echo -e ">seq\nAAAGAATTCAAAAGGATCCAAAACTGCAGAAAA" | $app digest -e EcoRI,BamHI,PstI
+}
+run digest fun
+assert_equal $(sed 1d $STDOUT_FILE | cut -f 3,4 | tr '\t' - | paste -sd ,) "1-4,5-14,15-28,29-33"
+assert_equal $(sed 1d $STDOUT_FILE | cut -f 9 | paste -sd ,) "5'AATT,5'GATC,3'TGCA,."
+
+fun() {
+    echo -e ">seq\nATTCAAAAAAAAAAAAAAGA" | $app digest -e EcoRI -c -F
+}
+run digest_circular fun
+assert_equal $(cat $STDOUT_FILE | $app seq -n -i) "seq_fragment=1_begin=20_end=39"
+assert_equal $(cat $STDOUT_FILE | $app seq -s) "AATTCAAAAAAAAAAAAAAG"
+
+fun() {
+    echo -e ">seq\nAAAAAGGTCTCAAAAAAAAAAAAGAGACCAAAAA" | $app digest -e BsaI -m 10
+}
+run digest_type_iis fun
+assert_equal $(sed 1d $STDOUT_FILE | cut -f 3,4 | tr '\t' - | paste -sd ,) "1-12,19-34"
+
+# ------------------------------------------------------------
+#                       oligo
+# ------------------------------------------------------------
+file=tests/primers-16S.tsv
+
+run oligo $app oligo -p $file
+assert_equal $(sed 1d $STDOUT_FILE | wc -l) 4
+assert_equal $(sed -n 2p $STDOUT_FILE | cut -f 5,6 | tr '\t' ,) "50.00,56.92"
+
+fun() {
+    echo -e ">hairpin\nTTGCGCGCAAAAGCGCGCAAT" | $app oligo
+}
+run oligo_hairpin fun
+assert_equal $(sed 1d $STDOUT_FILE | cut -f 7) "-8.55"
+
+run oligo_cross_dimers $app oligo -p $file -x
+assert_equal $(sed 1d $STDOUT_FILE | wc -l) 6
+
+fun() {
+    echo -e ">seq\nAGAGTTTGATCCTGGCTCAGAAAACCCCGGGGTTTTCCAGCAGCCGCGGTAAT" | $app amplicon -p $file -O --bed
+}
+run amplicon_oligo_props fun
+assert_equal $(cut -f 8-12 $STDOUT_FILE | tr '\t' ,) "56.92,60.17,50.00,64.71,-0.40"
+
+fun() {
+    echo -e ">seq\nAGAGTTTGATCCTGGCTCAGAAAACCCCGGGGTTTTCCAGCAGCCGCGGTAAT" | $app amplicon -p $file -O --bed --mg 3 --mono 20
+}
+run amplicon_oligo_props_conditions fun
+assert_equal $(cut -f 8,9 $STDOUT_FILE | tr '\t' ,) $($app oligo -p $file --mg 3 --mono 20 | sed 1d | head -n 2 | cut -f 6 | paste -sd,)
+
+# ------------------------------------------------------------
+#                       amplicon panel
+# ------------------------------------------------------------
+file=tests/SIRV_150601a.fasta
+
+run amplicon_panel $app amplicon --panel -p tests/primers-panel.tsv $file --panel-products products.tsv --panel-summary summary.tsv
+assert_equal $(sed -n 2p $STDOUT_FILE | cut -f 1,2,3 | tr '\t' ,) "amp1,400:0,."
+assert_equal $(grep -c cross products.tsv) 2
+assert_equal $(sed -n 2p summary.tsv | cut -f 4) 6
+rm products.tsv summary.tsv
+
+# ------------------------------------------------------------
+#                       primer-trim
+# ------------------------------------------------------------
+file=tests/amplicon-reads.fa
+
+run primer_trim_bed $app primer-trim -b tests/primers-scheme.bed -r tests/SIRV_150601a.fasta $file
+assert_equal $($app fx2tab -n -l $STDOUT_FILE | cut -f 2 | tr '\n' ,) "360,410,180,"
+assert_equal $($app seq -n $STDOUT_FILE | head -n 1 | cut -d " " -f 2,3 | tr ' ' ,) "amplicon=SIRV1_1,pool=1"
+
+run primer_trim_both_ends $app primer-trim -p tests/primers-panel.tsv -B $file
+assert_equal $($app seq -n $STDOUT_FILE | tr ' \n' =,) "r1=amplicon=amp1,r2=amplicon=amp2,"
 
 # ------------------------------------------------------------
 #                       rmdup
@@ -335,6 +586,30 @@ run "rmdup -s" fun
 assert_in_stderr "9 duplicated records removed"
 assert_equal $(cat $STDOUT_FILE | md5sum | cut -d" " -f 1) $(testseq | md5sum | cut -d" " -f 1)
 
+fun() {
+    repeated_seq | $app rmdup -s --max-mem 100 --tmp-dir tmp-rmdup
+}
+run "rmdup --max-mem" fun
+assert_in_stderr "9 duplicated records removed"
+assert_equal $(cat $STDOUT_FILE | md5sum | cut -d" " -f 1) $(testseq | md5sum | cut -d" " -f 1)
+assert_equal $(ls tmp-rmdup | wc -l) 0
+rm -r tmp-rmdup
+
+run "rmdup paired-end" $app rmdup -1 tests/pe-dup_1.fq -2 tests/pe-dup_2.fq -O tmp-rmdup -D tmp-rmdup.txt
+assert_in_stderr "2 duplicated read pairs removed"
+assert_equal $($app seq -n -i tmp-rmdup/pe-dup_2.fq | tr '\n' ,) "p1,p4,p5,"
+assert_equal "$(cat tmp-rmdup.txt)" "3	p1, p2, p3"
+
+run "rmdup paired-end -N -Q" $app rmdup -1 tests/pe-dup_1.fq -2 tests/pe-dup_2.fq -O tmp-rmdup -N 19 -Q
+assert_equal $($app seq -n -i tmp-rmdup/pe-dup_1.fq | tr '\n' ,) "p2,p5,"
+rm -r tmp-rmdup tmp-rmdup.txt
+
+run "rmdup optical duplicates" $app rmdup -s tests/optical.fq --optical-dist 100 --optical-report tmp-optical.tsv
+assert_equal $($app seq -n -i $STDOUT_FILE | wc -l) 3
+assert_equal $(grep -P "^all\tall" tmp-optical.tsv | cut -f 3-6 | tr '\t' ,) "9,6,3,3"
+assert_equal $(grep -P "^2\t1101" tmp-optical.tsv | cut -f 5) 2
+rm tmp-optical.tsv
+
 # ------------------------------------------------------------
 #                       common
 # ------------------------------------------------------------
@@ -348,8 +623,52 @@ fun() {
 }
 run common fun
 assert_equal $(cat t.c | $app stat -a | md5sum | cut -d" " -f 1) $(cat t.2 | $app stat -a | md5sum | cut -d" " -f 1)
+
+fun() {
+    $app common -s t.1 t.2 --max-mem 10K --tmp-dir tmp-common > t.d
+}
+run "common --max-mem" fun
+assert_equal $(cat t.d | md5sum | cut -d" " -f 1) $($app common -s t.1 t.2 | md5sum | cut -d" " -f 1)
+rm -r tmp-common
 rm t.*
 
+fun() {
+    for m in union difference sym-difference at-least; do
+        $app common -m $m -k 2 tests/common_set{1,2,3}.fa | $app seq -n | paste -sd ' '
+    done
+}
+run "common -m" fun
+assert_equal "$(cat $STDOUT_FILE | tr '\n' ';')" "a b c d e f;a d;a d f;b c e;"
+
+fun() {
+    $app common -m union -M tmp-matrix.tsv tests/common_set{1,2,3}.fa > /dev/null
+}
+run "common -M" fun
+assert_equal "$(sed 1d tmp-matrix.tsv | cut -f 2- | tr -d '\t' | paste -sd ' ')" "100 110 111 100 011 001"
+rm tmp-matrix.tsv
+
+
+# ------------------------------------------------------------
+#                       diff
+# ------------------------------------------------------------
+
+fun(){
+    $app diff -r tests/diff_a.fa tests/diff_b.fa | sed 1d | cut -f 1-3,7-9
+}
+run diff fun
+assert_equal "$(cat $STDOUT_FILE | tr '\t\n' ',;')" "seq_changed,s1,s1,2,0,0;seq_changed,s2,s2,0,1,0;renamed,s3,s3.rc,0,0,0;desc_changed,s4,s4,0,0,0;only_in_a,s5,,NA,NA,NA;only_in_b,,s6,NA,NA,NA;"
+
+fun(){
+    $app diff -s tests/diff_a.fa tests/diff_a.fa -a | sed 1d | cut -f 1 | sort | uniq -c | awk '{print $2,$1}'
+}
+run "diff -s itself" fun
+assert_equal "$(cat $STDOUT_FILE)" "identical 5"
+
+fun(){
+    $app diff -a <(echo -e ">s1\nACGT\n>s1\nACGA") <(echo -e ">s1\nACGA\n>s1\nACGT") | sed 1d | cut -f 1 | paste -sd,
+}
+run "diff duplicated IDs" fun
+assert_equal "$(cat $STDOUT_FILE)" "identical,identical"
 
 # ------------------------------------------------------------
 #                       split
@@ -374,6 +693,45 @@ rm -r stdin.split
 file=tests/hairpin.fa
 assert_equal $(cat $file | $app sample -p 0.1 | $app stat -a | md5sum | cut -d" " -f 1) $(cat $file | $app sample -p 0.1 | $app stat -a | md5sum | cut -d" " -f 1)
 
+# reservoir sampling
+run "sample -R" $app sample -R -n 1000 $file
+assert_equal 1000 $(grep -c "^>" $STDOUT_FILE)
+assert_equal $(cat $STDOUT_FILE | md5sum | cut -d" " -f 1) $(cat $file | $app sample -R -n 1000 | md5sum | cut -d" " -f 1)
+
+run "sample -W length" $app sample -W length -n 10000 tests/nanopore.fq.gz
+assert_equal 4000 $(cat $STDOUT_FILE | $app seq -n | wc -l)
+
+
+# ------------------------------------------------------------
+#                       subsample
+# ------------------------------------------------------------
+
+file=tests/nanopore.fq.gz
+
+fun(){
+    $app subsample -b 500K $file 2>/dev/null | $app stats -T | cut -f 5 | sed 1d
+    $app subsample -B --qual-weight 0 -b 100K $file 2>/dev/null | $app stats -T | cut -f 6 | sed 1d
+    $app sort -l -r $file 2>/dev/null | $app head -l 100K | $app stats -T | cut -f 6 | sed 1d
+}
+run subsample fun
+assert_equal $(head -n 1 $STDOUT_FILE) 512084
+assert_equal $(sed -n 2p $STDOUT_FILE) $(sed -n 3p $STDOUT_FILE)
+
+fun(){
+    zcat $file | $app subsample -c 50 -g 10K --tmp-dir . | md5sum | cut -d" " -f 1
+    $app subsample -c 50 -g 10K $file | md5sum | cut -d" " -f 1
+}
+run "subsample stdin" fun
+assert_equal $(head -n 1 $STDOUT_FILE) $(sed -n 2p $STDOUT_FILE)
+assert_equal $(ls -d seqkit-subsample-* 2>/dev/null | wc -l) 0
+
+fun(){
+    echo -e "@r1\nACGT\n+\nIIII\n@r2\nACGT\n+\nII" \
+        | $app subsample -b 2 --tmp-dir . 2>/dev/null || echo failed
+    ls -d seqkit-subsample-* 2>/dev/null | wc -l
+}
+run "subsample stdin with invalid records" fun
+assert_equal "$(cat $STDOUT_FILE | paste -sd,)" "failed,0"
 
 # ------------------------------------------------------------
 #                       head
@@ -446,6 +804,72 @@ assert_equal $(cat $file | $app stat -a | md5sum | cut -d" " -f 1) $(cat t.sort.
 assert_equal $(cat $file | $app stat -a | md5sum | cut -d" " -f 1) $(cat t.sort.s | $app stat -a | md5sum | cut -d" " -f 1)
 rm t.sort.*
 
+# external merge sort
+file=tests/reads_1.fq.gz
+
+fun(){
+    $app sort -l $file > t.sort.l
+    zcat $file | $app sort -l --max-mem 10K --tmp-dir . > t.sort.l.ext
+    $app sort -n $file > t.sort.n
+    $app sort -n --max-mem 10K --tmp-dir . $file > t.sort.n.ext
+}
+run "sort --max-mem" fun
+assert_equal $(cat t.sort.l | md5sum | cut -d" " -f 1) $(cat t.sort.l.ext | md5sum | cut -d" " -f 1)
+assert_equal $(cat t.sort.n | md5sum | cut -d" " -f 1) $(cat t.sort.n.ext | md5sum | cut -d" " -f 1)
+assert_equal $(ls -d seqkit-sort-* 2>/dev/null | wc -l) 0
+rm t.sort.*
+
+# more runs than those merged at the same time (256)
+fun(){
+    $app sort -l $file > t.sort.l
+    $app sort -l --max-mem 2K --tmp-dir . $file > t.sort.l.ext
+}
+run "sort --max-mem with multi-pass merging" fun
+assert_equal $(cat t.sort.l | md5sum | cut -d" " -f 1) $(cat t.sort.l.ext | md5sum | cut -d" " -f 1)
+assert_equal $(ls -d seqkit-sort-* 2>/dev/null | wc -l) 0
+rm t.sort.*
+
+file=tests/hairpin.fa.gz
+
+fun(){
+    $app sort -l $file > t.sort.l
+    $app sort -l --max-mem 10K --tmp-dir . $file > t.sort.l.ext
+    $app sort -s -L 5 $file | $app subseq -r 1:5 | $app seq -s > t.sort.s
+    $app sort -s -L 5 --max-mem 10K --tmp-dir . $file | $app subseq -r 1:5 | $app seq -s > t.sort.s.ext
+}
+run "sort --max-mem (FASTA)" fun
+assert_equal $(cat t.sort.l | md5sum | cut -d" " -f 1) $(cat t.sort.l.ext | md5sum | cut -d" " -f 1)
+assert_equal $(cat t.sort.s | md5sum | cut -d" " -f 1) $(cat t.sort.s.ext | md5sum | cut -d" " -f 1)
+assert_equal $(ls -d seqkit-sort-* 2>/dev/null | wc -l) 0
+rm t.sort.*
+
+# multiple keys
+fun(){
+    echo -e ">u3;size=5\nACGT\n>u10;size=12\nACGTAC\n>u2;size=12\nGGGCCC\n>u1\nAC\n>u20;size=5\nAAAAAAAA" \
+        | $app sort -K 'size:num:desc,id:natural' \
+        | $app seq -n -i | paste -sd,
+}
+run "sort -K" fun
+assert_equal "$(cat $STDOUT_FILE)" "u2;size=12,u10;size=12,u3;size=5,u20;size=5,u1"
+
+fun(){
+    echo -e ">u1;size=5\nACGT\n>u2;size=x\nACGTAC" \
+        | $app sort -K 'size:num' --max-mem 1 --tmp-dir . 2>/dev/null || echo failed
+    ls -d seqkit-sort-* 2>/dev/null | wc -l
+}
+run "sort -K invalid number" fun
+assert_equal "$(cat $STDOUT_FILE | paste -sd,)" "failed,0"
+
+fun(){
+    $app sort -l -r $file > t.sort.l
+    $app sort -K length:desc,id $file > t.sort.k
+    $app sort -K length:desc,id --max-mem 10K --tmp-dir . $file > t.sort.k.ext
+}
+run "sort -K length" fun
+assert_equal $(cat t.sort.l | md5sum | cut -d" " -f 1) $(cat t.sort.k | md5sum | cut -d" " -f 1)
+assert_equal $(cat t.sort.l | md5sum | cut -d" " -f 1) $(cat t.sort.k.ext | md5sum | cut -d" " -f 1)
+rm t.sort.*
+
 #-------------------------------------------------------------
 #                       bam
 #-------------------------------------------------------------
@@ -738,6 +1162,31 @@ cmp tests/sorted_scat_find.fq tests/sorted_scat_test_all.fq
 assert_equal $? 0
 rm -f tests/sorted_scat_output.fq tests/sorted_scat_test_all.fq tests/sorted_scat_find.fq tests/scat_test_all_sana.fq
 
+# ------------------------------------------------------------
+#                       sum
+# ------------------------------------------------------------
+
+fun(){
+    $app head -n 5 tests/hairpin.fa.gz > sum.old.fa
+    $app sum -m sum.old.fa > sum.manifest.tsv
+    $app head -n 4 sum.old.fa | $app replace -p '^cel-let-7' -r 'cel-let-7.v2' \
+        | $app mutate -p 1:C -s cel-lin-4 --quiet > sum.new.fa
+    echo -e '>new\nACGU' >> sum.new.fa
+    $app sum --check sum.manifest.tsv sum.new.fa --quiet 2>/dev/null | cut -f 2 | sed 1d
+    $app sum --check sum.manifest.tsv sum.new.fa --quiet >/dev/null 2>&1 || echo failed
+    $app sum --check sum.manifest.tsv sum.old.fa --quiet >/dev/null && echo ok
+    rm -f sum.old.fa sum.new.fa sum.manifest.tsv
+}
+run "sum --check" fun
+assert_equal "$(cat $STDOUT_FILE | tr '\n' ',')" "changed,renamed,added,removed,failed,ok,"
+
+# GA4GH sequence collection digests
+fun(){
+    echo -e ">chrX\nTTGGGGAA\n>chr1\nGGAA\n>chr2\nGCGC" | $app sum --seqcol -a | cut -f 1,5-7
+}
+run "sum --seqcol" fun
+assert_equal "$(cat $STDOUT_FILE | tr '\t' ,)" "XZlrcEGi6mlopZ2uD8ObHkQB1d0oDwKk,Fw1r9eRxfOZD98KKrhlYQNEdSRHoVxAG,cGRMZIb3AVgkcAfNv39RN7hnT5Chk7RX,0uDQVLuHaOZi1u76LjV__yrVUIz9Bwhr"
+
 # ------------------------------------------------------------
 #                       faidx
 # ------------------------------------------------------------